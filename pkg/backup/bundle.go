@@ -0,0 +1,160 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// PassphraseEnvVar the environment variable used to default the passphrase used to encrypt backup bundles
+	/* #nosec */
+	PassphraseEnvVar = "JX_BACKUP_PASSPHRASE"
+
+	// FileSuffix the file name suffix of a backup bundle
+	FileSuffix = ".tar.gz.enc"
+
+	fileGitURL             = "git-url.txt"
+	fileSecrets            = "secrets.yaml"
+	fileReleases           = "releases.yaml"
+	fileSourceRepositories = "sourcerepositories.yaml"
+	filePipelineActivities = "pipelineactivities.yaml"
+)
+
+// Release a release which was installed
+type Release struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Chart     string `json:"chart,omitempty"`
+	Version   string `json:"version,omitempty"`
+
+	// Values the values the release was installed with
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// Bundle the contents of a backup needed to re-run boot after a destroy
+type Bundle struct {
+	GitURL             string
+	SecretsYAML        string
+	Requirements       *config.RequirementsConfig
+	Releases           []Release
+	SourceRepositories []v1.SourceRepository
+	PipelineActivities []v1.PipelineActivity
+}
+
+// Marshal writes the bundle as a gzipped tarball encrypted with the given passphrase
+func (b *Bundle) Marshal(passphrase string) ([]byte, error) {
+	files := map[string]interface{}{
+		fileReleases:           b.Releases,
+		fileSourceRepositories: b.SourceRepositories,
+		filePipelineActivities: b.PipelineActivities,
+	}
+	if b.Requirements != nil {
+		files[config.RequirementsConfigFileName] = b.Requirements
+	}
+	contents := map[string][]byte{
+		fileGitURL:  []byte(b.GitURL),
+		fileSecrets: []byte(b.SecretsYAML),
+	}
+	for name, v := range files {
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal %s", name)
+		}
+		contents[name] = data
+	}
+
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for _, name := range []string{fileGitURL, fileSecrets, config.RequirementsConfigFileName, fileReleases, fileSourceRepositories, filePipelineActivities} {
+		data, ok := contents[name]
+		if !ok {
+			continue
+		}
+		err := tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0600,
+			Size: int64(len(data)),
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to write tar header for %s", name)
+		}
+		_, err = tw.Write(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to write %s to tarball", name)
+		}
+	}
+	err := tw.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to close tarball")
+	}
+	err = gw.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to close gzip stream")
+	}
+	return Encrypt(buf.Bytes(), passphrase)
+}
+
+// Unmarshal decrypts and reads the bundle
+func Unmarshal(data []byte, passphrase string) (*Bundle, error) {
+	plain, err := Decrypt(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read gzip stream")
+	}
+	contents := map[string][]byte{}
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read tarball")
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s from tarball", h.Name)
+		}
+		contents[h.Name] = data
+	}
+
+	b := &Bundle{
+		GitURL:      strings.TrimSpace(string(contents[fileGitURL])),
+		SecretsYAML: string(contents[fileSecrets]),
+	}
+	if data := contents[config.RequirementsConfigFileName]; len(data) > 0 {
+		b.Requirements = config.NewRequirementsConfig()
+		err = yaml.Unmarshal(data, b.Requirements)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal %s", config.RequirementsConfigFileName)
+		}
+	}
+	for name, v := range map[string]interface{}{
+		fileReleases:           &b.Releases,
+		fileSourceRepositories: &b.SourceRepositories,
+		filePipelineActivities: &b.PipelineActivities,
+	} {
+		data := contents[name]
+		if len(data) == 0 {
+			continue
+		}
+		err = yaml.Unmarshal(data, v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal %s", name)
+		}
+	}
+	return b, nil
+}
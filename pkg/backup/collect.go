@@ -0,0 +1,100 @@
+package backup
+
+import (
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
+	"github.com/jenkins-x-labs/helmboot/pkg/helmfiles"
+	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr"
+	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr/factory"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Collect creates a backup bundle of the current installation for the given releases including the values
+// each release was installed with
+func Collect(runner cmdrunner.CommandRunner, resolver *factory.KindResolver, releases []helmfiles.Release) (*Bundle, error) {
+	if runner == nil {
+		runner = cmdrunner.DefaultCommandRunner
+	}
+	b := &Bundle{}
+
+	// lets prefer the git URL from the Secret as it includes the user and token to clone private repositories
+	gitURL, err := resolver.LoadBootRunGitURLFromSecret()
+	if err != nil {
+		log.Logger().Debugf("could not load the git URL from the Secret: %s", err.Error())
+		gitURL = resolver.GitURL
+	}
+	b.GitURL = gitURL
+
+	sm, err := resolver.CreateSecretManager("")
+	if err != nil {
+		return nil, err
+	}
+	cb := func(currentYAML string) (string, error) {
+		b.SecretsYAML = currentYAML
+		return currentYAML, nil
+	}
+	err = sm.UpsertSecrets(cb, secretmgr.DefaultSecretsYaml)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load Secrets YAML from secret manager %s", sm.String())
+	}
+	b.Requirements = resolver.Requirements
+
+	for _, r := range releases {
+		b.Releases = append(b.Releases, Release{
+			Name:      r.Name,
+			Namespace: r.Namespace,
+			Chart:     r.Chart,
+			Version:   r.Version,
+			Values:    releaseValues(runner, r.Name, r.Namespace),
+		})
+	}
+
+	jxClient, ns, err := resolver.GetFactory().CreateJXClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create JX client")
+	}
+	srList, err := jxClient.JenkinsV1().SourceRepositories(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list SourceRepositories in namespace %s", ns)
+	}
+	b.SourceRepositories = srList.Items
+
+	paList, err := jxClient.JenkinsV1().PipelineActivities(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list PipelineActivities in namespace %s", ns)
+	}
+	b.PipelineActivities = paList.Items
+	return b, nil
+}
+
+// releaseValues returns the values of the installed release or nil if they cannot be found such as if the
+// release is not installed
+func releaseValues(runner cmdrunner.CommandRunner, name string, ns string) map[string]interface{} {
+	args := []string{"get", "values", name}
+	if ns != "" {
+		args = append(args, "--namespace", ns)
+	}
+	args = append(args, "--output", "yaml")
+	c := &util.Command{
+		Name: "helm",
+		Args: args,
+	}
+	text, err := runner(c)
+	if err != nil {
+		log.Logger().Warnf("failed to get the values of release %s in namespace %s: %s", name, ns, err.Error())
+		return nil
+	}
+	values := map[string]interface{}{}
+	err = yaml.Unmarshal([]byte(text), &values)
+	if err != nil {
+		log.Logger().Warnf("failed to parse the values of release %s in namespace %s: %s", name, ns, err.Error())
+		return nil
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+// magic the header of an encrypted bundle so we can detect invalid files and future format changes
+var magic = []byte("jxboot-backup-v1\n")
+
+// Encrypt encrypts the data using AES-GCM with a key derived from the passphrase
+func Encrypt(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.Errorf("no passphrase specified to encrypt the backup")
+	}
+	salt := make([]byte, saltLength)
+	_, err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate salt")
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+	answer := append([]byte{}, magic...)
+	answer = append(answer, salt...)
+	answer = append(answer, nonce...)
+	return gcm.Seal(answer, nonce, data, magic), nil
+}
+
+// Decrypt decrypts data created by Encrypt with the same passphrase
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.Errorf("no passphrase specified to decrypt the backup")
+	}
+	if !bytes.HasPrefix(data, magic) {
+		return nil, errors.Errorf("the file is not a boot backup bundle")
+	}
+	data = data[len(magic):]
+	if len(data) < saltLength {
+		return nil, errors.Errorf("the backup bundle is truncated")
+	}
+	salt := data[:saltLength]
+	data = data[saltLength:]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.Errorf("the backup bundle is truncated")
+	}
+	nonce := data[:gcm.NonceSize()]
+	answer, err := gcm.Open(nil, nonce, data[gcm.NonceSize():], magic)
+	if err != nil {
+		return nil, errors.Errorf("failed to decrypt the backup bundle. Please check the passphrase")
+	}
+	return answer, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, keyLength)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive the encryption key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create GCM")
+	}
+	return gcm, nil
+}
@@ -0,0 +1,57 @@
+package backup
+
+import (
+	"github.com/jenkins-x/jx/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RestoreResources creates the SourceRepositories and PipelineActivities in the bundle which do not exist
+// in the given namespace. The Jenkins X CRDs must have been installed first, e.g. by running boot
+func (b *Bundle) RestoreResources(jxClient versioned.Interface, ns string) error {
+	srInterface := jxClient.JenkinsV1().SourceRepositories(ns)
+	count := 0
+	for i := range b.SourceRepositories {
+		sr := b.SourceRepositories[i]
+		sr.ObjectMeta = restoredObjectMeta(sr.ObjectMeta, ns)
+		_, err := srInterface.Create(&sr)
+		if err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				continue
+			}
+			return errors.Wrapf(err, "failed to restore SourceRepository %s in namespace %s", sr.Name, ns)
+		}
+		count++
+	}
+	log.Logger().Infof("restored %s SourceRepositories in namespace %s", util.ColorInfo(count), ns)
+
+	paInterface := jxClient.JenkinsV1().PipelineActivities(ns)
+	count = 0
+	for i := range b.PipelineActivities {
+		pa := b.PipelineActivities[i]
+		pa.ObjectMeta = restoredObjectMeta(pa.ObjectMeta, ns)
+		_, err := paInterface.Create(&pa)
+		if err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				continue
+			}
+			return errors.Wrapf(err, "failed to restore PipelineActivity %s in namespace %s", pa.Name, ns)
+		}
+		count++
+	}
+	log.Logger().Infof("restored %s PipelineActivities in namespace %s", util.ColorInfo(count), ns)
+	return nil
+}
+
+// restoredObjectMeta removes the server generated fields so that the resource can be created again
+func restoredObjectMeta(m metav1.ObjectMeta, ns string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        m.Name,
+		Namespace:   ns,
+		Labels:      m.Labels,
+		Annotations: m.Annotations,
+	}
+}
@@ -0,0 +1,111 @@
+package backup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+)
+
+// bucketCopyCommands the CLI used to copy files to and from each kind of bucket URL
+var bucketCopyCommands = map[string][]string{
+	"gs": {"gsutil", "cp"},
+	"s3": {"aws", "s3", "cp"},
+}
+
+// FileName returns the file name of a new backup bundle for the given cluster
+func FileName(clusterName string, now time.Time) string {
+	if clusterName == "" {
+		clusterName = "cluster"
+	}
+	return fmt.Sprintf("jx-boot-backup-%s-%s%s", clusterName, now.UTC().Format("20060102-150405"), FileSuffix)
+}
+
+// IsBucketURL returns true if the location is a bucket URL such as gs://mybucket or s3://mybucket
+func IsBucketURL(location string) bool {
+	u, err := url.Parse(location)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// Save saves the data to the file name inside the location which is either a local directory or a bucket URL
+// returning the path or URL of the saved bundle
+func Save(runner cmdrunner.CommandRunner, location string, fileName string, data []byte) (string, error) {
+	if !IsBucketURL(location) {
+		err := os.MkdirAll(location, util.DefaultWritePermissions)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to create directory %s", location)
+		}
+		path := filepath.Join(location, fileName)
+		err = ioutil.WriteFile(path, data, 0600)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to save backup to %s", path)
+		}
+		return path, nil
+	}
+
+	bucketURL := strings.TrimSuffix(location, "/") + "/" + fileName
+	tmpDir, err := ioutil.TempDir("", "helmboot-backup-")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, fileName)
+	err = ioutil.WriteFile(path, data, 0600)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to save backup to %s", path)
+	}
+	err = copyBucketFile(runner, bucketURL, path, bucketURL)
+	if err != nil {
+		return "", err
+	}
+	return bucketURL, nil
+}
+
+// Load loads the backup bundle from the local file or bucket URL
+func Load(runner cmdrunner.CommandRunner, location string) ([]byte, error) {
+	path := location
+	if IsBucketURL(location) {
+		tmpDir, err := ioutil.TempDir("", "helmboot-backup-")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create temporary directory")
+		}
+		defer os.RemoveAll(tmpDir)
+		path = filepath.Join(tmpDir, "bundle"+FileSuffix)
+		err = copyBucketFile(runner, location, location, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load backup %s", location)
+	}
+	return data, nil
+}
+
+func copyBucketFile(runner cmdrunner.CommandRunner, bucketURL string, from string, to string) error {
+	u, err := url.Parse(bucketURL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse bucket URL %s", bucketURL)
+	}
+	args := bucketCopyCommands[u.Scheme]
+	if len(args) == 0 {
+		return errors.Errorf("unsupported backup bucket URL %s. Supported schemes are gs:// and s3://", bucketURL)
+	}
+	c := &util.Command{
+		Name: args[0],
+		Args: append(append([]string{}, args[1:]...), from, to),
+	}
+	_, err = runner(c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to run command: %s %s", c.Name, strings.Join(c.Args, " "))
+	}
+	return nil
+}
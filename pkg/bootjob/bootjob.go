@@ -0,0 +1,342 @@
+package bootjob
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// JobName the name of the boot Job
+	JobName = "jx-boot"
+
+	// ContainerName the name of the container in the boot Job
+	ContainerName = "boot"
+
+	// ServiceAccountName the name of the ServiceAccount used to run the boot Job
+	ServiceAccountName = "jx-boot"
+
+	// EnvSecretName the name of the Secret used to pass the environment variables into the boot Job
+	/* #nosec */
+	EnvSecretName = "jx-boot-env"
+
+	// DefaultImage the default container image used to run boot
+	DefaultImage = "gcr.io/jenkinsxio-labs/helmboot"
+
+	// DefaultImageTag the default image tag if there is no version in the version stream
+	DefaultImageTag = "latest"
+
+	// SecretsYAMLPath the path inside the boot Job of the secrets YAML
+	/* #nosec */
+	SecretsYAMLPath = "/tmp/secrets/jx-boot/secrets.yaml"
+
+	// SecretsVolumeName the name of the volume the secrets YAML is exported to inside the boot Job
+	SecretsVolumeName = "secrets"
+
+	// DefaultClusterRole the default ClusterRole bound to the boot ServiceAccount
+	DefaultClusterRole = "cluster-admin"
+)
+
+// Options the options for creating the boot Job resources
+type Options struct {
+	// Namespace the namespace to run the boot Job
+	Namespace string
+
+	// GitURL the git URL of the development environment including any user and token
+	GitURL string
+
+	// Image the container image including the tag
+	Image string
+
+	// BackoffLimit the number of retries of the boot Job. If zero the kubernetes default is used
+	BackoffLimit int32
+
+	// Run the run number of the boot Job used to keep a history of boot Jobs. If zero the Job is called JobName
+	Run int
+
+	// GitSHA the git commit SHA being booted
+	GitSHA string
+
+	// Version the version of the boot image
+	Version string
+
+	// Values the boot Job values containing the requirements and any job values such as the image or resources
+	Values *reqhelpers.BootJobValues
+}
+
+// Resources the kubernetes resources required to run the boot Job
+type Resources struct {
+	ServiceAccount     *corev1.ServiceAccount
+	ClusterRoleBinding *rbacv1.ClusterRoleBinding
+	Secret             *corev1.Secret
+	Job                *batchv1.Job
+}
+
+// ClusterRoleName returns the ClusterRole bound to the boot ServiceAccount for the given boot Job values
+func ClusterRoleName(jv *reqhelpers.BootJobValues) string {
+	if jv == nil || jv.ClusterRole == "" {
+		return DefaultClusterRole
+	}
+	return jv.ClusterRole
+}
+
+// CreateResources creates the kubernetes resources to run the boot Job for the given boot Job values
+func CreateResources(o *Options) *Resources {
+	ns := o.Namespace
+	jv := o.Values
+	if jv == nil {
+		jv = &reqhelpers.BootJobValues{}
+	}
+	labels := map[string]string{
+		LabelApp: JobName,
+	}
+	jobName := JobName
+	jobLabels := map[string]string{
+		LabelApp: JobName,
+	}
+	if o.Run > 0 {
+		jobName = JobNameForRun(o.Run)
+		jobLabels[LabelRun] = strconv.Itoa(o.Run)
+	}
+	jobAnnotations := map[string]string{}
+	for k, v := range map[string]string{
+		AnnotationGitURL:  RemoveGitCredentials(o.GitURL),
+		AnnotationGitSHA:  o.GitSHA,
+		AnnotationVersion: o.Version,
+	} {
+		if v != "" {
+			jobAnnotations[k] = v
+		}
+	}
+
+	env := reqhelpers.RequirementsEnvVars(jv.JXRequirements)
+	env["JX_BOOT_CONFIG_URL"] = o.GitURL
+	env[reqhelpers.OverlayEnvVar] = jv.RequirementsOverlay
+	secretData := map[string][]byte{}
+	for k, v := range env {
+		if v != "" {
+			secretData[k] = []byte(v)
+		}
+	}
+
+	image := o.Image
+	if image == "" {
+		image = DefaultImage + ":" + DefaultImageTag
+	}
+	if jv.Image.Repository != "" || jv.Image.Tag != "" {
+		repository := jv.Image.Repository
+		if repository == "" {
+			repository = DefaultImage
+		}
+		tag := jv.Image.Tag
+		if tag == "" {
+			tag = DefaultImageTag
+		}
+		image = repository + ":" + tag
+	}
+	clusterRole := ClusterRoleName(jv)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        jobName,
+			Namespace:   ns,
+			Labels:      jobLabels,
+			Annotations: jobAnnotations,
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: ServiceAccountName,
+					NodeSelector:       jv.NodeSelector,
+					Tolerations:        jv.Tolerations,
+					Volumes: []corev1.Volume{
+						{
+							Name: SecretsVolumeName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:      ContainerName,
+							Image:     image,
+							Command:   []string{"helmboot"},
+							Resources: jv.Resources,
+							Args:      []string{"run", "--batch-mode", "--git-url", "$(JX_BOOT_CONFIG_URL)"},
+							Env: []corev1.EnvVar{
+								{
+									Name:  "JX_BATCH_MODE",
+									Value: "true",
+								},
+								{
+									Name:  "JX_SECRETS_YAML",
+									Value: SecretsYAMLPath,
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      SecretsVolumeName,
+									MountPath: filepath.Dir(SecretsYAMLPath),
+								},
+							},
+							EnvFrom: []corev1.EnvFromSource{
+								{
+									SecretRef: &corev1.SecretEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{
+											Name: EnvSecretName,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if o.BackoffLimit > 0 {
+		backoffLimit := o.BackoffLimit
+		job.Spec.BackoffLimit = &backoffLimit
+	}
+
+	return &Resources{
+		ServiceAccount: &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ServiceAccountName,
+				Namespace: ns,
+				Labels:    labels,
+			},
+		},
+		ClusterRoleBinding: &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   ClusterRoleBindingName(ns),
+				Labels: labels,
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "ClusterRole",
+				Name:     clusterRole,
+			},
+			Subjects: []rbacv1.Subject{
+				{
+					Kind:      "ServiceAccount",
+					Name:      ServiceAccountName,
+					Namespace: ns,
+				},
+			},
+		},
+		Secret: &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      EnvSecretName,
+				Namespace: ns,
+				Labels:    labels,
+			},
+			Data: secretData,
+		},
+		Job: job,
+	}
+}
+
+// ClusterRoleBindingName returns the name of the ClusterRoleBinding for the boot Job in the given namespace
+func ClusterRoleBindingName(ns string) string {
+	return fmt.Sprintf("%s-%s", ServiceAccountName, ns)
+}
+
+// ApplyResources creates or updates the boot Job resources replacing any previous boot Job
+func ApplyResources(kubeClient kubernetes.Interface, r *Resources) error {
+	ns := r.Job.Namespace
+
+	saInterface := kubeClient.CoreV1().ServiceAccounts(ns)
+	_, err := saInterface.Get(r.ServiceAccount.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get ServiceAccount %s in namespace %s", r.ServiceAccount.Name, ns)
+		}
+		_, err = saInterface.Create(r.ServiceAccount)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create ServiceAccount %s in namespace %s", r.ServiceAccount.Name, ns)
+		}
+		log.Logger().Infof("created ServiceAccount %s in namespace %s", util.ColorInfo(r.ServiceAccount.Name), ns)
+	}
+
+	crbInterface := kubeClient.RbacV1().ClusterRoleBindings()
+	crb, err := crbInterface.Get(r.ClusterRoleBinding.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get ClusterRoleBinding %s", r.ClusterRoleBinding.Name)
+		}
+		_, err = crbInterface.Create(r.ClusterRoleBinding)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create ClusterRoleBinding %s", r.ClusterRoleBinding.Name)
+		}
+		log.Logger().Infof("created ClusterRoleBinding %s", util.ColorInfo(r.ClusterRoleBinding.Name))
+	} else {
+		crb.RoleRef = r.ClusterRoleBinding.RoleRef
+		crb.Subjects = r.ClusterRoleBinding.Subjects
+		_, err = crbInterface.Update(crb)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update ClusterRoleBinding %s", r.ClusterRoleBinding.Name)
+		}
+	}
+
+	secretInterface := kubeClient.CoreV1().Secrets(ns)
+	secret, err := secretInterface.Get(r.Secret.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get Secret %s in namespace %s", r.Secret.Name, ns)
+		}
+		_, err = secretInterface.Create(r.Secret)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create Secret %s in namespace %s", r.Secret.Name, ns)
+		}
+	} else {
+		secret.Data = r.Secret.Data
+		_, err = secretInterface.Update(secret)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update Secret %s in namespace %s", r.Secret.Name, ns)
+		}
+	}
+
+	err = DeleteJob(kubeClient, ns, r.Job.Name)
+	if err != nil {
+		return err
+	}
+	_, err = kubeClient.BatchV1().Jobs(ns).Create(r.Job)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create Job %s in namespace %s", r.Job.Name, ns)
+	}
+	log.Logger().Infof("created Job %s in namespace %s", util.ColorInfo(r.Job.Name), ns)
+	return nil
+}
+
+// DeleteJob deletes the Job and its pods if it exists
+func DeleteJob(kubeClient kubernetes.Interface, ns string, name string) error {
+	propagation := metav1.DeletePropagationBackground
+	err := kubeClient.BatchV1().Jobs(ns).Delete(name, &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to delete Job %s in namespace %s", name, ns)
+	}
+	log.Logger().Debugf("deleted the previous Job %s in namespace %s", name, ns)
+	return nil
+}
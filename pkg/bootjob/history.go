@@ -0,0 +1,453 @@
+package bootjob
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// LabelApp the label used to find the boot Jobs
+	LabelApp = "app"
+
+	// LabelRun the label containing the run number of a boot Job
+	LabelRun = "jenkins-x.io/boot-run"
+
+	// LabelHistory the label of the ConfigMaps recording the archived Jobs created by the boot chart
+	LabelHistory = "jenkins-x.io/boot-history"
+
+	// AnnotationGitURL the annotation containing the git URL booted without any credentials
+	AnnotationGitURL = "jenkins-x.io/boot-git-url"
+
+	// AnnotationGitSHA the annotation containing the git commit SHA booted
+	AnnotationGitSHA = "jenkins-x.io/boot-git-sha"
+
+	// AnnotationVersion the annotation containing the chart or image version of the boot Job
+	AnnotationVersion = "jenkins-x.io/boot-version"
+
+	// AnnotationError the annotation containing the error which failed the boot
+	AnnotationError = "jenkins-x.io/boot-error"
+
+	// AnnotationStep the annotation containing the step of the boot which failed
+	AnnotationStep = "jenkins-x.io/boot-step"
+
+	// DefaultHistoryLimit the default number of boot Jobs to keep
+	DefaultHistoryLimit = 5
+
+	// maxErrorLength the maximum length of the error stored in the Job annotation
+	maxErrorLength = 1000
+
+	// historyStatusKey the key of the archived Job status in the history ConfigMap
+	historyStatusKey = "status"
+)
+
+// JobRecord summarises a historical run of the boot Job
+type JobRecord struct {
+	Name       string
+	Run        int
+	GitURL     string
+	GitSHA     string
+	Version    string
+	Start      *metav1.Time
+	End        *metav1.Time
+	Outcome    string
+	FailedStep string
+	FailedErr  string
+}
+
+// credentialsRegex matches the user and password or token of any URLs
+var credentialsRegex = regexp.MustCompile(`://[^/@\s]+@`)
+
+// JobNameForRun returns the name of the boot Job for the given run number
+func JobNameForRun(run int) string {
+	return fmt.Sprintf("%s-%d", JobName, run)
+}
+
+// RunNumber returns the run number of the Job or 0 if it does not have one
+func RunNumber(job *batchv1.Job) int {
+	n, err := strconv.Atoi(job.Labels[LabelRun])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// ListJobs returns the boot Jobs in the namespace with the most recent first
+func ListJobs(kubeClient kubernetes.Interface, ns string) ([]batchv1.Job, error) {
+	list, err := kubeClient.BatchV1().Jobs(ns).List(metav1.ListOptions{
+		LabelSelector: LabelApp + "=" + JobName,
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to list boot Jobs in namespace %s", ns)
+	}
+	var answer []batchv1.Job
+	found := false
+	if list != nil {
+		for _, j := range list.Items {
+			if j.Name == JobName {
+				found = true
+			}
+			answer = append(answer, j)
+		}
+	}
+
+	// the Job created by the boot chart may not have our labels
+	if !found {
+		job, err := kubeClient.BatchV1().Jobs(ns).Get(JobName, metav1.GetOptions{})
+		if err == nil && job != nil {
+			answer = append(answer, *job)
+		} else if err != nil && !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get Job %s in namespace %s", JobName, ns)
+		}
+	}
+
+	// the archived Jobs created by the boot chart are recorded in ConfigMaps
+	cmList, err := kubeClient.CoreV1().ConfigMaps(ns).List(metav1.ListOptions{
+		LabelSelector: LabelHistory + "=true",
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to list the boot history ConfigMaps in namespace %s", ns)
+	}
+	if cmList != nil {
+		for i := range cmList.Items {
+			answer = append(answer, jobFromHistory(&cmList.Items[i]))
+		}
+	}
+
+	sort.Slice(answer, func(i, j int) bool {
+		j1 := &answer[i]
+		j2 := &answer[j]
+
+		// the Job created by the boot chart is the most recent as the previous Jobs are archived with a run number
+		if (j1.Name == JobName) != (j2.Name == JobName) {
+			return j1.Name == JobName
+		}
+		n1 := RunNumber(j1)
+		n2 := RunNumber(j2)
+		if n1 != n2 {
+			return n1 > n2
+		}
+		return j2.CreationTimestamp.Before(&j1.CreationTimestamp)
+	})
+	return answer, nil
+}
+
+// NextRunNumber returns the next run number for a boot Job
+func NextRunNumber(kubeClient kubernetes.Interface, ns string) (int, error) {
+	jobs, err := ListJobs(kubeClient, ns)
+	if err != nil {
+		return 0, err
+	}
+	answer := 1
+	for i := range jobs {
+		n := RunNumber(&jobs[i])
+		if n >= answer {
+			answer = n + 1
+		}
+	}
+	return answer, nil
+}
+
+// PruneJobs removes the oldest boot Jobs so that at most historyLimit Jobs are kept
+func PruneJobs(kubeClient kubernetes.Interface, ns string, historyLimit int) error {
+	if historyLimit <= 0 {
+		return nil
+	}
+	jobs, err := ListJobs(kubeClient, ns)
+	if err != nil {
+		return err
+	}
+	for i := historyLimit; i < len(jobs); i++ {
+		name := jobs[i].Name
+		if IsArchivedJob(&jobs[i]) {
+			err = kubeClient.CoreV1().ConfigMaps(ns).Delete(name, &metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to delete ConfigMap %s in namespace %s", name, ns)
+			}
+		} else {
+			err = DeleteJob(kubeClient, ns, name)
+			if err != nil {
+				return err
+			}
+		}
+
+		// the pods of archived Jobs are no longer owned by the Job so lets remove them too
+		pods, err := FindJobPods(kubeClient, ns, name)
+		if err != nil {
+			return err
+		}
+		for j := range pods {
+			podName := pods[j].Name
+			err = kubeClient.CoreV1().Pods(ns).Delete(podName, &metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to delete pod %s in namespace %s", podName, ns)
+			}
+		}
+	}
+	return nil
+}
+
+// ArchiveJob keeps the Job created by the boot chart in the boot history before the chart is reinstalled.
+//
+// The Job is recorded in a ConfigMap, named with the next run number, containing its annotations and status. The Job is
+// then removed leaving its pods, which are relabelled with the name of the record, so that their logs can still be
+// viewed. Returns the name of the record or an empty string if there is no Job to archive
+func ArchiveJob(kubeClient kubernetes.Interface, ns string) (string, error) {
+	jobInterface := kubeClient.BatchV1().Jobs(ns)
+	job, err := jobInterface.Get(JobName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to get Job %s in namespace %s", JobName, ns)
+	}
+	run, err := NextRunNumber(kubeClient, ns)
+	if err != nil {
+		return "", err
+	}
+	name := JobNameForRun(run)
+
+	status, err := json.Marshal(&job.Status)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal the status of Job %s", JobName)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels: map[string]string{
+				LabelApp:     JobName,
+				LabelRun:     strconv.Itoa(run),
+				LabelHistory: "true",
+			},
+			Annotations: job.Annotations,
+		},
+		Data: map[string]string{
+			historyStatusKey: string(status),
+		},
+	}
+	_, err = kubeClient.CoreV1().ConfigMaps(ns).Create(cm)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create ConfigMap %s in namespace %s", name, ns)
+	}
+
+	pods, err := FindJobPods(kubeClient, ns, JobName)
+	if err != nil {
+		return "", err
+	}
+	for i := range pods {
+		pod := &pods[i]
+		pod.Labels["job-name"] = name
+		pod.OwnerReferences = nil
+		_, err = kubeClient.CoreV1().Pods(ns).Update(pod)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to relabel pod %s in namespace %s", pod.Name, ns)
+		}
+	}
+
+	orphan := metav1.DeletePropagationOrphan
+	err = jobInterface.Delete(JobName, &metav1.DeleteOptions{
+		PropagationPolicy: &orphan,
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", errors.Wrapf(err, "failed to delete Job %s in namespace %s", JobName, ns)
+	}
+	log.Logger().Debugf("archived the Job %s in namespace %s as %s", JobName, ns, name)
+	return name, nil
+}
+
+// IsArchivedJob returns true if the Job was loaded from the ConfigMap recording an archived Job of the boot chart
+func IsArchivedJob(job *batchv1.Job) bool {
+	return job.Labels[LabelHistory] == "true"
+}
+
+// jobFromHistory converts the ConfigMap recording an archived Job back into the Job so that it can be viewed
+// in the boot history along with the other boot Jobs
+func jobFromHistory(cm *corev1.ConfigMap) batchv1.Job {
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              cm.Name,
+			Namespace:         cm.Namespace,
+			Labels:            cm.Labels,
+			Annotations:       cm.Annotations,
+			CreationTimestamp: cm.CreationTimestamp,
+		},
+	}
+	data := cm.Data[historyStatusKey]
+	if data != "" {
+		err := json.Unmarshal([]byte(data), &job.Status)
+		if err != nil {
+			log.Logger().Debugf("failed to unmarshal the status of the archived Job %s: %s", cm.Name, err.Error())
+		}
+	}
+	return job
+}
+
+// FindJob finds the boot Job for the given run number, the previous Job or the most recent Job
+func FindJob(jobs []batchv1.Job, run int, previous bool) (*batchv1.Job, error) {
+	if run > 0 {
+		for i := range jobs {
+			if RunNumber(&jobs[i]) == run {
+				return &jobs[i], nil
+			}
+		}
+		return nil, errors.Errorf("no boot Job found for run %d", run)
+	}
+	idx := 0
+	if previous {
+		idx = 1
+	}
+	if idx >= len(jobs) {
+		if previous {
+			return nil, errors.Errorf("there is no previous boot Job")
+		}
+		return nil, errors.Errorf("no boot Jobs found")
+	}
+	return &jobs[idx], nil
+}
+
+// ToJobRecord creates a summary of the given boot Job
+func ToJobRecord(job *batchv1.Job) *JobRecord {
+	ann := job.Annotations
+	if ann == nil {
+		ann = map[string]string{}
+	}
+	answer := &JobRecord{
+		Name:       job.Name,
+		Run:        RunNumber(job),
+		GitURL:     ann[AnnotationGitURL],
+		GitSHA:     ann[AnnotationGitSHA],
+		Version:    ann[AnnotationVersion],
+		Start:      job.Status.StartTime,
+		End:        job.Status.CompletionTime,
+		Outcome:    "Running",
+		FailedStep: ann[AnnotationStep],
+		FailedErr:  ann[AnnotationError],
+	}
+	if IsJobComplete(job) {
+		answer.Outcome = "Succeeded"
+	} else if failed, message := IsJobFailed(job); failed {
+		answer.Outcome = "Failed"
+		if answer.FailedErr == "" {
+			answer.FailedErr = message
+		}
+		for _, c := range job.Status.Conditions {
+			if c.Type == batchv1.JobFailed {
+				t := c.LastTransitionTime
+				answer.End = &t
+			}
+		}
+	}
+	return answer
+}
+
+// FindJobPods returns the pods for the given Job with the most recent first
+func FindJobPods(kubeClient kubernetes.Interface, ns string, jobName string) ([]corev1.Pod, error) {
+	selector := "job-name=" + jobName
+	list, err := kubeClient.CoreV1().Pods(ns).List(metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list pods in namespace %s with selector %s", ns, selector)
+	}
+	pods := list.Items
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[j].CreationTimestamp.Before(&pods[i].CreationTimestamp)
+	})
+	return pods, nil
+}
+
+// RemoveGitCredentials removes any user and token from the git URL
+func RemoveGitCredentials(gitURL string) string {
+	u, err := url.Parse(gitURL)
+	if err != nil || u.User == nil {
+		return gitURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// RemoveCredentials removes any user and token from the URLs in the given text
+func RemoveCredentials(text string) string {
+	return credentialsRegex.ReplaceAllString(text, "://")
+}
+
+// AnnotateJob adds the given annotations to the Job
+func AnnotateJob(kubeClient kubernetes.Interface, ns string, name string, annotations map[string]string) error {
+	jobInterface := kubeClient.BatchV1().Jobs(ns)
+	job, err := jobInterface.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get Job %s in namespace %s", name, ns)
+	}
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+	for k, v := range annotations {
+		if v != "" {
+			job.Annotations[k] = v
+		}
+	}
+	_, err = jobInterface.Update(job)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update Job %s in namespace %s", name, ns)
+	}
+	return nil
+}
+
+// CurrentJobName returns the name of the Job running the current pod or an empty string if not running inside a Job
+func CurrentJobName(kubeClient kubernetes.Interface, ns string) string {
+	podName := os.Getenv("HOSTNAME")
+	if podName == "" {
+		return ""
+	}
+	pod, err := kubeClient.CoreV1().Pods(ns).Get(podName, metav1.GetOptions{})
+	if err != nil {
+		log.Logger().Debugf("failed to find pod %s in namespace %s: %s", podName, ns, err.Error())
+		return ""
+	}
+	return pod.Labels["job-name"]
+}
+
+// RecordJobError records the boot error and the step which failed on the Job running the current pod so that it can
+// be viewed in the boot history. Any git credentials in the error are removed
+func RecordJobError(kubeClient kubernetes.Interface, ns string, step string, bootErr error) {
+	if bootErr == nil {
+		return
+	}
+	jobName := CurrentJobName(kubeClient, ns)
+	if jobName == "" {
+		return
+	}
+	message := RemoveCredentials(bootErr.Error())
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+	err := AnnotateJob(kubeClient, ns, jobName, map[string]string{
+		AnnotationError: message,
+		AnnotationStep:  step,
+	})
+	if err != nil {
+		log.Logger().Warnf("failed to record the boot error on Job %s: %s", jobName, err.Error())
+	}
+}
+
+// FormatTime formats the optional time for display
+func FormatTime(t *metav1.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
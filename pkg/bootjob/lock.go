@@ -0,0 +1,280 @@
+package bootjob
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// LockName the name of the Lease used to prevent concurrent boots
+	LockName = "jx-boot-lock"
+
+	// DefaultLockDuration the default duration of the boot lock before it expires unless it is renewed
+	DefaultLockDuration = 15 * time.Minute
+)
+
+// Lock a lock on booting the cluster using a Lease in the development namespace
+type Lock struct {
+	kubeClient kubernetes.Interface
+	ns         string
+	holder     string
+	duration   time.Duration
+	stop       chan struct{}
+	stopOnce   sync.Once
+	// transferred is true once the lock has been handed off to a boot Job which is then responsible for releasing it
+	transferred bool
+}
+
+// AcquireLock acquires the boot lock for the given holder identity. If the lock is already held by the same
+// holder it is renewed. Returns an error if the lock is held by another holder and has not expired
+func AcquireLock(kubeClient kubernetes.Interface, ns string, holder string, duration time.Duration) (*Lock, error) {
+	if duration <= 0 {
+		duration = DefaultLockDuration
+	}
+	leaseInterface := kubeClient.CoordinationV1().Leases(ns)
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(duration.Seconds())
+
+	lease, err := leaseInterface.Get(LockName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get Lease %s in namespace %s", LockName, ns)
+		}
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      LockName,
+				Namespace: ns,
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		_, err = leaseInterface.Create(lease)
+		if err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				return nil, errors.Errorf("the boot lock %s in namespace %s was acquired by another process", LockName, ns)
+			}
+			return nil, errors.Wrapf(err, "failed to create Lease %s in namespace %s", LockName, ns)
+		}
+	} else {
+		current := LockHolder(lease)
+		if current != "" && current != holder && !IsLockExpired(lease, time.Now()) {
+			return nil, errors.Errorf("the cluster is already being booted by %s which acquired the lock %s in namespace %s at %s and holds it until %s. If you are sure no other boot is running use --force-unlock",
+				current, LockName, ns, formatMicroTime(lease.Spec.AcquireTime), lockExpiry(lease).Format(time.RFC3339))
+		}
+		if current != holder {
+			lease.Spec.AcquireTime = &now
+			transitions := int32(1)
+			if lease.Spec.LeaseTransitions != nil {
+				transitions = *lease.Spec.LeaseTransitions + 1
+			}
+			lease.Spec.LeaseTransitions = &transitions
+		}
+		lease.Spec.HolderIdentity = &holder
+		lease.Spec.LeaseDurationSeconds = &seconds
+		lease.Spec.RenewTime = &now
+		_, err = leaseInterface.Update(lease)
+		if err != nil {
+			if apierrors.IsConflict(err) {
+				return nil, errors.Errorf("the boot lock %s in namespace %s was acquired by another process", LockName, ns)
+			}
+			return nil, errors.Wrapf(err, "failed to update Lease %s in namespace %s", LockName, ns)
+		}
+	}
+	log.Logger().Debugf("acquired the boot lock %s in namespace %s for %s", LockName, ns, holder)
+	return &Lock{
+		kubeClient: kubeClient,
+		ns:         ns,
+		holder:     holder,
+		duration:   duration,
+		stop:       make(chan struct{}),
+	}, nil
+}
+
+// ForceUnlock removes the boot lock whoever holds it
+func ForceUnlock(kubeClient kubernetes.Interface, ns string) error {
+	err := kubeClient.CoordinationV1().Leases(ns).Delete(LockName, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete Lease %s in namespace %s", LockName, ns)
+	}
+	log.Logger().Infof("removed the boot lock %s in namespace %s", LockName, ns)
+	return nil
+}
+
+// Holder returns the identity of the holder of the lock
+func (l *Lock) Holder() string {
+	return l.holder
+}
+
+// KeepAlive periodically renews the lock in the background until it is released
+func (l *Lock) KeepAlive() {
+	period := l.duration / 3
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				err := l.update(func(lease *coordinationv1.Lease) {
+					now := metav1.NewMicroTime(time.Now())
+					lease.Spec.RenewTime = &now
+				})
+				if err != nil {
+					log.Logger().Warnf("failed to renew the boot lock: %s", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// Transfer transfers the lock to a new holder such as the boot Job. The lock should be transferred before the
+// Job is created so that the Job can acquire it as soon as it starts. Once transferred Release does nothing
+// as only the new holder can release the lock
+func (l *Lock) Transfer(holder string) error {
+	l.stopRenewing()
+	err := l.update(func(lease *coordinationv1.Lease) {
+		now := metav1.NewMicroTime(time.Now())
+		lease.Spec.HolderIdentity = &holder
+		lease.Spec.RenewTime = &now
+	})
+	if err != nil {
+		return err
+	}
+	l.holder = holder
+	l.transferred = true
+	return nil
+}
+
+// Release releases the lock if it is still held by this holder and has not been transferred to another holder
+func (l *Lock) Release() error {
+	l.stopRenewing()
+	if l.transferred {
+		log.Logger().Debugf("not releasing the boot lock %s in namespace %s as it is held by %s", LockName, l.ns, l.holder)
+		return nil
+	}
+	return l.delete()
+}
+
+// CancelTransfer releases a lock which was transferred to a new holder which will never run such as a boot Job
+// which could not be created
+func (l *Lock) CancelTransfer() error {
+	if !l.transferred {
+		return nil
+	}
+	return l.delete()
+}
+
+// delete removes the lease if it is still held by this holder
+func (l *Lock) delete() error {
+	leaseInterface := l.kubeClient.CoordinationV1().Leases(l.ns)
+	lease, err := leaseInterface.Get(LockName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to get Lease %s in namespace %s", LockName, l.ns)
+	}
+	if LockHolder(lease) != l.holder {
+		return nil
+	}
+	err = leaseInterface.Delete(LockName, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete Lease %s in namespace %s", LockName, l.ns)
+	}
+	log.Logger().Debugf("released the boot lock %s in namespace %s", LockName, l.ns)
+	return nil
+}
+
+func (l *Lock) stopRenewing() {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+}
+
+// update modifies the lease if it is still held by this holder
+func (l *Lock) update(fn func(lease *coordinationv1.Lease)) error {
+	leaseInterface := l.kubeClient.CoordinationV1().Leases(l.ns)
+	lease, err := leaseInterface.Get(LockName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get Lease %s in namespace %s", LockName, l.ns)
+	}
+	current := LockHolder(lease)
+	if current != l.holder {
+		return errors.Errorf("the boot lock %s in namespace %s is now held by %s", LockName, l.ns, current)
+	}
+	fn(lease)
+	_, err = leaseInterface.Update(lease)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update Lease %s in namespace %s", LockName, l.ns)
+	}
+	return nil
+}
+
+// LockHolder returns the holder identity of the lease
+func LockHolder(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// IsLockExpired returns true if the lease has not been renewed within its duration
+func IsLockExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	return now.After(lockExpiry(lease))
+}
+
+func lockExpiry(lease *coordinationv1.Lease) time.Time {
+	renewTime := lease.Spec.RenewTime
+	if renewTime == nil {
+		renewTime = lease.Spec.AcquireTime
+	}
+	if renewTime == nil {
+		return lease.CreationTimestamp.Time
+	}
+	duration := DefaultLockDuration
+	if lease.Spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return renewTime.Add(duration)
+}
+
+func formatMicroTime(t *metav1.MicroTime) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// LocalHolderIdentity returns the lock holder identity for a boot run from the command line
+func LocalHolderIdentity() string {
+	name := "unknown"
+	u, err := user.Current()
+	if err == nil && u.Username != "" {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s@%s/%d", name, host, os.Getpid())
+}
+
+// JobHolderIdentity returns the lock holder identity for the given boot Job
+func JobHolderIdentity(jobName string) string {
+	return "job/" + jobName
+}
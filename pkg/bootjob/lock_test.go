@@ -0,0 +1,83 @@
+package bootjob_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/bootjob"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakejxfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestLock(t *testing.T) {
+	f := fakejxfactory.NewFakeFactory()
+	kubeClient, ns, err := f.CreateKubeClient()
+	require.NoError(t, err, "failed to create kube client")
+
+	lock, err := bootjob.AcquireLock(kubeClient, ns, "alice", time.Minute)
+	require.NoError(t, err, "failed to acquire lock")
+
+	_, err = bootjob.AcquireLock(kubeClient, ns, "bob", time.Minute)
+	require.Error(t, err, "should not be able to acquire the lock held by another holder")
+	assert.Contains(t, err.Error(), "alice", "the error should include the holder")
+
+	_, err = bootjob.AcquireLock(kubeClient, ns, "alice", time.Minute)
+	require.NoError(t, err, "the same holder should be able to renew the lock")
+
+	jobHolder := bootjob.JobHolderIdentity("jx-boot-1")
+	err = lock.Transfer(jobHolder)
+	require.NoError(t, err, "failed to transfer the lock")
+
+	_, err = bootjob.AcquireLock(kubeClient, ns, jobHolder, time.Minute)
+	require.NoError(t, err, "the boot Job should be able to acquire the transferred lock")
+
+	err = lock.Release()
+	require.NoError(t, err, "failed to release the lock")
+
+	_, err = bootjob.AcquireLock(kubeClient, ns, "bob", time.Minute)
+	require.Error(t, err, "releasing a transferred lock should not remove the lock held by the boot Job")
+
+	err = lock.CancelTransfer()
+	require.NoError(t, err, "failed to cancel the transfer of the lock")
+
+	_, err = bootjob.AcquireLock(kubeClient, ns, "bob", time.Minute)
+	require.NoError(t, err, "should be able to acquire the lock after the transfer is cancelled")
+
+	err = bootjob.ForceUnlock(kubeClient, ns)
+	require.NoError(t, err, "failed to force unlock")
+
+	_, err = bootjob.AcquireLock(kubeClient, ns, "alice", time.Minute)
+	require.NoError(t, err, "should be able to acquire the lock after a force unlock")
+}
+
+func TestLockExpired(t *testing.T) {
+	ns := "jx"
+	holder := "alice"
+	seconds := int32(60)
+	renewTime := metav1.NewMicroTime(time.Now().Add(-2 * time.Minute))
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bootjob.LockName,
+			Namespace: ns,
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &seconds,
+			AcquireTime:          &renewTime,
+			RenewTime:            &renewTime,
+		},
+	}
+	assert.True(t, bootjob.IsLockExpired(lease, time.Now()), "lease should have expired")
+
+	f := fakejxfactory.NewFakeFactoryWithObjects([]runtime.Object{lease}, nil, ns)
+	kubeClient, _, err := f.CreateKubeClient()
+	require.NoError(t, err, "failed to create kube client")
+
+	lock, err := bootjob.AcquireLock(kubeClient, ns, "bob", time.Minute)
+	require.NoError(t, err, "should be able to acquire an expired lock")
+	assert.Equal(t, "bob", lock.Holder(), "lock holder")
+}
@@ -0,0 +1,193 @@
+package bootjob
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/clienthelpers"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultTimeout the default time to wait for the boot Job to complete
+	DefaultTimeout = 60 * time.Minute
+
+	// DefaultPollPeriod the default period between polls of the boot Job status
+	DefaultPollPeriod = 2 * time.Second
+
+	// DefaultImagePullGracePeriod the default time a pod can fail to pull its image before the boot fails
+	DefaultImagePullGracePeriod = clienthelpers.DefaultImagePullGracePeriod
+
+	// defaultBackoffLimit the kubernetes default backoff limit if the Job does not specify one
+	defaultBackoffLimit = 6
+)
+
+// WaitOptions the options for waiting for the boot Job to complete
+type WaitOptions struct {
+	// Namespace the namespace of the boot Job
+	Namespace string
+
+	// Name the name of the boot Job. Defaults to JobName
+	Name string
+
+	// Timeout the overall time to wait for the Job to complete. Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// PollPeriod the period between polls of the Job status. Defaults to DefaultPollPeriod
+	PollPeriod time.Duration
+
+	// ImagePullGracePeriod the time a pod can fail to pull its image before the Job is failed. Defaults to DefaultImagePullGracePeriod
+	ImagePullGracePeriod time.Duration
+
+	// TailLogs an optional function to tail the logs of a pod of the Job which blocks until the container terminates.
+	// It is invoked in a separate goroutine so that the timeout and failures are still detected while tailing
+	TailLogs func(pod string) error
+}
+
+// WaitForJob waits for the boot Job to complete tailing the logs of each pod.
+// Returns an error if the Job fails, a pod cannot start or the timeout expires
+func WaitForJob(kubeClient kubernetes.Interface, o *WaitOptions) error {
+	ns := o.Namespace
+	name := o.Name
+	if name == "" {
+		name = JobName
+	}
+	timeout := o.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	pollPeriod := o.PollPeriod
+	if pollPeriod <= 0 {
+		pollPeriod = DefaultPollPeriod
+	}
+	gracePeriod := o.ImagePullGracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultImagePullGracePeriod
+	}
+	deadline := time.Now().Add(timeout)
+	selector := "job-name=" + name
+
+	tailed := map[string]bool{}
+	reported := map[string]bool{}
+	tailing := &sync.WaitGroup{}
+	for {
+		job, err := kubeClient.BatchV1().Jobs(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get Job %s in namespace %s", name, ns)
+		}
+		if IsJobComplete(job) {
+			// lets wait for the logs of the completed pods to be fully tailed
+			tailing.Wait()
+			log.Logger().Infof("the Job %s has completed successfully", util.ColorInfo(name))
+			return nil
+		}
+		failed, message := IsJobFailed(job)
+		if failed {
+			tailing.Wait()
+			return errors.Errorf("the Job %s in namespace %s failed: %s", name, ns, message)
+		}
+
+		podList, err := kubeClient.CoreV1().Pods(ns).List(metav1.ListOptions{
+			LabelSelector: selector,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to list pods in namespace %s with selector %s", ns, selector)
+		}
+		pods := podList.Items
+		sort.Slice(pods, func(i, j int) bool {
+			return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
+		})
+		for i := range pods {
+			pod := &pods[i]
+			podName := pod.Name
+			reason, fatal := PodFailure(pod, gracePeriod)
+			if fatal {
+				return errors.Errorf("the pod %s of Job %s cannot start: %s", podName, name, reason)
+			}
+			if o.TailLogs != nil && !tailed[podName] && pod.Status.Phase != corev1.PodPending {
+				tailed[podName] = true
+				tailing.Add(1)
+				go func() {
+					defer tailing.Done()
+					err := o.TailLogs(podName)
+					if err != nil {
+						log.Logger().Warnf("failed to tail the logs of pod %s: %s", podName, err.Error())
+					}
+				}()
+			}
+			if reason != "" && !reported[podName] {
+				reported[podName] = true
+				log.Logger().Warnf("the pod %s of Job %s failed: %s. %d of %d attempts have failed", podName, name, reason, job.Status.Failed, BackoffLimit(job)+1)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return errors.Errorf("timed out after %s waiting for the Job %s in namespace %s to complete", timeout.String(), name, ns)
+		}
+		time.Sleep(pollPeriod)
+	}
+}
+
+// IsJobComplete returns true if the Job has completed successfully
+func IsJobComplete(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobComplete && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// IsJobFailed returns true and a description of the failure if the Job has failed
+func IsJobFailed(job *batchv1.Job) (bool, string) {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			message := c.Reason
+			if c.Message != "" {
+				message = fmt.Sprintf("%s: %s", c.Reason, c.Message)
+			}
+			return true, message
+		}
+	}
+	return false, ""
+}
+
+// BackoffLimit returns the number of retries of the Job
+func BackoffLimit(job *batchv1.Job) int32 {
+	if job.Spec.BackoffLimit != nil {
+		return *job.Spec.BackoffLimit
+	}
+	return defaultBackoffLimit
+}
+
+// PodFailure returns a description of why the pod failed or cannot start or an empty string if it has not failed.
+// The fatal flag indicates the pod can never start such as if its image has not been pulled within the grace period
+func PodFailure(pod *corev1.Pod, imagePullGracePeriod time.Duration) (string, bool) {
+	if s := clienthelpers.WaitingFailure(pod, imagePullGracePeriod); s != nil {
+		return fmt.Sprintf("container %s is %s: %s", s.Name, s.State.Waiting.Reason, s.State.Waiting.Message), true
+	}
+	if pod.Status.Phase != corev1.PodFailed {
+		return "", false
+	}
+	if pod.Status.Reason == "Evicted" {
+		return fmt.Sprintf("the pod was evicted: %s", pod.Status.Message), false
+	}
+	for _, s := range pod.Status.ContainerStatuses {
+		t := s.State.Terminated
+		if t != nil && t.ExitCode != 0 {
+			return fmt.Sprintf("container %s terminated with exit code %d reason %s", s.Name, t.ExitCode, t.Reason), false
+		}
+	}
+	if pod.Status.Reason != "" {
+		return fmt.Sprintf("%s: %s", pod.Status.Reason, pod.Status.Message), false
+	}
+	return "the pod failed", false
+}
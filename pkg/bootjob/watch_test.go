@@ -0,0 +1,213 @@
+package bootjob_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/bootjob"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakejxfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestWaitForJob(t *testing.T) {
+	ns := "jx"
+
+	testCases := []struct {
+		name        string
+		job         *batchv1.Job
+		pods        []*corev1.Pod
+		expectError string
+		expectTail  []string
+		blockTail   bool
+	}{
+		{
+			name:       "complete",
+			job:        createJob(ns, batchv1.JobComplete, "", ""),
+			pods:       []*corev1.Pod{createPod(ns, "jx-boot-abc", corev1.PodSucceeded, nil)},
+			expectTail: nil,
+		},
+		{
+			name:        "failed",
+			job:         createJob(ns, batchv1.JobFailed, "BackoffLimitExceeded", "Job has reached the specified backoff limit"),
+			expectError: "BackoffLimitExceeded: Job has reached the specified backoff limit",
+		},
+		{
+			name: "image-pull-backoff",
+			job:  createJob(ns, "", "", ""),
+			pods: []*corev1.Pod{createPod(ns, "jx-boot-abc", corev1.PodPending, &corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{
+					Reason:  "ImagePullBackOff",
+					Message: "Back-off pulling image",
+				},
+			})},
+			expectError: "container boot is ImagePullBackOff",
+		},
+		{
+			name:        "timeout",
+			job:         createJob(ns, "", "", ""),
+			pods:        []*corev1.Pod{createPod(ns, "jx-boot-abc", corev1.PodRunning, nil)},
+			expectError: "timed out",
+			expectTail:  []string{"jx-boot-abc"},
+		},
+		{
+			name:        "timeout-while-tailing",
+			job:         createJob(ns, "", "", ""),
+			pods:        []*corev1.Pod{createPod(ns, "jx-boot-abc", corev1.PodRunning, nil)},
+			expectError: "timed out",
+			expectTail:  []string{"jx-boot-abc"},
+			blockTail:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		kubeObjects := []runtime.Object{tc.job}
+		for _, p := range tc.pods {
+			kubeObjects = append(kubeObjects, p)
+		}
+		f := fakejxfactory.NewFakeFactoryWithObjects(kubeObjects, nil, ns)
+		kubeClient, _, err := f.CreateKubeClient()
+		require.NoError(t, err, "failed to create kube client")
+
+		var tailed []string
+		lock := sync.Mutex{}
+		stopTail := make(chan struct{})
+		blockTail := tc.blockTail
+		o := &bootjob.WaitOptions{
+			Namespace:  ns,
+			Timeout:    50 * time.Millisecond,
+			PollPeriod: 10 * time.Millisecond,
+			TailLogs: func(pod string) error {
+				lock.Lock()
+				tailed = append(tailed, pod)
+				lock.Unlock()
+				if blockTail {
+					<-stopTail
+				}
+				return nil
+			},
+		}
+		err = bootjob.WaitForJob(kubeClient, o)
+		close(stopTail)
+		lock.Lock()
+		if tc.expectError != "" {
+			require.Error(t, err, "should have failed for test %s", tc.name)
+			assert.Contains(t, err.Error(), tc.expectError, "error for test %s", tc.name)
+		} else {
+			require.NoError(t, err, "should not have failed for test %s", tc.name)
+		}
+		assert.Equal(t, tc.expectTail, tailed, "tailed pods for test %s", tc.name)
+		lock.Unlock()
+	}
+}
+
+func TestPodFailure(t *testing.T) {
+	pulling := createPod("jx", "pulling", corev1.PodPending, &corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{
+			Reason:  "ImagePullBackOff",
+			Message: "Back-off pulling image",
+		},
+	})
+	pulling.CreationTimestamp = metav1.Now()
+
+	evicted := createPod("jx", "evicted", corev1.PodFailed, nil)
+	evicted.Status.Reason = "Evicted"
+	evicted.Status.Message = "The node was low on resource: memory"
+
+	testCases := []struct {
+		name          string
+		pod           *corev1.Pod
+		expectMessage string
+		expectFatal   bool
+	}{
+		{
+			name: "running",
+			pod:  createPod("jx", "running", corev1.PodRunning, nil),
+		},
+		{
+			name:          "evicted",
+			pod:           evicted,
+			expectMessage: "the pod was evicted: The node was low on resource: memory",
+		},
+		{
+			name: "error",
+			pod: createPod("jx", "error", corev1.PodFailed, &corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1,
+					Reason:   "Error",
+				},
+			}),
+			expectMessage: "container boot terminated with exit code 1 reason Error",
+		},
+		{
+			name: "err-image-pull",
+			pod: createPod("jx", "err-image-pull", corev1.PodPending, &corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{
+					Reason:  "ErrImagePull",
+					Message: "manifest unknown",
+				},
+			}),
+			expectMessage: "container boot is ErrImagePull: manifest unknown",
+			expectFatal:   true,
+		},
+		{
+			name: "image-pull-within-grace-period",
+			pod:  pulling,
+		},
+	}
+
+	for _, tc := range testCases {
+		message, fatal := bootjob.PodFailure(tc.pod, time.Minute)
+		assert.Equal(t, tc.expectMessage, message, "message for test %s", tc.name)
+		assert.Equal(t, tc.expectFatal, fatal, "fatal for test %s", tc.name)
+	}
+}
+
+func createJob(ns string, conditionType batchv1.JobConditionType, reason, message string) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bootjob.JobName,
+			Namespace: ns,
+		},
+	}
+	if conditionType != "" {
+		job.Status.Conditions = []batchv1.JobCondition{
+			{
+				Type:    conditionType,
+				Status:  corev1.ConditionTrue,
+				Reason:  reason,
+				Message: message,
+			},
+		}
+	}
+	return job
+}
+
+func createPod(ns, name string, phase corev1.PodPhase, state *corev1.ContainerState) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels: map[string]string{
+				"job-name": bootjob.JobName,
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: phase,
+		},
+	}
+	if state != nil {
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+			{
+				Name:  bootjob.ContainerName,
+				State: *state,
+			},
+		}
+	}
+	return pod
+}
@@ -0,0 +1,45 @@
+package clienthelpers
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultImagePullGracePeriod the default time a pod can fail to pull its image before it is considered failed as
+// pulling an image can fail briefly while a registry is unavailable or the image is still being pushed
+const DefaultImagePullGracePeriod = 2 * time.Minute
+
+// fatalWaitingReasons the container waiting reasons which will not resolve by themselves
+var fatalWaitingReasons = map[string]bool{
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+}
+
+// imagePullWaitingReasons the container waiting reasons which are fatal if they last longer than the image pull grace period
+var imagePullWaitingReasons = map[string]bool{
+	"ErrImagePull":     true,
+	"ImagePullBackOff": true,
+}
+
+// WaitingFailure returns the status of the first container of the pod which is waiting for a reason which will not
+// resolve by itself, such as an invalid image name, an image which has not been pulled within the grace period or
+// one of the additional fatal reasons. Returns nil if no container has failed
+func WaitingFailure(pod *corev1.Pod, imagePullGracePeriod time.Duration, fatalReasons ...string) *corev1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		s := &pod.Status.ContainerStatuses[i]
+		w := s.State.Waiting
+		if w == nil {
+			continue
+		}
+		if fatalWaitingReasons[w.Reason] || (imagePullWaitingReasons[w.Reason] && time.Since(pod.CreationTimestamp.Time) > imagePullGracePeriod) {
+			return s
+		}
+		for _, r := range fatalReasons {
+			if w.Reason == r {
+				return s
+			}
+		}
+	}
+	return nil
+}
@@ -28,6 +28,9 @@ var (
 	createExample = templates.Examples(`
 		# create a new git repository which we can then boot up
 		%s create
+
+		# create a new git repository explaining which apps were added or removed based on the requirements
+		%s create --explain
 	`)
 )
 
@@ -35,6 +38,7 @@ var (
 type CreateOptions struct {
 	envfactory.EnvFactory
 	DisableVerifyPackages bool
+	Explain               bool
 	Requirements          config.RequirementsConfig
 	Flags                 reqhelpers.RequirementFlags
 	InitialGitURL         string
@@ -51,7 +55,7 @@ func NewCmdCreate() (*cobra.Command, *CreateOptions) {
 		Use:     "create",
 		Short:   "Creates a new git repository for a new Jenkins X installation",
 		Long:    createLong,
-		Example: fmt.Sprintf(createExample, common.BinaryName),
+		Example: fmt.Sprintf(createExample, common.BinaryName, common.BinaryName),
 		Run: func(cmd *cobra.Command, args []string) {
 			o.Cmd = cmd
 			o.Args = args
@@ -62,6 +66,7 @@ func NewCmdCreate() (*cobra.Command, *CreateOptions) {
 	o.Cmd = cmd
 
 	cmd.Flags().StringVarP(&o.InitialGitURL, "initial-git-url", "", "", "The git URL to clone to fetch the initial set of files for a helm 3 / helmfile based git configuration if this command is not run inside a git clone or against a GitOps based cluster")
+	cmd.Flags().BoolVarP(&o.Explain, "explain", "", false, "Explains which rules added or removed apps in the jx-apps.yml file")
 	cmd.Flags().StringVarP(&o.Dir, "dir", "", "", "The directory used to create the development environment git repository inside. If not specified a temporary directory will be used")
 
 	reqhelpers.AddRequirementsFlagsOptions(cmd, &o.Flags)
@@ -82,15 +87,18 @@ func (o *CreateOptions) Run() error {
 		return err
 	}
 
-	err = reqhelpers.OverrideRequirements(o.Cmd, o.Args, dir, &o.Requirements, &o.Flags)
+	err = reqhelpers.OverrideRequirements(o.Cmd, o.Args, dir, "", &o.Requirements, &o.Flags)
 	if err != nil {
 		return errors.Wrapf(err, "failed to override requirements in dir %s", dir)
 	}
 
-	_, _, err = reqhelpers.ValidateApps(dir)
+	_, _, changes, err := reqhelpers.ValidateAppsWithRules(dir, &o.Requirements, "")
 	if err != nil {
 		return errors.Wrapf(err, "failed to validate the apps based on requirements in dir %s", dir)
 	}
+	if o.Explain {
+		reqhelpers.ExplainAppChanges(changes)
+	}
 
 	err = o.EnvFactory.VerifyPreInstall(o.DisableVerifyPackages, dir)
 	if err != nil {
@@ -103,7 +111,7 @@ func (o *CreateOptions) Run() error {
 	if err != nil {
 		return err
 	}
-	return o.EnvFactory.CreateDevEnvGitRepository(dir, o.Flags.EnvironmentGitPublic)
+	return o.EnvFactory.CreateDevEnvGitRepository(dir, &o.Requirements, o.Flags.EnvironmentGitPublic)
 }
 
 // gitCloneIfRequired if the specified directory is already a git clone then lets just use it
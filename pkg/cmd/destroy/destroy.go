@@ -2,25 +2,35 @@ package destroy
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/jenkins-x-labs/helmboot/pkg/backup"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
 	"github.com/jenkins-x-labs/helmboot/pkg/common"
 	"github.com/jenkins-x-labs/helmboot/pkg/githelpers"
+	"github.com/jenkins-x-labs/helmboot/pkg/helmfiles"
+	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
 	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr"
 	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr/factory"
+	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/jx/pkg/cmd/clients"
 	"github.com/jenkins-x/jx/pkg/cmd/helper"
 	"github.com/jenkins-x/jx/pkg/cmd/opts"
 	"github.com/jenkins-x/jx/pkg/cmd/step/create/helmfile"
 	"github.com/jenkins-x/jx/pkg/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/config"
 	"github.com/jenkins-x/jx/pkg/gits"
 	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/table"
 	"github.com/jenkins-x/jx/pkg/util"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -31,18 +41,92 @@ type Options struct {
 	KindResolver          factory.KindResolver
 	Gitter                gits.Gitter
 	Dir                   string
+	Only                  string
+	Releases              []string
+	DryRun                bool
+	KeepSecrets           bool
+	Purge                 bool
+	PurgeCRDs             bool
+	NoBackup              bool
+	BackupLocation        string
+	BackupPassphrase      string
 	BatchMode             bool
+	Out                   io.Writer
+
+	// CommandRunner runs the helmfile and helm commands so that they can be faked in tests
+	CommandRunner cmdrunner.CommandRunner
+
+	// HelmfileGenerator generates the helmfiles in the directory
+	HelmfileGenerator func(dir string) error
+
+	// ScmClientFactory optionally creates the SCM client used to remove webhooks when purging
+	ScmClientFactory func(serverURL, owner, kind string) (*scm.Client, error)
+
+	// APIExtensionsClient the client used to remove the CRDs when purging. Lazily created if not specified
+	APIExtensionsClient apiextensionsclientset.Interface
+
+	// Report the releases and secrets which were removed or would be removed if using --dry-run
+	Report Report
+}
+
+// Report the resources removed by the destroy command
+type Report struct {
+	Releases []RemovedRelease
+	Secrets  []string
+
+	// Backup the path or URL of the backup bundle created before removing anything
+	Backup string
+
+	// Purged the namespaces, CRDs, PVCs and webhooks removed via --purge
+	Purged []*PurgeItem
+
+	// NotRemoved the resources which could not be removed via --purge
+	NotRemoved []*PurgeItem
+}
+
+// RemovedRelease a release removed from a helmfile directory
+type RemovedRelease struct {
+	helmfiles.Release
+	Dir string
 }
 
 var (
 	destroyLong = templates.LongDesc(`
 		This command destroys all of the charts installed via the 'jx-apps.yml' file
 
+		You can destroy just the apps or system charts via --only or specific releases via --release.
+		The boot secrets are only removed when destroying all of the charts unless you specify --keep-secrets
+
+		Use --purge to also remove the environment namespaces, the PVCs created by the charts and the webhooks registered on the git repositories.
+		As CRDs are cluster wide the Jenkins X CRDs and all of their resources in every namespace are only removed if you also specify --purge-crds
+
+		An encrypted backup bundle is created before removing anything so that you can use 'restore' to re-run boot unless you specify --no-backup.
+		The bundle is written to the backup storage URL in the 'jx-requirements.yml' or the current directory
+
 `)
 
 	destroyExample = templates.Examples(`
-		# destroy the helm charts installed via 'jx-apps.yml'
-		%s destroy 
+		# destroy the helm charts installed via 'jx-apps.yml' after writing a backup bundle to the current directory
+		export JX_BACKUP_PASSPHRASE="my secret passphrase"
+		%s destroy
+
+		# view the releases which would be removed without removing them
+		%s destroy --dry-run
+
+		# destroy just the apps charts keeping the system charts and boot secrets
+		%s destroy --only apps
+
+		# destroy a single release
+		%s destroy --release lighthouse
+
+		# remove everything including the environment namespaces, PVCs, webhooks and the Jenkins X CRDs
+		%s destroy --purge --purge-crds
+
+		# destroy everything after writing a backup bundle to a bucket
+		%s destroy --backup-location gs://mybucket/backups
+
+		# destroy everything without creating a backup bundle
+		%s destroy --no-backup
 `)
 
 	dummySecretYaml = `foo: bar`
@@ -55,7 +139,7 @@ func NewCmdDestroy() *cobra.Command {
 		Use:     "destroy",
 		Short:   "destroys all of the charts installed via the 'jx-apps.yml' file",
 		Long:    destroyLong,
-		Example: fmt.Sprintf(destroyExample, common.BinaryName, common.BinaryName),
+		Example: fmt.Sprintf(destroyExample, common.BinaryName, common.BinaryName, common.BinaryName, common.BinaryName, common.BinaryName, common.BinaryName, common.BinaryName),
 		Run: func(command *cobra.Command, args []string) {
 			common.SetLoggingLevel(command, args)
 			err := options.Run()
@@ -63,6 +147,16 @@ func NewCmdDestroy() *cobra.Command {
 		},
 	}
 	command.Flags().StringVarP(&options.KindResolver.GitURL, "git-url", "u", "", "override the Git clone URL for the JX Boot source to start from, ignoring the versions stream. Normally specified with git-ref as well")
+	command.Flags().StringVarP(&options.KindResolver.Overlay, "overlay", "", os.Getenv(reqhelpers.OverlayEnvVar), "the requirements overlay in requirements/clusters used to compose the requirements with requirements/base.yml. Defaults to $"+reqhelpers.OverlayEnvVar)
+	command.Flags().StringVarP(&options.Only, "only", "", "", "only destroy the charts in the given helmfile directory. Possible values: "+strings.Join(helmfiles.Dirs, ", "))
+	command.Flags().StringArrayVarP(&options.Releases, "release", "r", nil, "the names of the releases to destroy. If not specified all releases are destroyed")
+	command.Flags().BoolVarP(&options.DryRun, "dry-run", "", false, "lists the releases which would be removed without removing them")
+	command.Flags().BoolVarP(&options.KeepSecrets, "keep-secrets", "", false, "do not remove the boot secrets")
+	command.Flags().BoolVarP(&options.Purge, "purge", "", false, "also removes the environment namespaces, the PVCs created by the charts and the webhooks registered on the git repositories")
+	command.Flags().BoolVarP(&options.PurgeCRDs, "purge-crds", "", false, "when purging also removes the Jenkins X CRDs which removes their resources in all namespaces of the cluster")
+	command.Flags().BoolVarP(&options.NoBackup, "no-backup", "", false, "do not create an encrypted backup bundle of the secrets, git URL, requirements, releases, SourceRepositories and PipelineActivities before removing anything")
+	command.Flags().StringVarP(&options.BackupLocation, "backup-location", "", "", "the local directory or bucket URL (gs:// or s3://) to write the backup bundle to. Defaults to the backup storage URL in the jx-requirements.yml or the current directory")
+	command.Flags().StringVarP(&options.BackupPassphrase, "backup-passphrase", "", os.Getenv(backup.PassphraseEnvVar), "the passphrase used to encrypt the backup bundle. Defaults to $"+backup.PassphraseEnvVar)
 	command.Flags().BoolVarP(&options.BatchMode, "batch-mode", "b", false, "Runs in batch mode without prompting for user input")
 
 	return command
@@ -70,11 +164,36 @@ func NewCmdDestroy() *cobra.Command {
 
 // Run implements the command
 func (o *Options) Run() error {
+	if o.Only != "" && util.StringArrayIndex(helmfiles.Dirs, o.Only) < 0 {
+		return util.InvalidOption("only", o.Only, helmfiles.Dirs)
+	}
+	if o.Purge && (o.Only != "" || len(o.Releases) > 0) {
+		return errors.Errorf("--purge cannot be used with --only or --release")
+	}
+	if o.PurgeCRDs && !o.Purge {
+		return errors.Errorf("--purge-crds can only be used with --purge")
+	}
+	if !o.DryRun && !o.NoBackup && o.BackupPassphrase == "" {
+		return errors.Errorf("please specify a passphrase to encrypt the backup bundle via --backup-passphrase or $%s or use --no-backup to destroy without a backup", backup.PassphraseEnvVar)
+	}
 	if o.CreateHelmfileOptions.CommonOptions == nil {
 		f := clients.NewFactory()
 		o.CreateHelmfileOptions.CommonOptions = opts.NewCommonOptionsWithTerm(f, os.Stdin, os.Stdout, os.Stderr)
 		o.CreateHelmfileOptions.CommonOptions.BatchMode = o.BatchMode
 	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.DefaultCommandRunner
+	}
+	if o.HelmfileGenerator == nil {
+		o.HelmfileGenerator = func(dir string) error {
+			o.CreateHelmfileOptions.Dir = dir
+			o.CreateHelmfileOptions.IgnoreNamespaceCheck = true
+			return o.CreateHelmfileOptions.Run()
+		}
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
 	gitURL := o.KindResolver.GitURL
 	if gitURL == "" {
 		var err error
@@ -89,12 +208,57 @@ func (o *Options) Run() error {
 		return errors.Wrapf(err, "failed to clone Git URL %s", gitURL)
 	}
 
-	o.CreateHelmfileOptions.Dir = dir
-	o.CreateHelmfileOptions.IgnoreNamespaceCheck = true
-	err = o.CreateHelmfileOptions.Run()
+	requirements, fileName, err := reqhelpers.LoadRequirements(dir, o.KindResolver.Overlay)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the requirements in dir %s", dir)
+	}
+	if reqhelpers.HasRequirementsOverlays(dir) {
+		// the helmfiles are generated from jx-requirements.yml so lets save the requirements composed from the overlay
+		err = requirements.SaveConfig(fileName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to save the composed requirements to %s", fileName)
+		}
+	}
+	err = o.HelmfileGenerator(dir)
 	if err != nil {
 		return errors.Wrapf(err, "failed to generate the helmfiles to %s", dir)
 	}
+	if o.KindResolver.Requirements == nil {
+		o.KindResolver.Requirements = requirements
+	}
+
+	o.Report = Report{}
+	selected, err := o.selectReleases(dir)
+	if err != nil {
+		return err
+	}
+	destroyAll := o.Only == "" && len(o.Releases) == 0
+	removeSecrets := destroyAll && !o.KeepSecrets
+	if removeSecrets {
+		o.Report.Secrets = []string{secretmgr.BootGitURLSecret, secretmgr.LocalSecret}
+	}
+
+	var purgeItems []*PurgeItem
+	if o.Purge {
+		var releases []RemovedRelease
+		for _, d := range helmfiles.Dirs {
+			releases = append(releases, selected[d]...)
+		}
+		purgeItems, err = o.findPurgeItems(requirements, gitURL, releases)
+		if err != nil {
+			return errors.Wrap(err, "failed to find the resources to purge")
+		}
+	}
+
+	if o.DryRun {
+		for i := len(helmfiles.Dirs) - 1; i >= 0; i-- {
+			o.Report.Releases = append(o.Report.Releases, selected[helmfiles.Dirs[i]]...)
+		}
+		o.Report.Purged = purgeItems
+		log.Logger().Infof("dry run so not removing anything. The following would be removed:")
+		o.renderReport()
+		return nil
+	}
 
 	if !o.BatchMode {
 		c, err := util.Confirm("You are about to destroy your boot installation. Are you sure?", false, "Destroying your installation will preserve your kubernetes cluster and the underlying cloud resources so you can re-run boot again", o.CreateHelmfileOptions.CommonOptions.GetIOFileHandles())
@@ -104,6 +268,30 @@ func (o *Options) Run() error {
 		if !c {
 			return nil
 		}
+		if o.Purge {
+			message := "You are about to purge the environment namespaces, PVCs and webhooks. Are you sure?"
+			help := "Purging removes the environment namespaces and the data in the PVCs of the charts"
+			if o.PurgeCRDs {
+				message = "You are about to purge the environment namespaces, PVCs, webhooks and the Jenkins X CRDs in ALL namespaces of the cluster. Are you sure?"
+				help = "Removing the CRDs removes all of the Jenkins X resources and data such as pipeline history in every namespace of the cluster"
+			}
+			c, err = util.Confirm(message, false, help, o.CreateHelmfileOptions.CommonOptions.GetIOFileHandles())
+			if err != nil {
+				return err
+			}
+			if !c {
+				return nil
+			}
+		}
+	}
+
+	if o.NoBackup {
+		log.Logger().Warnf("not creating a backup bundle as --no-backup was specified so you will not be able to restore the secrets, SourceRepositories and PipelineActivities via '%s restore'", common.BinaryName)
+	} else {
+		err = o.backup(dir, requirements)
+		if err != nil {
+			return errors.Wrap(err, "failed to create the backup bundle")
+		}
 	}
 
 	log.Logger().Infof("destroying the boot installation using temporary dir: %s", dir)
@@ -117,32 +305,151 @@ func (o *Options) Run() error {
 	env := map[string]string{
 		"JX_SECRETS_YAML": secretsYaml,
 	}
-	log.Logger().Infof("removing the apps charts...")
-	err = o.runCommand(filepath.Join(dir, "apps"), env, "helmfile", "destroy")
-	if err != nil {
-		return err
+
+	// lets remove the apps before the system charts they depend on
+	for i := len(helmfiles.Dirs) - 1; i >= 0; i-- {
+		d := helmfiles.Dirs[i]
+		var names []string
+		for _, r := range selected[d] {
+			names = append(names, r.Name)
+		}
+		if len(names) == 0 {
+			continue
+		}
+		args := []string{}
+		if len(o.Releases) > 0 {
+			for _, name := range names {
+				args = append(args, "--selector", "name="+name)
+			}
+		}
+		args = append(args, "destroy")
+
+		log.Logger().Infof("removing the %s charts: %s", d, util.ColorInfo(strings.Join(names, ", ")))
+		err = o.runCommand(filepath.Join(dir, d), env, "helmfile", args...)
+		if err != nil {
+			return err
+		}
+		o.Report.Releases = append(o.Report.Releases, selected[d]...)
+	}
+
+	if destroyAll {
+		err = o.runCommand(".", env, "helm", "delete", "jx-boot")
+		if err != nil {
+			log.Logger().Debugf("failed to remove the jx-boot chart: %s", err.Error())
+		}
+	}
+
+	if o.Purge {
+		o.purge(purgeItems)
+	}
+
+	if removeSecrets {
+		err = o.removeSecrets(o.Report.Secrets...)
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Logger().Infof("keeping the boot secrets")
+	}
+
+	o.renderReport()
+	if len(o.Report.NotRemoved) > 0 {
+		log.Logger().Warnf("the following resources could not be removed:")
+		t := table.CreateTable(o.Out)
+		t.AddRow("KIND", "NAMESPACE", "NAME", "ERROR")
+		for _, item := range o.Report.NotRemoved {
+			t.AddRow(item.Kind, item.Namespace, item.Name, item.Error)
+		}
+		t.Render()
+	}
+	log.Logger().Infof("chart removal complete. You can run 'jxl boot run' to reinstall")
+	return nil
+}
+
+// selectReleases returns the releases to remove for each helmfile directory
+func (o *Options) selectReleases(dir string) (map[string][]RemovedRelease, error) {
+	answer := map[string][]RemovedRelease{}
+	found := map[string]bool{}
+	for _, d := range helmfiles.Dirs {
+		if o.Only != "" && o.Only != d {
+			continue
+		}
+		releases, err := helmfiles.LoadReleases(filepath.Join(dir, d, helmfiles.HelmfileName))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range releases {
+			if len(o.Releases) > 0 && util.StringArrayIndex(o.Releases, r.Name) < 0 {
+				continue
+			}
+			found[r.Name] = true
+			answer[d] = append(answer[d], RemovedRelease{Release: r, Dir: d})
+		}
+	}
+	for _, name := range o.Releases {
+		if !found[name] {
+			return nil, errors.Errorf("could not find release %s in the helmfiles", name)
+		}
+	}
+	return answer, nil
+}
+
+// backup writes the backup bundle of the releases, secrets, git URL and Jenkins X resources
+func (o *Options) backup(dir string, requirements *config.RequirementsConfig) error {
+	var releases []helmfiles.Release
+	for _, d := range helmfiles.Dirs {
+		dirReleases, err := helmfiles.LoadReleases(filepath.Join(dir, d, helmfiles.HelmfileName))
+		if err != nil {
+			return err
+		}
+		releases = append(releases, dirReleases...)
 	}
 
-	log.Logger().Infof("removing the system charts...")
-	err = o.runCommand(filepath.Join(dir, "system"), env, "helmfile", "destroy")
+	bundle, err := backup.Collect(o.CommandRunner, &o.KindResolver, releases)
 	if err != nil {
 		return err
 	}
-
-	err = o.runCommand(".", env, "helm", "delete", "jx-boot")
+	data, err := bundle.Marshal(o.BackupPassphrase)
 	if err != nil {
-		log.Logger().Debugf("failed to remove the jx-boot chart: %s", err.Error())
+		return err
 	}
 
-	err = o.removeSecrets(secretmgr.BootGitURLSecret, secretmgr.LocalSecret)
+	location := o.BackupLocation
+	if location == "" {
+		storage := requirements.Storage.Backup
+		if storage.Enabled && storage.URL != "" {
+			location = storage.URL
+		} else {
+			location = "."
+		}
+	}
+	o.Report.Backup, err = backup.Save(o.CommandRunner, location, backup.FileName(requirements.Cluster.ClusterName, time.Now()), data)
 	if err != nil {
 		return err
 	}
-
-	log.Logger().Infof("chart removal complete. You can run 'jxl boot run' to reinstall")
+	log.Logger().Infof("saved the backup bundle to %s. You can use '%s restore %s' to restore it", util.ColorInfo(o.Report.Backup), common.BinaryName, o.Report.Backup)
 	return nil
 }
 
+// renderReport renders the releases and secrets which were removed
+func (o *Options) renderReport() {
+	t := table.CreateTable(o.Out)
+	t.AddRow("KIND", "NAMESPACE", "NAME", "CHART")
+	for _, r := range o.Report.Releases {
+		t.AddRow(r.Dir+" release", r.Namespace, r.Name, r.Chart)
+	}
+	for _, name := range o.Report.Secrets {
+		t.AddRow("Secret", "", name, "")
+	}
+	for _, item := range o.Report.Purged {
+		t.AddRow(item.Kind, item.Namespace, item.Name, "")
+	}
+	if o.Report.Backup != "" {
+		t.AddRow("Backup", "", o.Report.Backup, "")
+	}
+	t.Render()
+}
+
 // Git lazily create a gitter if its not specified
 func (o *Options) Git() gits.Gitter {
 	if o.Gitter == nil {
@@ -159,13 +466,13 @@ func (o *Options) runCommand(dir string, env map[string]string, cmd string, args
 	if !exists {
 		return fmt.Errorf("directory does not exist %s", dir)
 	}
-	c := util.Command{
+	c := &util.Command{
 		Name: cmd,
 		Args: args,
 		Dir:  dir,
 		Env:  env,
 	}
-	_, err = c.RunWithoutRetry()
+	_, err = o.CommandRunner(c)
 	if err != nil {
 		return errors.Wrapf(err, "failed to run command: %s %s in dir %s", cmd, strings.Join(args, " "), dir)
 	}
@@ -0,0 +1,379 @@
+package destroy_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/backup"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/destroy"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakejxfactory"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakerunner"
+	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr"
+	"github.com/jenkins-x/go-scm/scm"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/gits"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// gitFakeCopy a fake Gitter which clones by copying a local directory
+type gitFakeCopy struct {
+	gits.GitFake
+}
+
+func (f *gitFakeCopy) Clone(url string, directory string) error {
+	return util.CopyDir(url, directory, true)
+}
+
+func TestDestroy(t *testing.T) {
+	ns := "jx"
+	sourceDir := filepath.Join("test_data", "dev-env")
+	secretsYAML := "secrets:\n  adminUser:\n    username: admin\n    password: mypassword\n"
+	passphrase := "my-passphrase"
+
+	testCases := []struct {
+		name           string
+		only           string
+		releases       []string
+		dryRun         bool
+		keepSecrets    bool
+		noBackup       bool
+		expectCommands []string
+		expectReleases []string
+		expectSecrets  []string
+		expectError    string
+	}{
+		{
+			name:     "all",
+			noBackup: true,
+			expectCommands: []string{
+				"helmfile destroy",
+				"helmfile destroy",
+				"helm delete jx-boot",
+			},
+			expectReleases: []string{"jx/lighthouse", "cert-manager/cert-manager"},
+			expectSecrets:  []string{secretmgr.BootGitURLSecret, secretmgr.LocalSecret},
+		},
+		{
+			name:           "dry-run",
+			dryRun:         true,
+			expectReleases: []string{"jx/lighthouse", "cert-manager/cert-manager"},
+			expectSecrets:  []string{secretmgr.BootGitURLSecret, secretmgr.LocalSecret},
+		},
+		{
+			name:           "only-apps",
+			only:           "apps",
+			noBackup:       true,
+			expectCommands: []string{"helmfile destroy"},
+			expectReleases: []string{"jx/lighthouse"},
+		},
+		{
+			name:           "release",
+			releases:       []string{"cert-manager"},
+			noBackup:       true,
+			expectCommands: []string{"helmfile --selector name=cert-manager destroy"},
+			expectReleases: []string{"cert-manager/cert-manager"},
+		},
+		{
+			name:           "keep-secrets",
+			keepSecrets:    true,
+			noBackup:       true,
+			expectCommands: []string{"helmfile destroy", "helmfile destroy", "helm delete jx-boot"},
+			expectReleases: []string{"jx/lighthouse", "cert-manager/cert-manager"},
+		},
+		{
+			name: "backup",
+			expectCommands: []string{
+				"helm get values cert-manager --namespace cert-manager --output yaml",
+				"helm get values lighthouse --namespace jx --output yaml",
+				"helmfile destroy",
+				"helmfile destroy",
+				"helm delete jx-boot",
+			},
+			expectReleases: []string{"jx/lighthouse", "cert-manager/cert-manager"},
+			expectSecrets:  []string{secretmgr.BootGitURLSecret, secretmgr.LocalSecret},
+		},
+		{
+			name:        "missing-release",
+			releases:    []string{"does-not-exist"},
+			noBackup:    true,
+			expectError: "could not find release does-not-exist",
+		},
+		{
+			name:        "no-passphrase",
+			expectError: "passphrase",
+		},
+		{
+			name:        "invalid-only",
+			only:        "cheese",
+			expectError: "cheese",
+		},
+	}
+
+	for _, tc := range testCases {
+		var kubeObjects []runtime.Object
+		for _, name := range []string{secretmgr.BootGitURLSecret, secretmgr.LocalSecret} {
+			kubeObjects = append(kubeObjects, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: ns,
+				},
+				Data: map[string][]byte{
+					secretmgr.LocalSecretKey: []byte(secretsYAML),
+				},
+			})
+		}
+		f := fakejxfactory.NewFakeFactoryWithObjects(kubeObjects, nil, ns)
+
+		tmpDir, err := ioutil.TempDir("", "helmboot-destroy-")
+		require.NoError(t, err, "failed to create temp dir")
+		defer os.RemoveAll(tmpDir)
+
+		runner := &fakerunner.FakeRunner{
+			Results: map[string]string{
+				"helm get values lighthouse --namespace jx --output yaml": "replicaCount: 2\n",
+			},
+		}
+		o := &destroy.Options{
+			Gitter:      &gitFakeCopy{},
+			Dir:         tmpDir,
+			Only:        tc.only,
+			Releases:    tc.releases,
+			DryRun:      tc.dryRun,
+			KeepSecrets: tc.keepSecrets,
+			NoBackup:    tc.noBackup,
+			BatchMode:   true,
+			Out:         ioutil.Discard,
+			HelmfileGenerator: func(dir string) error {
+				return nil
+			},
+			CommandRunner: runner.Run,
+		}
+		o.KindResolver.GitURL = sourceDir
+		o.KindResolver.Kind = secretmgr.KindLocal
+		o.KindResolver.Factory = f
+		if !tc.noBackup && tc.name != "no-passphrase" {
+			o.BackupPassphrase = passphrase
+			o.BackupLocation = filepath.Join(tmpDir, "backups")
+		}
+
+		err = o.Run()
+		if tc.expectError != "" {
+			require.Error(t, err, "should have failed for test %s", tc.name)
+			assert.Contains(t, err.Error(), tc.expectError, "error for test %s", tc.name)
+			continue
+		}
+		require.NoError(t, err, "failed to run destroy for test %s", tc.name)
+
+		assert.Equal(t, tc.expectCommands, runner.CommandLines(), "commands for test %s", tc.name)
+		var releases []string
+		for _, r := range o.Report.Releases {
+			releases = append(releases, r.Namespace+"/"+r.Name)
+		}
+		assert.Equal(t, tc.expectReleases, releases, "releases for test %s", tc.name)
+		assert.Equal(t, tc.expectSecrets, o.Report.Secrets, "secrets for test %s", tc.name)
+
+		if !tc.noBackup && !tc.dryRun {
+			require.NotEmpty(t, o.Report.Backup, "should have created a backup for test %s", tc.name)
+			data, err := ioutil.ReadFile(o.Report.Backup)
+			require.NoError(t, err, "failed to load backup for test %s", tc.name)
+			bundle, err := backup.Unmarshal(data, passphrase)
+			require.NoError(t, err, "failed to unmarshal backup for test %s", tc.name)
+			assert.Equal(t, sourceDir, bundle.GitURL, "backup git URL for test %s", tc.name)
+			assert.Equal(t, secretsYAML, bundle.SecretsYAML, "backup secrets for test %s", tc.name)
+			require.Len(t, bundle.Releases, 2, "backup releases for test %s", tc.name)
+			assert.Empty(t, bundle.Releases[0].Values, "backup values of release %s for test %s", bundle.Releases[0].Name, tc.name)
+			assert.Equal(t, map[string]interface{}{"replicaCount": float64(2)}, bundle.Releases[1].Values, "backup values of release %s for test %s", bundle.Releases[1].Name, tc.name)
+		} else {
+			assert.Empty(t, o.Report.Backup, "should not have created a backup for test %s", tc.name)
+		}
+
+		kubeClient, _, err := f.CreateKubeClient()
+		require.NoError(t, err, "failed to create kube client")
+		list, err := kubeClient.CoreV1().Secrets(ns).List(metav1.ListOptions{})
+		require.NoError(t, err, "failed to list secrets")
+		expectRemaining := 2
+		if len(tc.expectSecrets) > 0 && !tc.dryRun {
+			expectRemaining = 0
+		}
+		assert.Len(t, list.Items, expectRemaining, "remaining secrets for test %s", tc.name)
+	}
+}
+
+// fakeRepositoryService a fake SCM repository service which manages webhooks in memory
+type fakeRepositoryService struct {
+	scm.RepositoryService
+	hooks map[string][]*scm.Hook
+}
+
+func (s *fakeRepositoryService) ListHooks(ctx context.Context, repo string, opts scm.ListOptions) ([]*scm.Hook, *scm.Response, error) {
+	return s.hooks[repo], nil, nil
+}
+
+func (s *fakeRepositoryService) DeleteHook(ctx context.Context, repo string, id string) (*scm.Response, error) {
+	var hooks []*scm.Hook
+	for _, h := range s.hooks[repo] {
+		if h.ID != id {
+			hooks = append(hooks, h)
+		}
+	}
+	s.hooks[repo] = hooks
+	return nil, nil
+}
+
+func TestDestroyPurge(t *testing.T) {
+	ns := "jx"
+	sourceDir := filepath.Join("test_data", "dev-env")
+
+	kubeObjects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "jx-staging"}},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "lighthouse-data",
+				Namespace: ns,
+				Labels: map[string]string{
+					"release": "lighthouse",
+				},
+			},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "not-ours",
+				Namespace: ns,
+			},
+		},
+	}
+	jxObjects := []runtime.Object{
+		&v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "myorg-myapp",
+				Namespace: ns,
+			},
+			Spec: v1.SourceRepositorySpec{
+				Provider: "https://github.com",
+				Org:      "myorg",
+				Repo:     "myapp",
+			},
+		},
+	}
+	f := fakejxfactory.NewFakeFactoryWithObjects(kubeObjects, jxObjects, ns)
+
+	apiClient := apiextensionsfake.NewSimpleClientset(
+		&apiextensionsv1beta1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "environments.jenkins.io"},
+			Spec:       apiextensionsv1beta1.CustomResourceDefinitionSpec{Group: "jenkins.io"},
+		},
+		&apiextensionsv1beta1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "lighthousejobs.lighthouse.jenkins.io"},
+			Spec:       apiextensionsv1beta1.CustomResourceDefinitionSpec{Group: "lighthouse.jenkins.io"},
+		},
+		&apiextensionsv1beta1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "certificates.certmanager.k8s.io"},
+			Spec:       apiextensionsv1beta1.CustomResourceDefinitionSpec{Group: "certmanager.k8s.io"},
+		},
+	)
+
+	repoService := &fakeRepositoryService{
+		hooks: map[string][]*scm.Hook{
+			"myorg/myapp": {
+				{ID: "1", Target: "http://hook-jx.myorg.com/hook"},
+				{ID: "2", Target: "https://ci.example.com/hook"},
+				{ID: "3", Target: "https://hook-jx.notmyorg.com/hook"},
+				{ID: "4", Target: "https://hook-jx.staging.myorg.com/hook"},
+			},
+		},
+	}
+
+	tmpDir, err := ioutil.TempDir("", "helmboot-destroy-")
+	require.NoError(t, err, "failed to create temp dir")
+	defer os.RemoveAll(tmpDir)
+
+	runner := &fakerunner.FakeRunner{}
+	o := &destroy.Options{
+		Gitter:      &gitFakeCopy{},
+		Dir:         tmpDir,
+		Purge:       true,
+		KeepSecrets: true,
+		NoBackup:    true,
+		BatchMode:   true,
+		Out:         ioutil.Discard,
+		HelmfileGenerator: func(dir string) error {
+			return nil
+		},
+		CommandRunner:       runner.Run,
+		APIExtensionsClient: apiClient,
+		ScmClientFactory: func(serverURL, owner, kind string) (*scm.Client, error) {
+			return &scm.Client{Repositories: repoService}, nil
+		},
+	}
+	o.KindResolver.GitURL = sourceDir
+	o.KindResolver.Factory = f
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run destroy --purge")
+
+	var purged []string
+	for _, item := range o.Report.Purged {
+		purged = append(purged, item.Kind+":"+item.Name)
+	}
+	assert.Equal(t, []string{
+		"Webhook:http://hook-jx.myorg.com/hook",
+		"PersistentVolumeClaim:lighthouse-data",
+		"Namespace:jx-staging",
+	}, purged, "purged resources without --purge-crds")
+	assert.Empty(t, o.Report.NotRemoved, "resources not removed")
+
+	var remainingHooks []string
+	for _, h := range repoService.hooks["myorg/myapp"] {
+		remainingHooks = append(remainingHooks, h.ID)
+	}
+	assert.Equal(t, []string{"2", "3", "4"}, remainingHooks, "remaining webhooks")
+
+	crdList, err := apiClient.ApiextensionsV1beta1().CustomResourceDefinitions().List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list CRDs")
+	assert.Len(t, crdList.Items, 3, "the CRDs should not be removed without --purge-crds")
+
+	o.PurgeCRDs = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run destroy --purge --purge-crds")
+
+	purged = nil
+	for _, item := range o.Report.Purged {
+		purged = append(purged, item.Kind+":"+item.Name)
+	}
+	assert.Equal(t, []string{
+		"CustomResourceDefinition:environments.jenkins.io",
+		"CustomResourceDefinition:lighthousejobs.lighthouse.jenkins.io",
+	}, purged, "purged resources with --purge-crds")
+
+	kubeClient, _, err := f.CreateKubeClient()
+	require.NoError(t, err, "failed to create kube client")
+	pvcList, err := kubeClient.CoreV1().PersistentVolumeClaims(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list PVCs")
+	require.Len(t, pvcList.Items, 1, "remaining PVCs")
+	assert.Equal(t, "not-ours", pvcList.Items[0].Name, "remaining PVC")
+
+	crdList, err = apiClient.ApiextensionsV1beta1().CustomResourceDefinitions().List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list CRDs")
+	require.Len(t, crdList.Items, 1, "remaining CRDs")
+	assert.Equal(t, "certificates.certmanager.k8s.io", crdList.Items[0].Name, "remaining CRD")
+
+	o.Purge = false
+	err = o.Run()
+	require.Error(t, err, "should not allow --purge-crds without --purge")
+
+	o.Purge = true
+	o.Only = "apps"
+	err = o.Run()
+	require.Error(t, err, "should not allow --purge with --only")
+}
@@ -0,0 +1,315 @@
+package destroy
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/jxadapt"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/factory"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/gits"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// jenkinsXGroup the API group of the Jenkins X CRDs
+	jenkinsXGroup = "jenkins.io"
+)
+
+// pvcReleaseLabels the labels charts use on PVCs to indicate the release which created them
+var pvcReleaseLabels = []string{"release", "app.kubernetes.io/instance"}
+
+// PurgeItem a resource removed by --purge
+type PurgeItem struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Error     string
+
+	remove func() error
+}
+
+// findPurgeItems finds the environment namespaces, PVCs, webhooks and CRDs to remove when purging
+func (o *Options) findPurgeItems(requirements *config.RequirementsConfig, gitURL string, releases []RemovedRelease) ([]*PurgeItem, error) {
+	kubeClient, devNs, err := o.KindResolver.GetFactory().CreateKubeClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes client")
+	}
+	jxClient, _, err := o.KindResolver.GetFactory().CreateJXClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create JX client")
+	}
+	var answer []*PurgeItem
+
+	// webhooks need to be found before we remove the SourceRepository CRD
+	webhooks, err := o.findWebhooks(requirements, gitURL, devNs)
+	if err != nil {
+		return nil, err
+	}
+	answer = append(answer, webhooks...)
+
+	// PVCs created by the charts
+	releaseNames := map[string]bool{}
+	namespaces := map[string]bool{
+		devNs: true,
+	}
+	for _, r := range releases {
+		releaseNames[r.Name] = true
+		if r.Namespace != "" {
+			namespaces[r.Namespace] = true
+		}
+	}
+	var nsList []string
+	for ns := range namespaces {
+		nsList = append(nsList, ns)
+	}
+	sort.Strings(nsList)
+	for _, ns := range nsList {
+		pvcList, err := kubeClient.CoreV1().PersistentVolumeClaims(ns).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list PersistentVolumeClaims in namespace %s", ns)
+		}
+		pvcs := pvcList.Items
+		sort.Slice(pvcs, func(i, j int) bool {
+			return pvcs[i].Name < pvcs[j].Name
+		})
+		for _, pvc := range pvcs {
+			if !hasReleaseLabel(pvc.Labels, releaseNames) {
+				continue
+			}
+			pvcNs := ns
+			name := pvc.Name
+			answer = append(answer, &PurgeItem{
+				Kind:      "PersistentVolumeClaim",
+				Namespace: pvcNs,
+				Name:      name,
+				remove: func() error {
+					return kubeClient.CoreV1().PersistentVolumeClaims(pvcNs).Delete(name, &metav1.DeleteOptions{})
+				},
+			})
+		}
+	}
+
+	// environment namespaces
+	for _, e := range requirements.Environments {
+		if e.Key == "dev" || e.RemoteCluster {
+			continue
+		}
+		ns := fmt.Sprintf("%s-%s", devNs, e.Key)
+		env, err := jxClient.JenkinsV1().Environments(devNs).Get(e.Key, metav1.GetOptions{})
+		if err == nil && env.Spec.Namespace != "" {
+			ns = env.Spec.Namespace
+		}
+		if ns == devNs || ns == "default" || strings.HasPrefix(ns, "kube-") {
+			continue
+		}
+		_, err = kubeClient.CoreV1().Namespaces().Get(ns, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to get namespace %s", ns)
+		}
+		envNs := ns
+		answer = append(answer, &PurgeItem{
+			Kind: "Namespace",
+			Name: envNs,
+			remove: func() error {
+				return kubeClient.CoreV1().Namespaces().Delete(envNs, &metav1.DeleteOptions{})
+			},
+		})
+	}
+
+	// Jenkins X CRDs which also removes their instances in all namespaces
+	if !o.PurgeCRDs {
+		log.Logger().Infof("not removing the Jenkins X CRDs as they are cluster wide. Use --purge-crds to remove them")
+		return answer, nil
+	}
+	apiClient, err := o.apiExtensionsClient()
+	if err != nil {
+		return nil, err
+	}
+	crdInterface := apiClient.ApiextensionsV1beta1().CustomResourceDefinitions()
+	crdList, err := crdInterface.List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list CustomResourceDefinitions")
+	}
+	crds := crdList.Items
+	sort.Slice(crds, func(i, j int) bool {
+		return crds[i].Name < crds[j].Name
+	})
+	for _, crd := range crds {
+		group := crd.Spec.Group
+		if group != jenkinsXGroup && !strings.HasSuffix(group, "."+jenkinsXGroup) {
+			continue
+		}
+		name := crd.Name
+		answer = append(answer, &PurgeItem{
+			Kind: "CustomResourceDefinition",
+			Name: name,
+			remove: func() error {
+				return crdInterface.Delete(name, &metav1.DeleteOptions{})
+			},
+		})
+	}
+	return answer, nil
+}
+
+// findWebhooks finds the webhooks registered by Jenkins X on the git repositories of the SourceRepository resources
+func (o *Options) findWebhooks(requirements *config.RequirementsConfig, gitURL string, ns string) ([]*PurgeItem, error) {
+	hookHost := webhookHost(requirements, ns)
+	if hookHost == "" {
+		log.Logger().Warnf("no ingress domain in the requirements so cannot detect the webhooks to remove")
+		return nil, nil
+	}
+	jxClient, _, err := o.KindResolver.GetFactory().CreateJXClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create JX client")
+	}
+	srList, err := jxClient.JenkinsV1().SourceRepositories(ns).List(metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to list SourceRepositories in namespace %s", ns)
+	}
+
+	ctx := context.Background()
+	clients := map[string]*scm.Client{}
+	var answer []*PurgeItem
+	for _, sr := range srList.Items {
+		server := sr.Spec.Provider
+		if server == "" {
+			server = gits.GitHubURL
+		}
+		fullName := scm.Join(sr.Spec.Org, sr.Spec.Repo)
+		client := clients[server]
+		if client == nil {
+			kind := sr.Spec.ProviderKind
+			if kind == "" {
+				kind = gits.SaasGitKind(server)
+			}
+			client, err = o.scmClient(server, sr.Spec.Org, kind, gitURL)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to create SCM client for server %s", server)
+			}
+			clients[server] = client
+		}
+		hooks, _, err := client.Repositories.ListHooks(ctx, fullName, scm.ListOptions{})
+		if err != nil {
+			log.Logger().Warnf("failed to list the webhooks of repository %s: %s", fullName, err.Error())
+			continue
+		}
+		for _, hook := range hooks {
+			if !isJenkinsXWebhook(hook.Target, hookHost) {
+				continue
+			}
+			repoClient := client
+			id := hook.ID
+			answer = append(answer, &PurgeItem{
+				Kind:      "Webhook",
+				Namespace: fullName,
+				Name:      hook.Target,
+				remove: func() error {
+					_, err := repoClient.Repositories.DeleteHook(ctx, fullName, id)
+					return err
+				},
+			})
+		}
+	}
+	return answer, nil
+}
+
+// purge removes the items recording any that could not be removed
+func (o *Options) purge(items []*PurgeItem) {
+	for _, item := range items {
+		err := item.remove()
+		if err != nil && !apierrors.IsNotFound(err) {
+			item.Error = err.Error()
+			o.Report.NotRemoved = append(o.Report.NotRemoved, item)
+			continue
+		}
+		log.Logger().Infof("removed %s %s", item.Kind, util.ColorInfo(item.Name))
+		o.Report.Purged = append(o.Report.Purged, item)
+	}
+}
+
+// scmClient creates the SCM client for the git server preferring the token in the boot git URL
+func (o *Options) scmClient(server, owner, kind, gitURL string) (*scm.Client, error) {
+	if o.ScmClientFactory != nil {
+		return o.ScmClientFactory(server, owner, kind)
+	}
+	u, err := url.Parse(gitURL)
+	if err == nil && u.User != nil && sameHost(server, u.Host) {
+		token, ok := u.User.Password()
+		if ok && token != "" {
+			return factory.NewClient(kind, server, token)
+		}
+	}
+	client, _, err := jxadapt.NewJXAdapter(o.KindResolver.GetFactory(), o.Git(), o.BatchMode).ScmClient(server, owner, kind)
+	return client, err
+}
+
+func (o *Options) apiExtensionsClient() (apiextensionsclientset.Interface, error) {
+	if o.APIExtensionsClient != nil {
+		return o.APIExtensionsClient, nil
+	}
+	cfg, err := o.KindResolver.GetFactory().CreateKubeConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes config")
+	}
+	o.APIExtensionsClient, err = apiextensionsclientset.NewForConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create API extensions client")
+	}
+	return o.APIExtensionsClient, nil
+}
+
+// webhookHost returns the host name of the Jenkins X hook endpoint for the ingress domain of the development namespace
+func webhookHost(requirements *config.RequirementsConfig, devNs string) string {
+	domain := requirements.Ingress.Domain
+	if domain == "" {
+		return ""
+	}
+	subDomain := requirements.Ingress.NamespaceSubDomain
+	if subDomain == "" {
+		subDomain = "-" + devNs + "."
+	}
+	return "hook" + subDomain + domain
+}
+
+// isJenkinsXWebhook returns true if the webhook target is the Jenkins X hook endpoint of the cluster
+func isJenkinsXWebhook(target string, hookHost string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Hostname(), hookHost)
+}
+
+// sameHost returns true if the git server URL has the given host
+func sameHost(serverURL string, host string) bool {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return strings.EqualFold(u.Host, host)
+}
+
+func hasReleaseLabel(labels map[string]string, releaseNames map[string]bool) bool {
+	for _, l := range pvcReleaseLabels {
+		if releaseNames[labels[l]] {
+			return true
+		}
+	}
+	return false
+}
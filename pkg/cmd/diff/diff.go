@@ -0,0 +1,292 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
+	"github.com/jenkins-x-labs/helmboot/pkg/common"
+	"github.com/jenkins-x-labs/helmboot/pkg/drift"
+	"github.com/jenkins-x-labs/helmboot/pkg/githelpers"
+	"github.com/jenkins-x-labs/helmboot/pkg/helmer"
+	"github.com/jenkins-x-labs/helmboot/pkg/helmfiles"
+	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr"
+	"github.com/jenkins-x/jx/pkg/cmd/helper"
+	"github.com/jenkins-x/jx/pkg/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/gits"
+	"github.com/jenkins-x/jx/pkg/jxfactory"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/table"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffLong = templates.LongDesc(`
+		Compares the releases in the development environment git repository with the releases installed in the cluster.
+
+		Reports releases which are missing from the cluster, extra releases installed in the cluster and releases with a different chart version
+`)
+
+	diffExample = templates.Examples(`
+		# compares the current directory with the cluster
+		%s diff
+
+		# compares a git repository with the cluster outputting JSON and failing if there is any drift
+		%s diff --git-url https://github.com/myorg/environment-mycluster-dev.git -o json --fail-on-drift
+
+		# also compares the rendered manifests via the helm diff plugin
+		%s diff --manifests --secrets-file /tmp/secrets.yaml
+
+		# only compares the releases in the given namespaces
+		%s diff --namespace jx --namespace cert-manager
+	`)
+
+	outputFormats = []string{"table", "json"}
+)
+
+// DiffOptions the options for comparing git with the cluster
+type DiffOptions struct {
+	JXFactory      jxfactory.Factory
+	Gitter         gits.Gitter
+	Dir            string
+	GitURL         string
+	SecretsFile    string
+	Output         string
+	IgnoreReleases []string
+	Namespaces     []string
+	Manifests      bool
+	FailOnDrift    bool
+	BatchMode      bool
+	Out            io.Writer
+
+	// CommandRunner runs the helmfile commands so that they can be faked in tests
+	CommandRunner cmdrunner.CommandRunner
+
+	// HelmfileGenerator generates the helmfiles in the directory
+	HelmfileGenerator func(dir string) error
+
+	// Report the drift report
+	Report *drift.Report
+}
+
+// NewCmdDiff creates a command object for the command
+func NewCmdDiff() (*cobra.Command, *DiffOptions) {
+	o := &DiffOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "diff",
+		Short:   "Compares the releases in git with the releases installed in the cluster",
+		Long:    diffLong,
+		Example: fmt.Sprintf(diffExample, common.BinaryName, common.BinaryName, common.BinaryName, common.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory containing the development environment git repository if not using --git-url")
+	cmd.Flags().StringVarP(&o.GitURL, "git-url", "u", "", "the git URL of the development environment to clone")
+	cmd.Flags().StringVarP(&o.SecretsFile, "secrets-file", "s", "", "the secrets YAML file to use when comparing manifests. If not specified dummy secrets are used")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "table", "the output format. Possible values: "+strings.Join(outputFormats, ", "))
+	cmd.Flags().StringArrayVarP(&o.IgnoreReleases, "ignore-release", "", []string{"jx-boot"}, "the names of installed releases which are not managed by the helmfiles")
+	cmd.Flags().StringArrayVarP(&o.Namespaces, "namespace", "n", nil, "the namespaces to compare. If not specified the releases in all namespaces are compared")
+	cmd.Flags().BoolVarP(&o.Manifests, "manifests", "", false, "also compares the rendered manifests with the cluster via 'helmfile diff' which requires the helm diff plugin")
+	cmd.Flags().BoolVarP(&o.FailOnDrift, "fail-on-drift", "", false, "returns a non-zero exit code if any drift is detected")
+	cmd.Flags().BoolVarP(&o.BatchMode, "batch-mode", "b", false, "Runs in batch mode without prompting for user input")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *DiffOptions) Run() error {
+	if util.StringArrayIndex(outputFormats, o.Output) < 0 {
+		return util.InvalidOption("output", o.Output, outputFormats)
+	}
+	if o.JXFactory == nil {
+		o.JXFactory = jxfactory.NewFactory()
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.DefaultCommandRunner
+	}
+	if o.HelmfileGenerator == nil {
+		o.HelmfileGenerator = func(dir string) error {
+			return helmfiles.Generate(dir, o.BatchMode)
+		}
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+
+	_, devNs, err := o.JXFactory.CreateKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create kube client")
+	}
+
+	dir, err := githelpers.CloneOrCopyToTempDir(o.Git(), o.GitURL, o.Dir)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	err = o.HelmfileGenerator(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate the helmfiles")
+	}
+
+	var desired []helmfiles.Release
+	for _, d := range helmfiles.Dirs {
+		releases, err := helmfiles.LoadReleases(filepath.Join(dir, d, helmfiles.HelmfileName))
+		if err != nil {
+			return err
+		}
+		for _, r := range releases {
+			if r.Namespace == "" {
+				r.Namespace = devNs
+			}
+			if len(o.Namespaces) > 0 && util.StringArrayIndex(o.Namespaces, r.Namespace) < 0 {
+				continue
+			}
+			desired = append(desired, r)
+		}
+	}
+
+	installed, err := o.findInstalledReleases()
+	if err != nil {
+		return err
+	}
+
+	o.Report = drift.Compare(desired, installed)
+
+	if o.Manifests {
+		err = o.diffManifests(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = o.writeReport()
+	if err != nil {
+		return err
+	}
+	if o.FailOnDrift && o.Report.HasDrift() {
+		return errors.Errorf("detected drift between git and the cluster")
+	}
+	return nil
+}
+
+// findInstalledReleases finds the installed releases in all namespaces or the namespaces specified via --namespace
+func (o *DiffOptions) findInstalledReleases() ([]helmer.ReleaseSummary, error) {
+	var commands []*util.Command
+	if len(o.Namespaces) == 0 {
+		commands = append(commands, &util.Command{
+			Name: "helm",
+			Args: []string{"list", "--all", "--all-namespaces", "--output", "json"},
+		})
+	}
+	for _, ns := range o.Namespaces {
+		commands = append(commands, &util.Command{
+			Name: "helm",
+			Args: []string{"list", "--all", "--namespace", ns, "--output", "json"},
+		})
+	}
+
+	var answer []helmer.ReleaseSummary
+	for _, c := range commands {
+		output, err := o.CommandRunner(c)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to run command: %s %s", c.Name, strings.Join(c.Args, " "))
+		}
+		releases, err := drift.ParseHelmList(output)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range releases {
+			if util.StringArrayIndex(o.IgnoreReleases, r.ReleaseName) >= 0 {
+				continue
+			}
+			answer = append(answer, r)
+		}
+	}
+	return answer, nil
+}
+
+// diffManifests compares the rendered manifests with the cluster via the helm diff plugin
+func (o *DiffOptions) diffManifests(dir string) error {
+	secretsFile := o.SecretsFile
+	if secretsFile == "" {
+		secretsFile = filepath.Join(dir, "secrets.yaml")
+		err := secretmgr.WriteDummySecretsYAML(secretsFile)
+		if err != nil {
+			return err
+		}
+	}
+	secretsFile, err := filepath.Abs(secretsFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the absolute path of %s", secretsFile)
+	}
+
+	o.Report.ManifestDiffs = map[string]string{}
+	for _, d := range helmfiles.Dirs {
+		c := &util.Command{
+			Name: "helmfile",
+			Args: []string{"diff", "--suppress-secrets"},
+			Dir:  filepath.Join(dir, d),
+			Env: map[string]string{
+				"JX_SECRETS_YAML": secretsFile,
+			},
+		}
+		output, err := o.CommandRunner(c)
+		if err != nil {
+			return errors.Wrapf(err, "failed to run command: %s %s in dir %s", c.Name, strings.Join(c.Args, " "), c.Dir)
+		}
+		if strings.TrimSpace(output) != "" {
+			o.Report.ManifestDiffs[d] = output
+		}
+	}
+	return nil
+}
+
+// writeReport writes the report in the output format
+func (o *DiffOptions) writeReport() error {
+	r := o.Report
+	if o.Output == "json" {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal the drift report to JSON")
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	}
+
+	if !r.HasDrift() {
+		log.Logger().Infof("no drift detected between git and the cluster")
+		return nil
+	}
+	if len(r.Differences) > 0 {
+		t := table.CreateTable(o.Out)
+		t.AddRow("DRIFT", "NAMESPACE", "RELEASE", "GIT CHART", "GIT VERSION", "CLUSTER CHART", "CLUSTER VERSION", "STATUS")
+		for _, d := range r.Differences {
+			t.AddRow(d.Kind, d.Namespace, d.Release, d.Chart, d.DesiredVersion, d.InstalledChart, d.InstalledVersion, d.Status)
+		}
+		t.Render()
+	}
+	for _, d := range helmfiles.Dirs {
+		text := r.ManifestDiffs[d]
+		if text != "" {
+			fmt.Fprintf(o.Out, "\nmanifest differences for %s:\n%s\n", d, text)
+		}
+	}
+	return nil
+}
+
+// Git lazily create a gitter if its not specified
+func (o *DiffOptions) Git() gits.Gitter {
+	if o.Gitter == nil {
+		o.Gitter = gits.NewGitCLI()
+	}
+	return o.Gitter
+}
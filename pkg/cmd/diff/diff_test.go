@@ -0,0 +1,106 @@
+package diff_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/diff"
+	"github.com/jenkins-x-labs/helmboot/pkg/drift"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakejxfactory"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakerunner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const helmListJSON = `[
+  {"name":"jx-boot","namespace":"jx","revision":"1","updated":"2020-04-01 12:00:00","status":"deployed","chart":"jxl-boot-0.0.10","app_version":""},
+  {"name":"lighthouse","namespace":"jx","revision":"3","updated":"2020-04-01 12:00:00","status":"deployed","chart":"lighthouse-0.0.600","app_version":""},
+  {"name":"old-app","namespace":"jx","revision":"1","updated":"2020-04-01 12:00:00","status":"deployed","chart":"old-app-1.0.0","app_version":"1.0.0"},
+  {"name":"prometheus","namespace":"monitoring","revision":"2","updated":"2020-04-01 12:00:00","status":"deployed","chart":"prometheus-11.0.0","app_version":"2.16.0"}
+]`
+
+func TestDiff(t *testing.T) {
+	runner := &fakerunner.FakeRunner{
+		Results: map[string]string{
+			"helm list --all --all-namespaces --output json": helmListJSON,
+			"helmfile diff --suppress-secrets":               "jx, lighthouse, Deployment (apps) has changed",
+		},
+	}
+	out := &bytes.Buffer{}
+
+	_, o := diff.NewCmdDiff()
+	o.Dir = filepath.Join("test_data", "dev-env")
+	o.JXFactory = fakejxfactory.NewFakeFactory()
+	o.CommandRunner = runner.Run
+	o.HelmfileGenerator = func(dir string) error {
+		return nil
+	}
+	o.Output = "json"
+	o.Manifests = true
+	o.FailOnDrift = true
+	o.Out = out
+
+	err := o.Run()
+	require.Error(t, err, "should have failed due to drift")
+	assert.Contains(t, err.Error(), "drift", "error message")
+
+	report := &drift.Report{}
+	err = json.Unmarshal(out.Bytes(), report)
+	require.NoError(t, err, "failed to parse JSON output %s", out.String())
+
+	var kinds []string
+	for _, d := range report.Differences {
+		kinds = append(kinds, d.Namespace+"/"+d.Release+"="+d.Kind)
+	}
+	assert.Equal(t, []string{
+		"cert-manager/cert-manager=" + drift.KindMissing,
+		"jx/lighthouse=" + drift.KindVersionSkew,
+		"jx/old-app=" + drift.KindExtra,
+		"monitoring/prometheus=" + drift.KindExtra,
+	}, kinds, "differences")
+	assert.Len(t, report.ManifestDiffs, 2, "manifest diffs")
+	require.Len(t, runner.Commands, 3, "commands")
+	assert.NotEmpty(t, runner.Commands[1].Env["JX_SECRETS_YAML"], "should pass the secrets YAML to helmfile")
+}
+
+func TestDiffNamespaceFilter(t *testing.T) {
+	runner := &fakerunner.FakeRunner{
+		Results: map[string]string{
+			"helm list --all --namespace jx --output json": `[
+  {"name":"lighthouse","namespace":"jx","revision":"3","updated":"2020-04-01 12:00:00","status":"deployed","chart":"lighthouse-0.0.600","app_version":""},
+  {"name":"old-app","namespace":"jx","revision":"1","updated":"2020-04-01 12:00:00","status":"deployed","chart":"old-app-1.0.0","app_version":"1.0.0"}
+]`,
+		},
+	}
+	out := &bytes.Buffer{}
+
+	_, o := diff.NewCmdDiff()
+	o.Dir = filepath.Join("test_data", "dev-env")
+	o.JXFactory = fakejxfactory.NewFakeFactory()
+	o.CommandRunner = runner.Run
+	o.HelmfileGenerator = func(dir string) error {
+		return nil
+	}
+	o.Namespaces = []string{"jx"}
+	o.Output = "json"
+	o.Out = out
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run diff")
+
+	report := &drift.Report{}
+	err = json.Unmarshal(out.Bytes(), report)
+	require.NoError(t, err, "failed to parse JSON output %s", out.String())
+
+	var kinds []string
+	for _, d := range report.Differences {
+		kinds = append(kinds, d.Namespace+"/"+d.Release+"="+d.Kind)
+	}
+	assert.Equal(t, []string{
+		"jx/lighthouse=" + drift.KindVersionSkew,
+		"jx/old-app=" + drift.KindExtra,
+	}, kinds, "differences")
+	assert.Equal(t, []string{"helm list --all --namespace jx --output json"}, runner.CommandLines(), "commands")
+}
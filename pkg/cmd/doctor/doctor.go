@@ -0,0 +1,401 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
+	"github.com/jenkins-x-labs/helmboot/pkg/common"
+	"github.com/jenkins-x-labs/helmboot/pkg/githelpers"
+	"github.com/jenkins-x-labs/helmboot/pkg/jxadapt"
+	"github.com/jenkins-x-labs/helmboot/pkg/preflight"
+	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
+	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr/factory"
+	"github.com/jenkins-x/go-scm/scm"
+	scmfactory "github.com/jenkins-x/go-scm/scm/factory"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/jx/pkg/cmd/helper"
+	"github.com/jenkins-x/jx/pkg/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/gits"
+	"github.com/jenkins-x/jx/pkg/kube"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// MinHelmVersion the minimum supported helm version
+	MinHelmVersion = "3.0.0"
+
+	// MinHelmfileVersion the minimum supported helmfile version
+	MinHelmfileVersion = "0.98.0"
+)
+
+var (
+	doctorLong = templates.LongDesc(`
+		Runs a number of checks on the cluster, the development environment git repository, the secrets and the local binaries
+		and prints a hint on how to fix each failure
+`)
+
+	doctorExample = templates.Examples(`
+		# checks the current cluster
+		%s doctor
+
+		# outputs the checks as JSON
+		%s doctor -o json
+	`)
+
+	outputFormats = []string{"table", "json"}
+)
+
+// DoctorOptions the options for diagnosing problems
+type DoctorOptions struct {
+	KindResolver factory.KindResolver
+	Gitter       gits.Gitter
+	Output       string
+	BatchMode    bool
+	Out          io.Writer
+
+	// CommandRunner runs the helm, helmfile and git commands so that they can be faked in tests
+	CommandRunner cmdrunner.CommandRunner
+
+	// ScmClientFactory optionally creates the SCM client used to verify the git token
+	ScmClientFactory func(serverURL, owner, kind string) (*scm.Client, error)
+
+	// Results the results of the checks
+	Results []preflight.Result
+
+	kubeClient   kubernetes.Interface
+	jxClient     versioned.Interface
+	ns           string
+	devEnv       *v1.Environment
+	requirements *config.RequirementsConfig
+	gitURL       string
+}
+
+// checkResult the JSON output of a check
+type checkResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// NewCmdDoctor creates a command object for the command
+func NewCmdDoctor() (*cobra.Command, *DoctorOptions) {
+	o := &DoctorOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "doctor",
+		Short:   "Checks the cluster, git repository, secrets and binaries and prints hints on how to fix any problems",
+		Long:    doctorLong,
+		Example: fmt.Sprintf(doctorExample, common.BinaryName, common.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "table", "the output format. Possible values: "+strings.Join(outputFormats, ", "))
+	cmd.Flags().BoolVarP(&o.BatchMode, "batch-mode", "b", false, "Runs in batch mode without prompting for user input")
+	cmd.Flags().StringVarP(&o.KindResolver.Overlay, "overlay", "", os.Getenv(reqhelpers.OverlayEnvVar), "the requirements overlay in requirements/clusters used to compose the requirements with requirements/base.yml. Defaults to $"+reqhelpers.OverlayEnvVar)
+	return cmd, o
+}
+
+// Run implements the command
+func (o *DoctorOptions) Run() error {
+	if util.StringArrayIndex(outputFormats, o.Output) < 0 {
+		return util.InvalidOption("output", o.Output, outputFormats)
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.DefaultCommandRunner
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+
+	o.Results = preflight.RunChecks(o.checks())
+
+	err := o.render()
+	if err != nil {
+		return err
+	}
+	failed := preflight.Failed(o.Results)
+	if len(failed) > 0 {
+		return errors.Errorf("%d of %d checks failed", len(failed), len(o.Results))
+	}
+	return nil
+}
+
+func (o *DoctorOptions) checks() []preflight.Check {
+	bin := common.BinaryName
+	return []preflight.Check{
+		{
+			Name: "kubernetes",
+			Run:  o.checkKubernetes,
+			Hint: "check the current context via 'kubectl config current-context' and that the cluster is reachable",
+		},
+		{
+			Name: "dev namespace",
+			Run:  o.checkNamespace,
+			Hint: "switch to the namespace Jenkins X is installed in via 'jx ns jx'",
+		},
+		{
+			Name: "dev Environment",
+			Run:  o.checkDevEnvironment,
+			Hint: fmt.Sprintf("boot the cluster via '%s run' to create the dev Environment", bin),
+		},
+		{
+			Name: "boot git URL",
+			Run:  o.checkGitURL,
+			Hint: fmt.Sprintf("store the git URL via '%s run --git-url=https://github.com/myorg/environment-mycluster-dev.git'", bin),
+		},
+		{
+			Name: "secret manager",
+			Run:  o.checkSecretManager,
+			Hint: "check the secretStorage in your jx-requirements.yml",
+		},
+		{
+			Name: "secrets",
+			Run:  o.checkSecrets,
+			Hint: fmt.Sprintf("populate the secrets via '%s secrets edit'", bin),
+		},
+		{
+			Name: "git token",
+			Run:  o.checkGitToken,
+			Hint: fmt.Sprintf("use a git token which can push to the repository and update it via '%s secrets edit'", bin),
+		},
+		{
+			Name: "helm",
+			Run: func() (string, error) {
+				return preflight.VerifyBinaryVersion(o.CommandRunner, "helm", []string{"version", "--short", "--client"}, MinHelmVersion)
+			},
+			Hint: "install helm 3: https://helm.sh/docs/intro/install/",
+		},
+		{
+			Name: "helmfile",
+			Run: func() (string, error) {
+				return preflight.VerifyBinaryVersion(o.CommandRunner, "helmfile", []string{"--version"}, MinHelmfileVersion)
+			},
+			Hint: "install helmfile: https://github.com/roboll/helmfile#installation",
+		},
+		{
+			Name: "version stream",
+			Run:  o.checkVersionStream,
+			Hint: "check the versionStream in your jx-requirements.yml refers to a git repository you can access",
+		},
+		{
+			Name: "apps",
+			Run:  o.checkApps,
+			Hint: fmt.Sprintf("update jx-apps.yml via '%s verify requirements --git-url=<dev repository git URL> --explain'", bin),
+		},
+	}
+}
+
+func (o *DoctorOptions) checkKubernetes() (string, error) {
+	var err error
+	f := o.KindResolver.GetFactory()
+	o.kubeClient, o.ns, err = f.CreateKubeClient()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create the kubernetes client")
+	}
+	o.jxClient, _, err = f.CreateJXClient()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create the jx client")
+	}
+	return preflight.VerifyKubeVersion(o.kubeClient, preflight.MinKubeVersion)
+}
+
+func (o *DoctorOptions) checkNamespace() (string, error) {
+	if o.kubeClient == nil {
+		return "", errors.Errorf("cannot connect to kubernetes")
+	}
+	devNs, _, err := kube.GetDevNamespace(o.kubeClient, o.ns)
+	if err == nil && devNs != "" {
+		o.ns = devNs
+	}
+	return preflight.VerifyNamespace(o.kubeClient, o.ns)
+}
+
+func (o *DoctorOptions) checkDevEnvironment() (string, error) {
+	if o.jxClient == nil {
+		return "", errors.Errorf("cannot connect to kubernetes")
+	}
+	devEnv, err := kube.GetDevEnvironment(o.jxClient, o.ns)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find the dev Environment in namespace %s", o.ns)
+	}
+	if devEnv == nil {
+		return "", errors.Errorf("there is no dev Environment in namespace %s", o.ns)
+	}
+	o.devEnv = devEnv
+	requirements, err := config.GetRequirementsConfigFromTeamSettings(&devEnv.Spec.TeamSettings)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load the requirements from the dev Environment in namespace %s", o.ns)
+	}
+	if requirements == nil {
+		return "", errors.Errorf("the dev Environment in namespace %s has no requirements", o.ns)
+	}
+	o.requirements = requirements
+	return fmt.Sprintf("found the dev Environment in namespace %s", o.ns), nil
+}
+
+func (o *DoctorOptions) checkGitURL() (string, error) {
+	if o.kubeClient == nil {
+		return "", errors.Errorf("cannot connect to kubernetes")
+	}
+	gitURL, err := o.KindResolver.LoadBootRunGitURLFromSecret()
+	if err != nil {
+		return "", err
+	}
+	_, err = gits.ParseGitURL(gitURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the git URL %s", util.SanitizeURL(gitURL))
+	}
+	o.gitURL = gitURL
+	return fmt.Sprintf("the dev repository is %s", util.SanitizeURL(gitURL)), nil
+}
+
+func (o *DoctorOptions) checkSecretManager() (string, error) {
+	if o.kubeClient == nil {
+		return "", errors.Errorf("cannot connect to kubernetes")
+	}
+	if o.requirements != nil {
+		o.KindResolver.Requirements = o.requirements
+	}
+	if o.gitURL != "" {
+		o.KindResolver.GitURL = o.gitURL
+	}
+	sm, err := o.KindResolver.CreateSecretManager("")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create the secret manager")
+	}
+	return fmt.Sprintf("using secret manager %s", sm.String()), nil
+}
+
+func (o *DoctorOptions) checkSecrets() (string, error) {
+	if o.KindResolver.Kind == "" {
+		return "", errors.Errorf("no secret manager")
+	}
+	err := o.KindResolver.VerifySecrets()
+	if err != nil {
+		return "", err
+	}
+	return "the boot secrets are valid", nil
+}
+
+func (o *DoctorOptions) checkGitToken() (string, error) {
+	if o.gitURL == "" {
+		return "", errors.Errorf("no boot git URL")
+	}
+	gitInfo, err := gits.ParseGitURL(o.gitURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the git URL %s", util.SanitizeURL(o.gitURL))
+	}
+	server := gitInfo.HostURLWithoutUser()
+	owner := gitInfo.Organisation
+	kind := gits.SaasGitKind(server)
+	if o.requirements != nil && o.requirements.Cluster.GitKind != "" {
+		kind = o.requirements.Cluster.GitKind
+	}
+	scmClient, err := o.scmClient(server, owner, kind)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create the SCM client for server %s", server)
+	}
+	return preflight.VerifyRepositoryPush(scmClient, scm.Join(owner, gitInfo.Name))
+}
+
+func (o *DoctorOptions) checkVersionStream() (string, error) {
+	u := common.DefaultVersionsURL
+	if o.requirements != nil && o.requirements.VersionStream.URL != "" {
+		u = o.requirements.VersionStream.URL
+	}
+	return preflight.VerifyGitURL(o.CommandRunner, u)
+}
+
+func (o *DoctorOptions) checkApps() (string, error) {
+	if o.gitURL == "" {
+		return "", errors.Errorf("no boot git URL")
+	}
+	dir, err := githelpers.GitCloneToTempDir(o.Git(), o.gitURL, "")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to clone %s", util.SanitizeURL(o.gitURL))
+	}
+	defer os.RemoveAll(dir)
+
+	requirements, _, err := reqhelpers.LoadRequirements(dir, o.KindResolver.Overlay)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load the requirements of %s", util.SanitizeURL(o.gitURL))
+	}
+
+	// the clone is temporary so lets see what validating the apps would change
+	_, _, changes, err := reqhelpers.ValidateAppsWithRules(dir, requirements, "")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to validate the apps in %s", util.SanitizeURL(o.gitURL))
+	}
+	if len(changes) > 0 {
+		var explanations []string
+		for i := range changes {
+			explanations = append(explanations, changes[i].String())
+		}
+		return "", errors.Errorf("jx-apps.yml does not match the requirements: %s", strings.Join(explanations, "; "))
+	}
+	return "jx-apps.yml matches the requirements", nil
+}
+
+func (o *DoctorOptions) render() error {
+	if o.Output == "json" {
+		var results []checkResult
+		for _, r := range o.Results {
+			cr := checkResult{
+				Name:    r.Name,
+				Status:  "PASS",
+				Message: r.Message,
+			}
+			if !r.Passed() {
+				cr.Status = "FAIL"
+				cr.Message = r.Error.Error()
+				cr.Hint = r.Hint
+			}
+			results = append(results, cr)
+		}
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal the results to JSON")
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	}
+	preflight.RenderTable(o.Out, o.Results)
+	return nil
+}
+
+// scmClient creates the SCM client for the git server preferring the token in the boot git URL
+func (o *DoctorOptions) scmClient(server, owner, kind string) (*scm.Client, error) {
+	if o.ScmClientFactory != nil {
+		return o.ScmClientFactory(server, owner, kind)
+	}
+	u, err := url.Parse(o.gitURL)
+	if err == nil && u.User != nil {
+		token, ok := u.User.Password()
+		if ok && token != "" {
+			return scmfactory.NewClient(kind, server, token)
+		}
+	}
+	client, _, err := jxadapt.NewJXAdapter(o.KindResolver.GetFactory(), o.Git(), o.BatchMode).ScmClient(server, owner, kind)
+	return client, err
+}
+
+// Git lazily create a gitter if its not specified
+func (o *DoctorOptions) Git() gits.Gitter {
+	if o.Gitter == nil {
+		o.Gitter = gits.NewGitCLI()
+	}
+	return o.Gitter
+}
@@ -0,0 +1,116 @@
+package logs
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/bootjob"
+	"github.com/jenkins-x-labs/helmboot/pkg/common"
+	"github.com/jenkins-x/jx/pkg/cmd/helper"
+	"github.com/jenkins-x/jx/pkg/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/jxfactory"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	logsLong = templates.LongDesc(`
+		Displays the logs of the current or a previous boot Job
+`)
+
+	logsExample = templates.Examples(`
+		# display the logs of the most recent boot Job
+		%s logs
+
+		# display the logs of the boot Job before the most recent one
+		%s logs --previous
+
+		# display the logs of the boot Job for a run number from the status command
+		%s logs --job 3
+	`)
+)
+
+// LogsOptions the options for viewing the logs of a boot Job
+type LogsOptions struct {
+	JXFactory jxfactory.Factory
+	Namespace string
+	Previous  bool
+	Job       int
+	Out       io.Writer
+}
+
+// NewCmdLogs creates a command object for the command
+func NewCmdLogs() (*cobra.Command, *LogsOptions) {
+	o := &LogsOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "logs",
+		Short:   "Displays the logs of the current or a previous boot Job",
+		Long:    logsLong,
+		Example: fmt.Sprintf(logsExample, common.BinaryName, common.BinaryName, common.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "the namespace of the boot Jobs. Defaults to the current namespace")
+	cmd.Flags().BoolVarP(&o.Previous, "previous", "p", false, "displays the logs of the boot Job before the most recent one")
+	cmd.Flags().IntVarP(&o.Job, "job", "j", 0, "the run number of the boot Job to display the logs of")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *LogsOptions) Run() error {
+	if o.Previous && o.Job > 0 {
+		return errors.Errorf("cannot specify both --previous and --job")
+	}
+	if o.JXFactory == nil {
+		o.JXFactory = jxfactory.NewFactory()
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	kubeClient, ns, err := o.JXFactory.CreateKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create kube client")
+	}
+	if o.Namespace != "" {
+		ns = o.Namespace
+	}
+
+	jobs, err := bootjob.ListJobs(kubeClient, ns)
+	if err != nil {
+		return err
+	}
+	job, err := bootjob.FindJob(jobs, o.Job, o.Previous)
+	if err != nil {
+		return errors.Wrapf(err, "in namespace %s", ns)
+	}
+	pods, err := bootjob.FindJobPods(kubeClient, ns, job.Name)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return errors.Errorf("no pods found for the boot Job %s in namespace %s. Its pods may have been garbage collected", job.Name, ns)
+	}
+	pod := pods[0].Name
+	log.Logger().Infof("logs of boot Job %s pod %s", util.ColorInfo(job.Name), util.ColorInfo(pod))
+
+	stream, err := kubeClient.CoreV1().Pods(ns).GetLogs(pod, &corev1.PodLogOptions{
+		Container: bootjob.ContainerName,
+	}).Stream()
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the logs of pod %s in namespace %s", pod, ns)
+	}
+	defer stream.Close()
+
+	_, err = io.Copy(o.Out, stream)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the logs of pod %s in namespace %s", pod, ns)
+	}
+	return nil
+}
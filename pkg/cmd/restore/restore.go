@@ -0,0 +1,163 @@
+package restore
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/backup"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/run"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
+	"github.com/jenkins-x-labs/helmboot/pkg/common"
+	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr"
+	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr/factory"
+	"github.com/jenkins-x/jx/pkg/cmd/helper"
+	"github.com/jenkins-x/jx/pkg/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreLong = templates.LongDesc(`
+		Restores a backup bundle created by the destroy command.
+
+		Imports the secrets and the git URL Secret from the bundle, runs boot and then restores the
+		SourceRepositories and PipelineActivities from the bundle
+`)
+
+	restoreExample = templates.Examples(`
+		# restores a local backup bundle and runs boot
+		export JX_BACKUP_PASSPHRASE="my secret passphrase"
+		%s restore jx-boot-backup-mycluster-20200401-120000.tar.gz.enc
+
+		# restores a backup bundle from a bucket without running boot
+		%s restore gs://mybucket/backups/jx-boot-backup-mycluster-20200401-120000.tar.gz.enc --no-boot
+	`)
+)
+
+// RestoreOptions the options for restoring a backup bundle
+type RestoreOptions struct {
+	KindResolver factory.KindResolver
+	Bundle       string
+	Passphrase   string
+	NoBoot       bool
+	BatchMode    bool
+
+	// CommandRunner runs the commands to download bundles from buckets so that they can be faked in tests
+	CommandRunner cmdrunner.CommandRunner
+
+	// BootRunner runs boot for the given git URL once the secrets are restored
+	BootRunner func(gitURL string) error
+
+	// Result the restored bundle
+	Result *backup.Bundle
+}
+
+// NewCmdRestore creates a command object for the command
+func NewCmdRestore() (*cobra.Command, *RestoreOptions) {
+	o := &RestoreOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "restore <bundle>",
+		Short:   "Restores a backup bundle created by the destroy command and runs boot",
+		Long:    restoreLong,
+		Example: fmt.Sprintf(restoreExample, common.BinaryName, common.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 0 {
+				o.Bundle = args[0]
+			}
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Passphrase, "passphrase", "", os.Getenv(backup.PassphraseEnvVar), "the passphrase used to decrypt the backup bundle. Defaults to $"+backup.PassphraseEnvVar)
+	cmd.Flags().StringVarP(&o.KindResolver.Kind, "kind", "k", "", "the kind of Secret Manager you wish to use. If no value is supplied it is detected based on the jx-requirements.yml. Possible values are: "+strings.Join(secretmgr.KindValues, ", "))
+	cmd.Flags().BoolVarP(&o.NoBoot, "no-boot", "", false, "restore the secrets, git URL and Jenkins X resources without running boot. The Jenkins X CRDs must already be installed to restore the resources")
+	cmd.Flags().BoolVarP(&o.BatchMode, "batch-mode", "b", false, "Runs in batch mode without prompting for user input")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *RestoreOptions) Run() error {
+	if o.Bundle == "" {
+		return errors.Errorf("missing argument: the file or bucket URL of the backup bundle")
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.DefaultCommandRunner
+	}
+	if o.BootRunner == nil {
+		o.BootRunner = o.runBoot
+	}
+
+	data, err := backup.Load(o.CommandRunner, o.Bundle)
+	if err != nil {
+		return err
+	}
+	b, err := backup.Unmarshal(data, o.Passphrase)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read backup bundle %s", o.Bundle)
+	}
+	o.Result = b
+	if b.GitURL == "" {
+		return errors.Errorf("the backup bundle %s does not contain a git URL", o.Bundle)
+	}
+
+	r := &o.KindResolver
+	r.GitURL = b.GitURL
+	if b.Requirements != nil {
+		r.Requirements = b.Requirements
+	}
+	sm, err := r.CreateSecretManager(b.SecretsYAML)
+	if err != nil {
+		return err
+	}
+	cb := func(currentYaml string) (string, error) {
+		return b.SecretsYAML, nil
+	}
+	err = sm.UpsertSecrets(cb, secretmgr.DefaultSecretsYaml)
+	if err != nil {
+		return errors.Wrapf(err, "failed to import Secrets YAML to secret manager %s", sm.String())
+	}
+	log.Logger().Infof("imported Secrets to %s from backup %s", sm.String(), util.ColorInfo(o.Bundle))
+
+	err = r.SaveBootRunGitCloneSecret(b.SecretsYAML)
+	if err != nil {
+		return err
+	}
+	log.Logger().Infof("restored the git URL Secret %s", util.ColorInfo(secretmgr.BootGitURLSecret))
+
+	if !o.NoBoot {
+		err = o.BootRunner(r.GitURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	jxClient, ns, err := r.GetFactory().CreateJXClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create JX client")
+	}
+	err = b.RestoreResources(jxClient, ns)
+	if err != nil {
+		if !o.NoBoot {
+			return err
+		}
+		log.Logger().Warnf("could not restore the SourceRepositories and PipelineActivities as the Jenkins X CRDs may not be installed yet: %s", err.Error())
+		log.Logger().Infof("you can now run '%s run' to boot the cluster and then re-run restore to restore them", common.BinaryName)
+		return nil
+	}
+	if o.NoBoot {
+		log.Logger().Infof("you can now run '%s run' to boot the cluster", common.BinaryName)
+	}
+	return nil
+}
+
+func (o *RestoreOptions) runBoot(gitURL string) error {
+	_, ro := run.NewCmdRun()
+	ro.GitURL = gitURL
+	ro.BatchMode = o.BatchMode
+	ro.KindResolver.Factory = o.KindResolver.Factory
+	return ro.Run()
+}
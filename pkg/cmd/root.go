@@ -3,10 +3,16 @@ package cmd
 import (
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/create"
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/destroy"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/diff"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/doctor"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/logs"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/restore"
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/run"
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/secrets"
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/show"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/status"
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/step"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/template"
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/upgrade"
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/verify"
 	"github.com/jenkins-x-labs/helmboot/pkg/common"
@@ -26,14 +32,20 @@ func Main() *cobra.Command {
 			}
 		},
 	}
-	cmd.AddCommand(run.NewCmdRun())
+	cmd.AddCommand(common.SplitCommand(run.NewCmdRun()))
 	cmd.AddCommand(secrets.NewCmdSecrets())
 	cmd.AddCommand(step.NewCmdStep())
 	cmd.AddCommand(destroy.NewCmdDestroy())
+	cmd.AddCommand(common.SplitCommand(restore.NewCmdRestore()))
 
 	cmd.AddCommand(common.SplitCommand(create.NewCmdCreate()))
 	cmd.AddCommand(common.SplitCommand(upgrade.NewCmdUpgrade()))
 	cmd.AddCommand(verify.NewCmdVerify())
 	cmd.AddCommand(common.SplitCommand(show.NewCmdShow()))
+	cmd.AddCommand(common.SplitCommand(status.NewCmdStatus()))
+	cmd.AddCommand(common.SplitCommand(logs.NewCmdLogs()))
+	cmd.AddCommand(common.SplitCommand(template.NewCmdTemplate()))
+	cmd.AddCommand(common.SplitCommand(diff.NewCmdDiff()))
+	cmd.AddCommand(common.SplitCommand(doctor.NewCmdDoctor()))
 	return cmd
 }
@@ -0,0 +1,152 @@
+package run
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/bootjob"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
+	"github.com/jenkins-x-labs/helmboot/pkg/helmfiles"
+	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+)
+
+// RunLocal runs the boot steps from the local directory against the current kubernetes context rather than
+// inside the boot Job; exporting and verifying the secrets, resolving the requirements and applying the
+// helmfiles for the system and then the apps charts
+func (o *RunOptions) RunLocal() error {
+	requirements, dir, err := o.resolveRequirements()
+	if err != nil {
+		return err
+	}
+	o.KindResolver.Dir = dir
+	o.KindResolver.Requirements = requirements
+	if o.KindResolver.GitURL == "" {
+		o.KindResolver.GitURL = o.GitURL
+	}
+	if o.KindResolver.GitURL == "" {
+		o.KindResolver.GitURL = requirements.BootConfigURL
+	}
+
+	secretsFile := os.Getenv("JX_SECRETS_YAML")
+	if secretsFile == "" {
+		tmpDir, err := ioutil.TempDir("", "jx-boot-secrets-")
+		if err != nil {
+			return errors.Wrap(err, "failed to create temporary directory for the secrets YAML")
+		}
+		defer os.RemoveAll(tmpDir)
+		secretsFile = filepath.Join(tmpDir, "secrets.yaml")
+		err = os.Setenv("JX_SECRETS_YAML", secretsFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to set $JX_SECRETS_YAML")
+		}
+		defer os.Unsetenv("JX_SECRETS_YAML")
+	}
+	err = o.verifySecretsYAML()
+	if err != nil {
+		return err
+	}
+
+	kubeClient, ns, err := o.KindResolver.GetFactory().CreateKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create kube client")
+	}
+	if requirements.Cluster.Namespace != "" {
+		ns = requirements.Cluster.Namespace
+	}
+	lock, err := o.acquireLock(kubeClient, ns, bootjob.LocalHolderIdentity())
+	if err != nil {
+		return err
+	}
+	defer o.releaseLock(lock)
+
+	log.Logger().Infof("booting cluster %s locally from dir %s", util.ColorInfo(requirements.Cluster.ClusterName), util.ColorInfo(dir))
+
+	if o.HelmfileGenerator == nil {
+		o.HelmfileGenerator = func(dir string) error {
+			return helmfiles.Generate(dir, o.BatchMode)
+		}
+	}
+	err = o.HelmfileGenerator(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate the helmfiles in dir %s", dir)
+	}
+
+	for _, d := range helmfiles.Dirs {
+		log.Logger().Infof("applying the %s charts...", d)
+		c := &util.Command{
+			Name: "helmfile",
+			Args: []string{"--file", filepath.Join(d, helmfiles.HelmfileName), "apply"},
+			Dir:  dir,
+			Env: map[string]string{
+				"JX_SECRETS_YAML": secretsFile,
+			},
+			Out: os.Stdout,
+			Err: os.Stderr,
+		}
+		err = o.runCommand(c)
+		if err != nil {
+			return err
+		}
+	}
+	log.Logger().Infof("the local boot of cluster %s has completed successfully", util.ColorInfo(requirements.Cluster.ClusterName))
+	return nil
+}
+
+// resolveRequirements loads the requirements from the boot directory returning the requirements and the directory
+// containing them. If the requirements are composed from a cluster overlay they are saved to the
+// jx-requirements.yml file so that the boot steps use the composed requirements
+func (o *RunOptions) resolveRequirements() (*config.RequirementsConfig, string, error) {
+	requirements, fileName, err := reqhelpers.LoadRequirements(o.Dir, o.Overlay)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to load the requirements from dir %s", o.Dir)
+	}
+	dir := filepath.Dir(fileName)
+	if !reqhelpers.HasRequirementsOverlays(o.Dir) {
+		exists, err := util.FileExists(fileName)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "failed to check if file exists %s", fileName)
+		}
+		if !exists {
+			return nil, "", errors.Errorf("no %s file found in dir %s. Please run from a clone of the development environment git repository", config.RequirementsConfigFileName, o.Dir)
+		}
+		return requirements, dir, nil
+	}
+
+	overlay := o.Overlay
+	if overlay == "" {
+		overlay = os.Getenv(reqhelpers.OverlayEnvVar)
+	}
+	if overlay != "" {
+		err = requirements.SaveConfig(fileName)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "failed to save the requirements composed from overlay %s to %s", overlay, fileName)
+		}
+		log.Logger().Infof("saved the requirements composed from overlay %s to %s", util.ColorInfo(overlay), util.ColorInfo(fileName))
+	}
+	return requirements, dir, nil
+}
+
+// runCommand runs the command via the command runner
+func (o *RunOptions) runCommand(c *util.Command) error {
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.DefaultCommandRunner
+	}
+	exists, err := util.DirExists(c.Dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check dir exists: %s", c.Dir)
+	}
+	if !exists {
+		return errors.Errorf("directory does not exist %s", c.Dir)
+	}
+	_, err = o.CommandRunner(c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to run command: %s %s in dir %s", c.Name, strings.Join(c.Args, " "), c.Dir)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package run_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/run"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakejxfactory"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakerunner"
+	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
+	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	secretsYAML = `secrets:
+  adminUser:
+    username: admin
+    password: dummypwd
+  hmacToken: dummyhmac
+  pipelineUser:
+    username: someuser
+    token: dummytoken
+    email: me@foo.com
+`
+)
+
+func TestRunLocal(t *testing.T) {
+	ns := "jx"
+	os.Unsetenv("JX_SECRETS_YAML")
+
+	dir, err := ioutil.TempDir("", "helmboot-run-local-")
+	require.NoError(t, err, "failed to create temp dir")
+	defer os.RemoveAll(dir)
+
+	requirements := config.NewRequirementsConfig()
+	requirements.Cluster.ClusterName = "mycluster"
+	requirements.Cluster.Namespace = ns
+	err = requirements.SaveConfig(filepath.Join(dir, config.RequirementsConfigFileName))
+	require.NoError(t, err, "failed to save requirements")
+
+	runner := &fakerunner.FakeRunner{}
+	o := newLocalRunOptions(dir, ns, runner)
+	o.GitURL = "https://github.com/myorg/environment-mycluster-dev.git"
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run local boot")
+
+	assert.Equal(t, []string{
+		"helmfile --file system/helmfile.yaml apply",
+		"helmfile --file apps/helmfile.yaml apply",
+	}, runner.CommandLines(), "should apply the system charts and then the apps charts")
+	for _, c := range runner.Commands {
+		assert.Equal(t, dir, c.Dir, "command dir for %s", fakerunner.CommandLine(c))
+		assert.NotEmpty(t, c.Env["JX_SECRETS_YAML"], "should pass the secrets YAML to helmfile")
+	}
+	assert.Empty(t, os.Getenv("JX_SECRETS_YAML"), "should not modify the environment")
+}
+
+func TestRunLocalWithOverlay(t *testing.T) {
+	ns := "jx"
+	os.Unsetenv("JX_SECRETS_YAML")
+	os.Unsetenv(reqhelpers.OverlayEnvVar)
+
+	dir, err := ioutil.TempDir("", "helmboot-run-local-overlay-")
+	require.NoError(t, err, "failed to create temp dir")
+	defer os.RemoveAll(dir)
+
+	clustersDir := filepath.Join(dir, reqhelpers.RequirementsDir, reqhelpers.RequirementsClustersDir)
+	err = os.MkdirAll(clustersDir, util.DefaultWritePermissions)
+	require.NoError(t, err, "failed to create dir %s", clustersDir)
+	err = ioutil.WriteFile(filepath.Join(dir, reqhelpers.RequirementsDir, reqhelpers.RequirementsBaseFileName), []byte("cluster:\n  namespace: jx\n"), util.DefaultWritePermissions)
+	require.NoError(t, err, "failed to save the base requirements")
+	err = ioutil.WriteFile(filepath.Join(clustersDir, "prod-eu.yml"), []byte("cluster:\n  clusterName: prod-eu\n"), util.DefaultWritePermissions)
+	require.NoError(t, err, "failed to save the requirements overlay")
+
+	runner := &fakerunner.FakeRunner{}
+	o := newLocalRunOptions(dir, ns, runner)
+
+	err = o.Run()
+	require.Error(t, err, "should fail without an overlay or %s", config.RequirementsConfigFileName)
+	require.Empty(t, runner.Commands, "commands")
+
+	o.Overlay = "prod-eu"
+	err = o.Run()
+	require.NoError(t, err, "failed to run local boot with overlay")
+
+	assert.Len(t, runner.Commands, 2, "commands")
+	requirements, err := config.LoadRequirementsConfigFile(filepath.Join(dir, config.RequirementsConfigFileName))
+	require.NoError(t, err, "failed to load the composed requirements")
+	assert.Equal(t, "prod-eu", requirements.Cluster.ClusterName, "the composed requirements cluster name")
+}
+
+// newLocalRunOptions creates the options to boot locally from the given dir using a fake cluster and helmfile
+func newLocalRunOptions(dir string, ns string, runner *fakerunner.FakeRunner) *run.RunOptions {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretmgr.LocalSecret,
+			Namespace: ns,
+		},
+		Data: map[string][]byte{
+			secretmgr.LocalSecretKey: []byte(secretsYAML),
+		},
+	}
+	o := &run.RunOptions{}
+	o.Dir = dir
+	o.Local = true
+	o.BatchMode = true
+	o.KindResolver.Factory = fakejxfactory.NewFakeFactoryWithObjects([]runtime.Object{secret}, nil, ns)
+	o.KindResolver.Kind = secretmgr.KindLocal
+	o.CommandRunner = runner.Run
+	o.HelmfileGenerator = func(dir string) error {
+		return nil
+	}
+	return o
+}
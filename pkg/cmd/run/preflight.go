@@ -0,0 +1,144 @@
+package run
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/common"
+	"github.com/jenkins-x-labs/helmboot/pkg/preflight"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// preflightResults the results of the pre-flight checks which are reused when creating the boot Job
+type preflightResults struct {
+	// version the version of the boot chart or image resolved from the version stream
+	version         string
+	versionResolved bool
+
+	// secretsVerified true if the boot secrets have been verified
+	secretsVerified bool
+}
+
+// runPreflightChecks verifies the cluster, git repository, versions and secrets before we replace the boot Job
+func (o *RunOptions) runPreflightChecks(kubeClient kubernetes.Interface, ns string, requirements *config.RequirementsConfig) error {
+	if o.SkipPreflight {
+		log.Logger().Warnf("skipping the pre-flight checks")
+		return nil
+	}
+	checks := []preflight.Check{
+		{
+			Name: "kube context",
+			Run: func() (string, error) {
+				cfg, _, err := o.KindResolver.GetFactory().KubeConfig().LoadConfig()
+				if err != nil {
+					return "", errors.Wrap(err, "failed to load the kube config")
+				}
+				return preflight.VerifyKubeContext(cfg, requirements.Cluster.ClusterName)
+			},
+			Hint: "switch to the context of the cluster you wish to boot via 'kubectl config use-context' or use --skip-preflight",
+		},
+		{
+			Name: "namespace",
+			Run: func() (string, error) {
+				return preflight.VerifyNamespace(kubeClient, ns)
+			},
+		},
+		{
+			Name: "create Jobs",
+			Run: func() (string, error) {
+				return preflight.VerifyCanCreate(kubeClient, ns, "batch", "jobs")
+			},
+		},
+		{
+			Name: "create Secrets",
+			Run: func() (string, error) {
+				return preflight.VerifyCanCreate(kubeClient, ns, "", "secrets")
+			},
+		},
+		{
+			Name: "git repository",
+			Run: func() (string, error) {
+				// lets use the git URL including the git user and token used by the boot Job
+				return preflight.VerifyGitURL(o.CommandRunner, o.GitURL)
+			},
+		},
+		{
+			Name: "version stream",
+			Run: func() (string, error) {
+				return o.verifyVersion(requirements)
+			},
+		},
+		{
+			Name: "secrets",
+			Run: func() (string, error) {
+				return o.verifySecrets(requirements)
+			},
+		},
+	}
+
+	results := preflight.RunChecks(checks)
+	preflight.RenderTable(os.Stdout, results)
+
+	failed := preflight.Failed(results)
+	if len(failed) > 0 {
+		return errors.Errorf("%d of %d pre-flight checks failed. Please fix the failures or use --skip-preflight", len(failed), len(results))
+	}
+	return nil
+}
+
+// verifyVersion verifies the version of the boot chart or image resolves in the version stream
+func (o *RunOptions) verifyVersion(requirements *config.RequirementsConfig) (string, error) {
+	name := o.ChartName
+	if o.NativeJob {
+		if o.Image != "" {
+			return fmt.Sprintf("using image %s", o.Image), nil
+		}
+		name = "image"
+	}
+	version, err := o.resolveVersion(requirements)
+	if err != nil {
+		return "", err
+	}
+	if version == "" {
+		return fmt.Sprintf("no version of %s in the version stream so using the latest", name), nil
+	}
+	return fmt.Sprintf("resolved %s version %s", name, version), nil
+}
+
+// verifySecrets verifies the boot secrets are populated
+func (o *RunOptions) verifySecrets(requirements *config.RequirementsConfig) (string, error) {
+	if requirements.SecretStorage == config.SecretStorageTypeVault {
+		return "secrets are stored in vault", nil
+	}
+	o.KindResolver.Requirements = requirements
+	err := o.KindResolver.VerifySecrets()
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid secrets. Please run '%s secrets edit' to populate them", common.BinaryName)
+	}
+	o.preflightResults.secretsVerified = true
+	return "secrets are valid", nil
+}
+
+// resolveVersion returns the version of the boot image or chart from the version stream reusing the version
+// resolved by the pre-flight checks
+func (o *RunOptions) resolveVersion(requirements *config.RequirementsConfig) (string, error) {
+	if o.preflightResults.versionResolved {
+		return o.preflightResults.version, nil
+	}
+	var version string
+	var err error
+	if o.NativeJob {
+		version, err = o.findImageVersion(requirements)
+	} else {
+		version, err = o.findChartVersion(requirements)
+	}
+	if err != nil {
+		return version, err
+	}
+	o.preflightResults.version = version
+	o.preflightResults.versionResolved = true
+	return version, nil
+}
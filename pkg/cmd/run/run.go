@@ -5,10 +5,15 @@ import (
 	"io/ioutil"
 	"net/url"
 	"os"
+	"os/exec"
 	"strings"
+	"time"
 
+	"github.com/jenkins-x-labs/helmboot/pkg/bootjob"
 	"github.com/jenkins-x-labs/helmboot/pkg/clienthelpers"
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/secrets"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/step"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
 	"github.com/jenkins-x-labs/helmboot/pkg/common"
 	"github.com/jenkins-x-labs/helmboot/pkg/helmer"
 	"github.com/jenkins-x-labs/helmboot/pkg/jxadapt"
@@ -23,26 +28,46 @@ import (
 	"github.com/jenkins-x/jx/pkg/cmd/templates"
 	"github.com/jenkins-x/jx/pkg/config"
 	"github.com/jenkins-x/jx/pkg/gits"
-	"github.com/jenkins-x/jx/pkg/kube"
 	"github.com/jenkins-x/jx/pkg/log"
 	"github.com/jenkins-x/jx/pkg/util"
 	"github.com/jenkins-x/jx/pkg/versionstream"
 	"github.com/jenkins-x/jx/pkg/versionstream/versionstreamrepo"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 // RunOptions contains the command line arguments for this command
 type RunOptions struct {
 	boot.BootOptions
-	KindResolver factory.KindResolver
-	Gitter       gits.Gitter
-	ChartName    string
-	GitUserName  string
-	GitToken     string
-	BatchMode    bool
-	JobMode      bool
+	KindResolver  factory.KindResolver
+	Gitter        gits.Gitter
+	ChartName     string
+	GitUserName   string
+	GitToken      string
+	BatchMode     bool
+	JobMode       bool
+	NativeJob     bool
+	Image         string
+	JobValues     string
+	NoWait        bool
+	Timeout       time.Duration
+	HistoryLimit  int
+	ForceUnlock   bool
+	Local         bool
+	SkipPreflight bool
+	Overlay       string
+
+	// CommandRunner runs commands such as helmfile when booting locally so that they can be faked in tests
+	CommandRunner cmdrunner.CommandRunner
+
+	// HelmfileGenerator generates the helmfiles in the directory when booting locally
+	HelmfileGenerator func(dir string) error
+
+	// BootRunner runs the boot pipeline inside the cluster. Defaults to running the boot steps
+	BootRunner func() error
+
+	preflightResults preflightResults
 }
 
 var (
@@ -57,21 +82,38 @@ var (
 
 		# runs the boot Job to upgrade a cluster from the latest in git
 		%s run 
+
+		# runs the boot Job creating the resources via the Kubernetes API rather than the helm CLI
+		%s run --native
+
+		# creates the boot Job and returns immediately without waiting for it to complete
+		%s run --no-wait
+
+		# runs the boot steps from the current directory against the current kubernetes context without a boot Job
+		%s run --local
+
+		# runs the boot Job composing the requirements from the base requirements and the prod-eu cluster overlay
+		%s run --overlay prod-eu
 `)
 )
 
 const (
 	defaultChartName = "jx-labs/jxl-boot"
+
+	// the steps of the boot inside the boot Job which are recorded on the Job if they fail
+	stepPrepare      = "prepare"
+	stepAcquireLock  = "acquire-lock"
+	stepBootPipeline = "boot-pipeline"
 )
 
 // NewCmdRun creates the new command
-func NewCmdRun() *cobra.Command {
-	options := RunOptions{}
+func NewCmdRun() (*cobra.Command, *RunOptions) {
+	options := &RunOptions{}
 	command := &cobra.Command{
 		Use:     "run",
 		Short:   "boots up Jenkins and/or Jenkins X in a Kubernetes cluster using GitOps by triggering a Kubernetes Job inside the cluster",
 		Long:    stepCustomPipelineLong,
-		Example: fmt.Sprintf(stepCustomPipelineExample, common.BinaryName, common.BinaryName),
+		Example: fmt.Sprintf(stepCustomPipelineExample, common.BinaryName, common.BinaryName, common.BinaryName, common.BinaryName, common.BinaryName, common.BinaryName),
 		Run: func(command *cobra.Command, args []string) {
 			common.SetLoggingLevel(command, args)
 			err := options.Run()
@@ -96,13 +138,27 @@ func NewCmdRun() *cobra.Command {
 	command.PersistentFlags().BoolVarP(&options.BatchMode, "batch-mode", "b", defaultBatchMode, "Runs in batch mode without prompting for user input")
 
 	command.Flags().BoolVarP(&options.JobMode, "job", "", false, "if running inside the cluster lets still default to creating the boot Job rather than running boot locally")
-
-	return command
+	command.Flags().BoolVarP(&options.Local, "local", "", false, "runs the boot steps from the current directory against the current kubernetes context rather than creating a boot Job. Useful for debugging boot or for local developer clusters")
+	command.Flags().BoolVarP(&options.SkipPreflight, "skip-preflight", "", false, "skips the pre-flight checks of the cluster, git repository, versions and secrets before creating the boot Job")
+	command.Flags().BoolVarP(&options.NativeJob, "native", "", false, "creates the boot Job directly via the Kubernetes API rather than via the helm CLI. Enabled by default if the helm binary is not installed")
+	command.Flags().StringVarP(&options.JobValues, "job-values", "", "", "a YAML file of additional values for the boot Job chart such as the Job resources, image, nodeSelector, tolerations and the clusterRole bound to the boot ServiceAccount")
+	command.Flags().BoolVarP(&options.NoWait, "no-wait", "", false, "returns as soon as the boot Job is created rather than waiting for it to complete")
+	command.Flags().IntVarP(&options.HistoryLimit, "history-limit", "", bootjob.DefaultHistoryLimit, "the number of boot Jobs to keep so that their status and logs can be viewed via the status and logs commands")
+	command.Flags().BoolVarP(&options.ForceUnlock, "force-unlock", "", false, "removes the lock held by another boot before booting. Only use this if you are sure no other boot is running")
+	command.Flags().DurationVarP(&options.Timeout, "timeout", "", bootjob.DefaultTimeout, "the maximum time to wait for the boot Job to complete")
+	command.Flags().StringVarP(&options.Overlay, "overlay", "", os.Getenv(reqhelpers.OverlayEnvVar), "the requirements overlay in requirements/clusters used to compose the requirements with requirements/base.yml. Defaults to $"+reqhelpers.OverlayEnvVar)
+	command.Flags().StringVarP(&options.Image, "image", "", "", "the container image to use for the boot Job when using --native. Defaults to the version in the version stream of "+bootjob.DefaultImage)
+
+	return command, options
 }
 
 // Run implements the command
 func (o *RunOptions) Run() error {
 	o.KindResolver.Dir = o.Dir
+	o.KindResolver.Overlay = o.Overlay
+	if o.Local {
+		return o.RunLocal()
+	}
 	if (o.JobMode || !clienthelpers.IsInCluster()) && os.Getenv("JX_DEBUG_JOB") != "true" {
 		return o.RunBootJob()
 	}
@@ -112,15 +168,73 @@ func (o *RunOptions) Run() error {
 		bo.CommonOptions = opts.NewCommonOptionsWithTerm(f, os.Stdin, os.Stdout, os.Stderr)
 		bo.BatchMode = o.BatchMode
 	}
-	err := o.addUserPasswordForPrivateGitClone(true)
+	err := o.prepareInClusterBoot()
+	if err != nil {
+		o.recordJobError(stepPrepare, err)
+		return err
+	}
+
+	kubeClient, ns, err := o.KindResolver.GetFactory().CreateKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create kube client")
+	}
+	holder := bootjob.LocalHolderIdentity()
+	jobName := bootjob.CurrentJobName(kubeClient, ns)
+	if jobName != "" {
+		holder = bootjob.JobHolderIdentity(jobName)
+	}
+	lock, err := o.acquireLock(kubeClient, ns, holder)
+	if err != nil {
+		o.recordJobError(stepAcquireLock, err)
+		return err
+	}
+	defer o.releaseLock(lock)
+
+	if o.BootRunner == nil {
+		o.BootRunner = bo.Run
+	}
+	err = o.BootRunner()
+	if err != nil {
+		o.recordJobError(stepBootPipeline, err)
+		return err
+	}
+	o.restoreActivities()
+	return nil
+}
+
+// prepareInClusterBoot composes the requirements from any requirements overlay into jx-requirements.yml, exports the
+// secrets to $JX_SECRETS_YAML and then adds the git user and token from the secrets to the git URL so that private
+// development environment git repositories can be cloned
+func (o *RunOptions) prepareInClusterBoot() error {
+	err := o.detectGitURL()
 	if err != nil {
 		return err
 	}
+	if reqhelpers.HasRequirementsOverlays(o.Dir) {
+		// the boot pipeline reads jx-requirements.yml so lets save the requirements composed from the overlay
+		_, _, err = o.resolveRequirements()
+		if err != nil {
+			return err
+		}
+	}
 	err = o.verifySecretsYAML()
 	if err != nil {
 		return err
 	}
-	return bo.Run()
+	return o.addUserPasswordForPrivateGitClone(true)
+}
+
+// restoreActivities restores any PipelineActivity resources migrated into the boot git repository by the upgrade command
+func (o *RunOptions) restoreActivities() {
+	ro := &step.RestoreActivitiesOptions{
+		JXFactory: o.KindResolver.GetFactory(),
+		Dir:       o.Dir,
+		KeepFile:  true,
+	}
+	err := ro.Run()
+	if err != nil {
+		log.Logger().Warnf("failed to restore the PipelineActivity resources: %s", err.Error())
+	}
 }
 
 // RunBootJob runs the boot installer Job
@@ -129,22 +243,66 @@ func (o *RunOptions) RunBootJob() error {
 	if err != nil {
 		return err
 	}
-	requirements, gitURL, err := reqhelpers.FindRequirementsAndGitURL(o.KindResolver.GetFactory(), o.GitURL, o.Git(), o.Dir)
+	requirements, gitURL, err := reqhelpers.FindRequirementsAndGitURL(o.KindResolver.GetFactory(), o.GitURL, o.Git(), o.Dir, o.Overlay)
 	if err != nil {
 		return err
 	}
 	if gitURL == "" {
 		return util.MissingOption("git-url")
 	}
+	if o.GitURL == "" {
+		o.GitURL = gitURL
+	}
 
 	err = o.addUserPasswordForPrivateGitClone(false)
 	if err != nil {
 		return errors.Wrapf(err, "could not default the git user and token to clone the git URL")
 	}
 
+	kubeClient, ns, err := o.KindResolver.GetFactory().CreateKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create kube client")
+	}
+	if requirements.Cluster.Namespace != "" {
+		ns = requirements.Cluster.Namespace
+	}
+	lock, err := o.acquireLock(kubeClient, ns, bootjob.LocalHolderIdentity())
+	if err != nil {
+		return err
+	}
+	defer o.releaseLock(lock)
+
 	clusterName := requirements.Cluster.ClusterName
 	log.Logger().Infof("running helmboot Job for cluster %s with git URL %s", util.ColorInfo(clusterName), util.ColorInfo(gitURL))
 
+	if !o.NativeJob {
+		_, err = exec.LookPath("helm")
+		if err != nil {
+			log.Logger().Infof("the helm binary is not installed so creating the boot Job via the Kubernetes API")
+			o.NativeJob = true
+		}
+	}
+
+	err = o.runPreflightChecks(kubeClient, ns, requirements)
+	if err != nil {
+		return err
+	}
+	if o.NativeJob {
+		err = o.verifyBootSecret(requirements)
+		if err != nil {
+			return err
+		}
+		// lets pass the git URL including the user and token so that the boot Job can clone the repository
+		jobName, err := o.createNativeBootJob(requirements, o.GitURL, lock)
+		if err != nil {
+			return err
+		}
+		return o.waitForJob(jobName)
+	}
+
+	// lets keep the previous boot Job in the history as deleting the chart removes it
+	o.archiveJob(kubeClient, ns)
+
 	log.Logger().Debug("deleting the old jx-boot chart ...")
 	c := util.Command{
 		Name: "helm",
@@ -172,64 +330,247 @@ func (o *RunOptions) RunBootJob() error {
 		log.Logger().Warnf("failed to update helm repositories: %s", err.Error())
 	}
 
-	version, err := o.findChartVersion(requirements)
+	version, err := o.resolveVersion(requirements)
+	if err != nil {
+		return err
+	}
+
+	values, err := reqhelpers.GetBootJobValues(requirements, gitURL, o.Overlay, o.JobValues)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate the boot Job values")
+	}
+	valuesFile, err := reqhelpers.WriteBootJobValuesFile(values)
 	if err != nil {
 		return err
 	}
+	defer os.Remove(valuesFile)
 
-	c = reqhelpers.GetBootJobCommand(requirements, gitURL, o.ChartName, version)
+	c = reqhelpers.GetBootJobCommand(requirements, gitURL, o.ChartName, version, valuesFile)
 
 	commandLine := fmt.Sprintf("%s %s", c.Name, strings.Join(c.Args, " "))
 
+	// lets transfer the lock before installing the chart so that the boot Job can acquire it as soon as it starts
+	err = o.transferLock(lock, bootjob.JobName)
+	if err != nil {
+		return err
+	}
+
 	log.Logger().Infof("running the command:\n\n%s\n\n", util.ColorInfo(commandLine))
 
 	_, err = c.RunWithoutRetry()
 	if err != nil {
+		o.cancelLockTransfer(lock)
 		return errors.Wrapf(err, "failed to run command %s", commandLine)
 	}
 
-	return o.tailJobLogs()
+	o.annotateJob(bootjob.JobName, gitURL, version)
+	o.pruneJobs(kubeClient, ns)
+	return o.waitForJob(bootjob.JobName)
 }
 
-func (o *RunOptions) tailJobLogs() error {
-	a := jxadapt.NewJXAdapter(o.KindResolver.GetFactory(), o.Git(), o.BatchMode)
-	client, ns, err := o.KindResolver.GetFactory().CreateKubeClient()
+// createNativeBootJob creates the boot Job resources directly via the Kubernetes API returning the name of the Job.
+// The boot lock is transferred to the Job before it is created
+func (o *RunOptions) createNativeBootJob(requirements *config.RequirementsConfig, gitURL string, lock *bootjob.Lock) (string, error) {
+	image := o.Image
+	version := ""
+	if image == "" {
+		var err error
+		version, err = o.resolveVersion(requirements)
+		if err != nil {
+			return "", err
+		}
+		if version == "" {
+			version = bootjob.DefaultImageTag
+		}
+		image = bootjob.DefaultImage + ":" + version
+	}
+
+	kubeClient, ns, err := o.KindResolver.GetFactory().CreateKubeClient()
 	if err != nil {
-		return err
+		return "", errors.Wrap(err, "failed to create kube client")
+	}
+	reqNs := requirements.Cluster.Namespace
+	if reqNs != "" {
+		ns = reqNs
 	}
-	co := a.NewCommonOptions()
 
-	selector := map[string]string{
-		"job-name": "jx-boot",
+	o.archiveJob(kubeClient, ns)
+	run, err := bootjob.NextRunNumber(kubeClient, ns)
+	if err != nil {
+		return "", err
 	}
-	containerName := "boot"
-	podInterface := client.CoreV1().Pods(ns)
-	for {
-		pod := ""
-		if err != nil {
-			return err
-		}
-		pod, err = co.WaitForReadyPodForSelectorLabels(client, ns, selector, false)
-		if err != nil {
-			return err
-		}
-		if pod == "" {
-			return fmt.Errorf("No pod found for namespace %s with selector %v", ns, selector)
-		}
-		err = co.TailLogs(ns, pod, containerName)
-		if err != nil {
-			return nil
-		}
-		podResource, err := podInterface.Get(pod, metav1.GetOptions{})
+
+	values, err := reqhelpers.GetBootJobValues(requirements, bootjob.RemoveGitCredentials(gitURL), o.Overlay, o.JobValues)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate the boot Job values")
+	}
+	jobValues, err := reqhelpers.ToBootJobValues(values)
+	if err != nil {
+		return "", err
+	}
+	bo := &bootjob.Options{
+		Namespace: ns,
+		GitURL:    gitURL,
+		Image:     image,
+		Run:       run,
+		GitSHA:    o.resolveGitSHA(gitURL),
+		Version:   version,
+		Values:    jobValues,
+	}
+	resources := bootjob.CreateResources(bo)
+	jobName := resources.Job.Name
+
+	err = o.transferLock(lock, jobName)
+	if err != nil {
+		return "", err
+	}
+
+	log.Logger().Infof("creating the boot Job in namespace %s using image %s", util.ColorInfo(ns), util.ColorInfo(image))
+	err = bootjob.ApplyResources(kubeClient, resources)
+	if err != nil {
+		o.cancelLockTransfer(lock)
+		return "", err
+	}
+
+	o.pruneJobs(kubeClient, ns)
+	return jobName, nil
+}
+
+// archiveJob keeps any Job created by the boot chart in the boot history
+func (o *RunOptions) archiveJob(kubeClient kubernetes.Interface, ns string) {
+	name, err := bootjob.ArchiveJob(kubeClient, ns)
+	if err != nil {
+		log.Logger().Warnf("failed to keep the previous boot Job in the history: %s", err.Error())
+		return
+	}
+	if name != "" {
+		log.Logger().Infof("kept the previous boot Job as %s", util.ColorInfo(name))
+	}
+}
+
+// pruneJobs keeps the history of previous boot Jobs so we can diagnose failures removing the oldest Jobs
+func (o *RunOptions) pruneJobs(kubeClient kubernetes.Interface, ns string) {
+	err := bootjob.PruneJobs(kubeClient, ns, o.HistoryLimit)
+	if err != nil {
+		log.Logger().Warnf("failed to remove old boot Jobs: %s", err.Error())
+	}
+}
+
+// annotateJob annotates the boot Job created by the chart with the details of the boot run
+func (o *RunOptions) annotateJob(jobName, gitURL, version string) {
+	kubeClient, ns, err := o.KindResolver.GetFactory().CreateKubeClient()
+	if err != nil {
+		log.Logger().Warnf("failed to create kube client: %s", err.Error())
+		return
+	}
+	err = bootjob.AnnotateJob(kubeClient, ns, jobName, map[string]string{
+		bootjob.AnnotationGitURL:  bootjob.RemoveGitCredentials(gitURL),
+		bootjob.AnnotationGitSHA:  o.resolveGitSHA(gitURL),
+		bootjob.AnnotationVersion: version,
+	})
+	if err != nil {
+		log.Logger().Warnf("failed to annotate the boot Job: %s", err.Error())
+	}
+}
+
+// resolveGitSHA returns the commit SHA of the git ref being booted or an empty string if it cannot be found
+func (o *RunOptions) resolveGitSHA(gitURL string) string {
+	ref := o.GitRef
+	if ref == "" {
+		ref = "master"
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.DefaultCommandRunner
+	}
+	c := &util.Command{
+		Name: "git",
+		Args: []string{"ls-remote", gitURL, ref},
+	}
+	text, err := o.CommandRunner(c)
+	if err != nil {
+		log.Logger().Debugf("failed to find the git commit SHA of %s: %s", ref, err.Error())
+		return ""
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// recordJobError records the boot error and the step which failed on the boot Job when running inside the boot Job
+func (o *RunOptions) recordJobError(step string, bootErr error) {
+	kubeClient, ns, err := o.KindResolver.GetFactory().CreateKubeClient()
+	if err != nil {
+		log.Logger().Debugf("failed to create kube client: %s", err.Error())
+		return
+	}
+	bootjob.RecordJobError(kubeClient, ns, step, bootErr)
+}
+
+// acquireLock acquires the boot lock so that concurrent boots are not possible, removing any existing lock if --force-unlock is specified
+func (o *RunOptions) acquireLock(kubeClient kubernetes.Interface, ns string, holder string) (*bootjob.Lock, error) {
+	if o.ForceUnlock {
+		err := bootjob.ForceUnlock(kubeClient, ns)
 		if err != nil {
-			return errors.Wrapf(err, "failed to get pod %s in namespace %s", pod, ns)
+			return nil, err
 		}
-		if kube.IsPodCompleted(podResource) {
-			log.Logger().Infof("the Job pod %s has completed successfully", pod)
-			return nil
-		}
-		log.Logger().Warnf("Job pod %s is not completed but has status: %s", pod, kube.PodStatus(podResource))
 	}
+	lock, err := bootjob.AcquireLock(kubeClient, ns, holder, bootjob.DefaultLockDuration)
+	if err != nil {
+		return nil, err
+	}
+	lock.KeepAlive()
+	return lock, nil
+}
+
+// transferLock transfers the boot lock to the boot Job before it is created so that the Job keeps the cluster locked while it runs
+func (o *RunOptions) transferLock(lock *bootjob.Lock, jobName string) error {
+	err := lock.Transfer(bootjob.JobHolderIdentity(jobName))
+	if err != nil {
+		return errors.Wrapf(err, "failed to transfer the boot lock to the Job %s", jobName)
+	}
+	return nil
+}
+
+// cancelLockTransfer releases the boot lock transferred to a boot Job which could not be created
+func (o *RunOptions) cancelLockTransfer(lock *bootjob.Lock) {
+	err := lock.CancelTransfer()
+	if err != nil {
+		log.Logger().Warnf("failed to release the boot lock: %s", err.Error())
+	}
+}
+
+// releaseLock releases the boot lock unless it has been transferred to the boot Job which then releases it when it completes
+func (o *RunOptions) releaseLock(lock *bootjob.Lock) {
+	err := lock.Release()
+	if err != nil {
+		log.Logger().Warnf("failed to release the boot lock: %s", err.Error())
+	}
+}
+
+// waitForJob waits for the boot Job to complete tailing the logs of its pods unless --no-wait is specified
+func (o *RunOptions) waitForJob(jobName string) error {
+	if o.NoWait {
+		log.Logger().Infof("not waiting for the boot Job to complete. You can view its logs via: %s", util.ColorInfo(common.BinaryName+" logs"))
+		return nil
+	}
+	a := jxadapt.NewJXAdapter(o.KindResolver.GetFactory(), o.Git(), o.BatchMode)
+	client, ns, err := o.KindResolver.GetFactory().CreateKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create kube client")
+	}
+	co := a.NewCommonOptions()
+
+	wo := &bootjob.WaitOptions{
+		Namespace: ns,
+		Name:      jobName,
+		Timeout:   o.Timeout,
+		TailLogs: func(pod string) error {
+			return co.TailLogs(ns, pod, bootjob.ContainerName)
+		},
+	}
+	return bootjob.WaitForJob(client, wo)
 }
 
 // Git lazily create a gitter if its not specified
@@ -264,6 +605,9 @@ func (o *RunOptions) verifyBootSecret(requirements *config.RequirementsConfig) e
 		}
 		log.Logger().Infof("switched to the %s namespace", reqNs)
 		ns = reqNs
+	} else if o.preflightResults.secretsVerified {
+		// the pre-flight checks have already verified the secrets in this namespace
+		return nil
 	}
 
 	o.KindResolver.Requirements = requirements
@@ -290,6 +634,20 @@ func (o *RunOptions) verifyBootSecret(requirements *config.RequirementsConfig) e
 	return nil
 }
 
+func (o *RunOptions) findImageVersion(req *config.RequirementsConfig) (string, error) {
+	f := clients.NewFactory()
+	co := opts.NewCommonOptionsWithTerm(f, os.Stdin, os.Stdout, os.Stderr)
+	co.BatchMode = o.BatchMode
+
+	u := req.VersionStream.URL
+	ref := req.VersionStream.Ref
+	version, err := getVersionNumber(versionstream.KindDocker, bootjob.DefaultImage, u, ref, o.Git(), co.GetIOFileHandles())
+	if err != nil {
+		return version, errors.Wrapf(err, "failed to find version of image %s in version stream %s ref %s", bootjob.DefaultImage, u, ref)
+	}
+	return version, nil
+}
+
 func (o *RunOptions) findChartVersion(req *config.RequirementsConfig) (string, error) {
 	if o.ChartName == "" || o.ChartName[0] == '.' || o.ChartName[0] == '/' || o.ChartName[0] == '\\' || strings.Count(o.ChartName, "/") > 1 {
 		// relative chart folder so ignore version
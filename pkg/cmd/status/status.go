@@ -0,0 +1,102 @@
+package status
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/bootjob"
+	"github.com/jenkins-x-labs/helmboot/pkg/common"
+	"github.com/jenkins-x/jx/pkg/cmd/helper"
+	"github.com/jenkins-x/jx/pkg/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/jxfactory"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusLong = templates.LongDesc(`
+		Displays the history of the boot Jobs including the git commit booted, the outcome and the error which failed the boot
+`)
+
+	statusExample = templates.Examples(`
+		# display the recent boot Jobs
+		%s status
+	`)
+)
+
+// StatusOptions the options for viewing the boot Job history
+type StatusOptions struct {
+	JXFactory jxfactory.Factory
+	Namespace string
+	Out       io.Writer
+
+	// Records the records of the boot Jobs with the most recent first
+	Records []*bootjob.JobRecord
+}
+
+// NewCmdStatus creates a command object for the command
+func NewCmdStatus() (*cobra.Command, *StatusOptions) {
+	o := &StatusOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "status",
+		Short:   "Displays the history of the boot Jobs",
+		Long:    statusLong,
+		Example: fmt.Sprintf(statusExample, common.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "the namespace of the boot Jobs. Defaults to the current namespace")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *StatusOptions) Run() error {
+	if o.JXFactory == nil {
+		o.JXFactory = jxfactory.NewFactory()
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	kubeClient, ns, err := o.JXFactory.CreateKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create kube client")
+	}
+	if o.Namespace != "" {
+		ns = o.Namespace
+	}
+
+	jobs, err := bootjob.ListJobs(kubeClient, ns)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		log.Logger().Infof("no boot Jobs found in namespace %s", ns)
+		return nil
+	}
+
+	t := table.CreateTable(o.Out)
+	t.AddRow("RUN", "JOB", "OUTCOME", "GIT URL", "SHA", "VERSION", "STARTED", "FINISHED", "FAILED STEP", "ERROR")
+	for i := range jobs {
+		r := bootjob.ToJobRecord(&jobs[i])
+		o.Records = append(o.Records, r)
+
+		run := ""
+		if r.Run > 0 {
+			run = strconv.Itoa(r.Run)
+		}
+		sha := r.GitSHA
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		t.AddRow(run, r.Name, r.Outcome, r.GitURL, sha, r.Version, bootjob.FormatTime(r.Start), bootjob.FormatTime(r.End), r.FailedStep, r.FailedErr)
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,59 @@
+package status_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/bootjob"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/status"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakejxfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestStatus(t *testing.T) {
+	ns := "jx"
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bootjob.JobNameForRun(1),
+			Namespace: ns,
+			Labels: map[string]string{
+				bootjob.LabelApp: bootjob.JobName,
+				bootjob.LabelRun: "1",
+			},
+			Annotations: map[string]string{
+				bootjob.AnnotationGitSHA: "0123456789abcdef",
+				bootjob.AnnotationError:  "failed to run step verify-env",
+			},
+		},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{
+				{
+					Type:   batchv1.JobFailed,
+					Status: corev1.ConditionTrue,
+					Reason: "BackoffLimitExceeded",
+				},
+			},
+		},
+	}
+	f := fakejxfactory.NewFakeFactoryWithObjects([]runtime.Object{job}, nil, ns)
+
+	out := &bytes.Buffer{}
+	_, o := status.NewCmdStatus()
+	o.JXFactory = f
+	o.Out = out
+	err := o.Run()
+	require.NoError(t, err, "failed to run status")
+
+	require.Len(t, o.Records, 1, "records")
+	assert.Equal(t, "Failed", o.Records[0].Outcome, "outcome")
+	assert.Equal(t, "failed to run step verify-env", o.Records[0].FailedErr, "failure")
+
+	text := out.String()
+	assert.Contains(t, text, "jx-boot-1", "output")
+	assert.Contains(t, text, "0123456", "output")
+}
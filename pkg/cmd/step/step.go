@@ -19,6 +19,7 @@ func NewCmdStep() *cobra.Command {
 			}
 		},
 	}
+	command.AddCommand(common.SplitCommand(NewCmdRestoreActivities()))
 	command.AddCommand(common.SplitCommand(NewCmdStatus()))
 	return command
 }
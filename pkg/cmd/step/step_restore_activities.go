@@ -0,0 +1,239 @@
+package step
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/common"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/cmd/helper"
+	"github.com/jenkins-x/jx/pkg/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/jxfactory"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	restoreActivitiesLong = templates.LongDesc(`
+		Restores the PipelineActivity resources migrated into the git repository by the upgrade command so that the build history survives a cluster migration
+
+		The checksum of the restored file is recorded in a ConfigMap so that the same file is only restored once. This step is run by the boot Job after a successful boot
+`)
+
+	restoreActivitiesExample = templates.Examples(`
+		# restores the PipelineActivity resources from the current directory
+		%s step restore-activities
+
+		# restores the PipelineActivity resources replacing any existing resources and archiving the file afterwards
+		%s step restore-activities --overwrite --archive-file /tmp/pipelineActivities.yaml
+	`)
+)
+
+const (
+	// RestoredActivitiesConfigMapName the name of the ConfigMap recording the checksum of the last restored file
+	RestoredActivitiesConfigMapName = "jx-boot-restored-activities"
+
+	// restoredChecksumKey the ConfigMap data key of the checksum
+	restoredChecksumKey = "sha256"
+)
+
+// RestoreActivitiesOptions the options for restoring PipelineActivity resources
+type RestoreActivitiesOptions struct {
+	JXFactory   jxfactory.Factory
+	Dir         string
+	Namespace   string
+	ArchiveFile string
+	Overwrite   bool
+	KeepFile    bool
+
+	// Created the names of the resources created
+	Created []string
+
+	// Updated the names of the resources updated
+	Updated []string
+
+	// Skipped the names of the existing resources which were skipped
+	Skipped []string
+
+	// AlreadyRestored true if the file had already been restored
+	AlreadyRestored bool
+}
+
+// NewCmdRestoreActivities creates a command object for the command
+func NewCmdRestoreActivities() (*cobra.Command, *RestoreActivitiesOptions) {
+	o := &RestoreActivitiesOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "restore-activities",
+		Short:   "Restores the PipelineActivity resources migrated into the git repository",
+		Long:    restoreActivitiesLong,
+		Example: fmt.Sprintf(restoreActivitiesExample, common.BinaryName, common.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory containing the "+common.PipelineActivitiesYAMLFile+" file")
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "the namespace to restore the resources into. Defaults to the current namespace")
+	cmd.Flags().StringVarP(&o.ArchiveFile, "archive-file", "", "", "if specified the file is moved to this location after the resources are restored")
+	cmd.Flags().BoolVarP(&o.Overwrite, "overwrite", "", false, "replaces any existing PipelineActivity resources rather than skipping them")
+	cmd.Flags().BoolVarP(&o.KeepFile, "keep-file", "", false, "keeps the file after the resources are restored rather than removing it")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *RestoreActivitiesOptions) Run() error {
+	fileName := filepath.Join(o.Dir, common.PipelineActivitiesYAMLFile)
+	exists, err := util.FileExists(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if file exists %s", fileName)
+	}
+	if !exists {
+		log.Logger().Infof("no PipelineActivity resources to restore as there is no file %s", fileName)
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", fileName)
+	}
+	paList := &v1.PipelineActivityList{}
+	err = yaml.Unmarshal(data, paList)
+	if err != nil {
+		return errors.Wrapf(err, "failed to unmarshal PipelineActivity resources from file %s", fileName)
+	}
+
+	if o.JXFactory == nil {
+		o.JXFactory = jxfactory.NewFactory()
+	}
+	jxClient, ns, err := o.JXFactory.CreateJXClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create the Jenkins X client")
+	}
+	kubeClient, _, err := o.JXFactory.CreateKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create kube client")
+	}
+	if o.Namespace != "" {
+		ns = o.Namespace
+	}
+
+	// lets only restore the same file once as the file may not be removed from git
+	checksum := fmt.Sprintf("%x", sha256.Sum256(data))
+	cm, err := kubeClient.CoreV1().ConfigMaps(ns).Get(RestoredActivitiesConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get ConfigMap %s in namespace %s", RestoredActivitiesConfigMapName, ns)
+		}
+		cm = nil
+	}
+	if cm != nil && cm.Data[restoredChecksumKey] == checksum && !o.Overwrite {
+		o.AlreadyRestored = true
+		log.Logger().Infof("the PipelineActivity resources in file %s have already been restored", fileName)
+		return o.removeFile(fileName)
+	}
+
+	paInterface := jxClient.JenkinsV1().PipelineActivities(ns)
+	for i := range paList.Items {
+		pa := &paList.Items[i]
+		name := pa.Name
+		if pa.Namespace != "" && pa.Namespace != ns {
+			log.Logger().Debugf("moving PipelineActivity %s from namespace %s to %s", name, pa.Namespace, ns)
+		}
+		pa.Namespace = ns
+		pa.ResourceVersion = ""
+
+		existing, err := paInterface.Get(name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to get PipelineActivity %s in namespace %s", name, ns)
+			}
+			_, err = paInterface.Create(pa)
+			if err != nil {
+				return errors.Wrapf(err, "failed to create PipelineActivity %s in namespace %s", name, ns)
+			}
+			o.Created = append(o.Created, name)
+			continue
+		}
+		if !o.Overwrite {
+			o.Skipped = append(o.Skipped, name)
+			continue
+		}
+		pa.ResourceVersion = existing.ResourceVersion
+		_, err = paInterface.Update(pa)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update PipelineActivity %s in namespace %s", name, ns)
+		}
+		o.Updated = append(o.Updated, name)
+	}
+
+	log.Logger().Infof("restored PipelineActivity resources in namespace %s: %d created, %d updated, %d skipped", util.ColorInfo(ns), len(o.Created), len(o.Updated), len(o.Skipped))
+
+	err = saveRestoredChecksum(kubeClient, ns, cm, checksum)
+	if err != nil {
+		return err
+	}
+	return o.removeFile(fileName)
+}
+
+// saveRestoredChecksum records the checksum of the restored file in the ConfigMap
+func saveRestoredChecksum(kubeClient kubernetes.Interface, ns string, cm *corev1.ConfigMap, checksum string) error {
+	var err error
+	if cm == nil {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      RestoredActivitiesConfigMapName,
+				Namespace: ns,
+			},
+			Data: map[string]string{
+				restoredChecksumKey: checksum,
+			},
+		}
+		_, err = kubeClient.CoreV1().ConfigMaps(ns).Create(cm)
+	} else {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[restoredChecksumKey] = checksum
+		_, err = kubeClient.CoreV1().ConfigMaps(ns).Update(cm)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to save ConfigMap %s in namespace %s", RestoredActivitiesConfigMapName, ns)
+	}
+	return nil
+}
+
+// removeFile removes or archives the file once the resources are restored
+func (o *RestoreActivitiesOptions) removeFile(fileName string) error {
+	if o.ArchiveFile != "" {
+		err := os.MkdirAll(filepath.Dir(o.ArchiveFile), util.DefaultWritePermissions)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create the parent directory of %s", o.ArchiveFile)
+		}
+		err = util.RenameFile(fileName, o.ArchiveFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to archive file %s to %s", fileName, o.ArchiveFile)
+		}
+		log.Logger().Infof("archived file %s to %s", fileName, util.ColorInfo(o.ArchiveFile))
+		return nil
+	}
+	if o.KeepFile {
+		return nil
+	}
+	err := os.Remove(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove file %s", fileName)
+	}
+	log.Logger().Infof("removed file %s", fileName)
+	return nil
+}
@@ -0,0 +1,70 @@
+package step_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/step"
+	"github.com/jenkins-x-labs/helmboot/pkg/common"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakejxfactory"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestRestoreActivities(t *testing.T) {
+	ns := "jx"
+	existing := &v1.PipelineActivity{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myorg-myapp-master-1",
+			Namespace: ns,
+		},
+		Spec: v1.PipelineActivitySpec{
+			Build: "1",
+		},
+	}
+	f := fakejxfactory.NewFakeFactoryWithObjects(nil, []runtime.Object{existing}, ns)
+
+	dir, err := ioutil.TempDir("", "helmboot-restore-activities-")
+	require.NoError(t, err, "failed to create temp dir")
+	err = util.CopyDir(filepath.Join("test_data", "restore-activities"), dir, true)
+	require.NoError(t, err, "failed to copy test data to %s", dir)
+
+	_, o := step.NewCmdRestoreActivities()
+	o.JXFactory = f
+	o.Dir = dir
+	err = o.Run()
+	require.NoError(t, err, "failed to restore activities")
+
+	assert.Equal(t, []string{"myorg-myapp-master-2"}, o.Created, "created activities")
+	assert.Equal(t, []string{"myorg-myapp-master-1"}, o.Skipped, "skipped activities")
+	assert.Empty(t, o.Updated, "updated activities")
+
+	jxClient, _, err := f.CreateJXClient()
+	require.NoError(t, err, "failed to create jx client")
+	pa, err := jxClient.JenkinsV1().PipelineActivities(ns).Get("myorg-myapp-master-2", metav1.GetOptions{})
+	require.NoError(t, err, "failed to find restored activity")
+	assert.Equal(t, v1.ActivityStatusTypeFailed, pa.Spec.Status, "restored activity status")
+
+	fileName := filepath.Join(dir, common.PipelineActivitiesYAMLFile)
+	exists, err := util.FileExists(fileName)
+	require.NoError(t, err, "failed to check file exists %s", fileName)
+	assert.False(t, exists, "should have removed the file %s", fileName)
+
+	// restoring the same file again should be a no-op
+	err = util.CopyDir(filepath.Join("test_data", "restore-activities"), dir, true)
+	require.NoError(t, err, "failed to copy test data to %s", dir)
+
+	_, o = step.NewCmdRestoreActivities()
+	o.JXFactory = f
+	o.Dir = dir
+	o.KeepFile = true
+	err = o.Run()
+	require.NoError(t, err, "failed to restore activities again")
+	assert.True(t, o.AlreadyRestored, "should have detected the file was already restored")
+	assert.Empty(t, o.Created, "created activities on the second restore")
+}
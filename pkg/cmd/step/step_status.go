@@ -4,15 +4,15 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jenkins-x-labs/helmboot/pkg/common"
-	"github.com/jenkins-x-labs/helmboot/pkg/envfactory"
+	"github.com/jenkins-x-labs/helmboot/pkg/deploystatus"
 	"github.com/jenkins-x-labs/helmboot/pkg/jxadapt"
 	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
 	"github.com/jenkins-x/go-scm/scm"
 	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
 	"github.com/jenkins-x/jx/pkg/client/clientset/versioned"
-	"github.com/jenkins-x/jx/pkg/cloud"
 	"github.com/jenkins-x/jx/pkg/cmd/helper"
 	"github.com/jenkins-x/jx/pkg/cmd/templates"
 	"github.com/jenkins-x/jx/pkg/config"
@@ -30,17 +30,36 @@ import (
 var (
 	statusLong = templates.LongDesc(`
 		Updates the git deployment status after a promotion
+
+		The state of the deployment is derived from the rollout of the app in the target namespace.
+		A status is only posted when the state changes and the deployments of previous versions are marked inactive
+
+		When using --watch the Releases in all namespaces are watched so the ServiceAccount needs a ClusterRole which can
+		get, list and watch releases.jenkins.io, deployments, replicasets and pods in all namespaces along with
+		get, create and update of leases in the development namespace for the leader election
 `)
 
 	statusExample = templates.Examples(`
 		# update the status in git after a promote pipeline
 		%s step status
+
+		# run as a controller updating the status in git as promotions happen
+		%s step status --watch
 	`)
 )
 
 // StatusOptions the options for viewing running PRs
 type StatusOptions struct {
-	JXFactory jxfactory.Factory
+	JXFactory      jxfactory.Factory
+	Watch          bool
+	ResyncPeriod   time.Duration
+	Workers        int
+	LeaderElect    bool
+	MetricsAddress string
+	Links          deploystatus.LinksConfig
+
+	// requirementsCache caches the dev Environment requirements for the resync period
+	requirementsCache *deploystatus.RequirementsCache
 }
 
 // NewCmdStatus creates a command object for the command
@@ -51,13 +70,20 @@ func NewCmdStatus() (*cobra.Command, *StatusOptions) {
 		Use:     "status",
 		Short:   "Updates the git deployment status after a promotion",
 		Long:    statusLong,
-		Example: fmt.Sprintf(statusExample, common.BinaryName),
+		Example: fmt.Sprintf(statusExample, common.BinaryName, common.BinaryName),
 		Run: func(cmd *cobra.Command, args []string) {
 			err := o.Run()
 			helper.CheckErr(err)
 		},
 	}
-
+	cmd.Flags().BoolVarP(&o.Watch, "watch", "w", false, "runs as a controller watching Releases and Deployments to update the git deployment statuses as promotions happen")
+	cmd.Flags().DurationVarP(&o.ResyncPeriod, "resync", "", deploystatus.DefaultResyncPeriod, "the period to re-process all Releases when using --watch")
+	cmd.Flags().IntVarP(&o.Workers, "workers", "", 1, "the number of workers processing Releases when using --watch")
+	cmd.Flags().BoolVarP(&o.LeaderElect, "leader-elect", "", true, "uses leader election when using --watch so that only one replica updates the statuses")
+	cmd.Flags().StringVarP(&o.MetricsAddress, "metrics-addr", "", ":8080", "the address to expose the metrics on when using --watch. Disabled if empty")
+	cmd.Flags().StringVarP(&o.Links.LogURL, "log-url", "", "", "the go template of the log URL of the deployment statuses. Overrides the "+deploystatus.LinksLogURLKey+" in the "+deploystatus.LinksConfigMapName+" ConfigMap")
+	cmd.Flags().StringVarP(&o.Links.EnvironmentURL, "environment-url", "", "", "the go template of the environment URL of the deployment statuses. Overrides the "+deploystatus.LinksEnvironmentURLKey+" in the "+deploystatus.LinksConfigMapName+" ConfigMap")
+	cmd.Flags().StringVarP(&o.Links.AzureResourceGroup, "azure-resource-group", "", "", "the resource group of the AKS cluster used to link to Log Analytics. Overrides the "+deploystatus.LinksAzureResourceGroupKey+" in the "+deploystatus.LinksConfigMapName+" ConfigMap")
 	return cmd, o
 }
 
@@ -75,6 +101,14 @@ func (o *StatusOptions) Run() error {
 		return err
 	}
 
+	o.requirementsCache = deploystatus.NewRequirementsCache(o.ResyncPeriod, func() (*v1.Environment, *config.RequirementsConfig, error) {
+		return reqhelpers.GetRequirementsFromEnvironment(kubeClient, jxClient, ns)
+	})
+
+	if o.Watch {
+		return o.watch(kubeClient, jxClient, ns)
+	}
+
 	list, err := jxClient.JenkinsV1().Releases(ns).List(metav1.ListOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -84,7 +118,7 @@ func (o *StatusOptions) Run() error {
 		return err
 	}
 	for _, r := range list.Items {
-		err = o.updateStatus(&r, kubeClient, jxClient, ns)
+		_, err = o.updateStatus(&r, kubeClient, jxClient, ns)
 		if err != nil {
 			return err
 		}
@@ -92,140 +126,139 @@ func (o *StatusOptions) Run() error {
 	return nil
 }
 
-func (o *StatusOptions) updateStatus(r *v1.Release, kubeClient kubernetes.Interface, jxClient versioned.Interface, ns string) error {
+func (o *StatusOptions) updateStatus(r *v1.Release, kubeClient kubernetes.Interface, jxClient versioned.Interface, ns string) (bool, error) {
 	owner := r.Spec.GitOwner
 	gitURL := r.Spec.GitCloneURL
 	if gitURL == "" {
 		log.Logger().Warnf("no GitCloneURL for release %s", r.Name)
-		return nil
+		return false, nil
 	}
 	gitInfo, err := gits.ParseGitURL(gitURL)
 	if err != nil {
-		return errors.Wrapf(err, "failed to parse git URL for release %s", r.Name)
+		return false, errors.Wrapf(err, "failed to parse git URL for release %s", r.Name)
 	}
 	server := gitInfo.HostURL()
-	gitKind := gits.SaasGitKind(server)
 
-	scmClient, _, err := o.JXAdapter().ScmClient(server, owner, gitKind)
+	devEnv, requirements, err := o.requirementsCache.Get()
 	if err != nil {
-		return errors.Wrapf(err, "failed to create SCM client for server %s", server)
+		return false, errors.Wrapf(err, "failed to get requirements from namespace %s", ns)
 	}
 
-	if scmClient.Deployments == nil {
-		log.Logger().Warnf("cannot update deployment status of release %s as the git server %s does not support Deployments", r.Name, server)
-		return nil
+	gitKind := o.gitKind(requirements, server)
+	scmClient, _, err := o.JXAdapter().ScmClient(server, owner, gitKind)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to create SCM client for server %s", server)
 	}
 	ctx := context.Background()
 	fullName := scm.Join(owner, r.Spec.GitRepository)
 
-	devEnv, requirements, err := reqhelpers.GetRequirementsFromEnvironment(kubeClient, jxClient, ns)
-	if err != nil {
-		return errors.Wrapf(err, "failed to get requirements from namespace %s", ns)
-	}
-
 	releaseNS := r.Namespace
 	if releaseNS == "" {
 		releaseNS = ns
 	}
-	environment := environmentLabel(jxClient, devEnv.Namespace, releaseNS)
+	env := findEnvironment(jxClient, devEnv.Namespace, releaseNS)
+	environment := environmentLabel(env, releaseNS)
 	version := r.Spec.Version
+	appName := r.Spec.GitRepository
 
-	// lets try find the existing deployment if it exists
-	deployments, _, err := scmClient.Deployments.List(ctx, fullName, scm.ListOptions{})
-	if err != nil && !envfactory.IsScmNotFound(err) {
-		return err
+	state, stateDescription, err := deploystatus.State(kubeClient, releaseNS, appName, version)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to find the deployment state of app %s in namespace %s", appName, releaseNS)
 	}
-	var deployment *scm.Deployment
-	for _, d := range deployments {
-		if d.Ref == version && d.Environment == environment {
-			log.Logger().Infof("found existing deployment %s", d.Link)
-			deployment = d
-			break
-		}
+	log.Logger().Debugf("app %s version %s in namespace %s is %s: %s", appName, version, releaseNS, state, stateDescription)
+
+	targetLink, err := services.FindServiceURL(kubeClient, ns, appName)
+	if err != nil {
+		log.Logger().Warnf("failed to find Target URL for app %s version %s: %s", appName, version, err.Error())
 	}
+	description := fmt.Sprintf("Deployment %s: %s", strings.TrimPrefix(version, "v"), stateDescription)
 
-	appName := r.Spec.GitRepository
-	if deployment == nil {
-		deploymentInput := &scm.DeploymentInput{
-			Ref:                   version,
-			Task:                  "deploy",
-			Environment:           environment,
-			Description:           fmt.Sprintf("release %s for version %s", appName, version),
-			RequiredContexts:      nil,
-			AutoMerge:             false,
-			TransientEnvironment:  false,
-			ProductionEnvironment: strings.HasPrefix(strings.ToLower(environment), "prod"),
+	linkResolver, err := deploystatus.NewLinkResolver(kubeClient, devEnv.Namespace, o.Links)
+	if err != nil {
+		log.Logger().Warnf("failed to load the deployment links configuration: %s", err.Error())
+	}
+	linkContext := deploystatus.NewLinkContext(requirements, releaseNS, appName, version, environment)
+	logLink, err := linkResolver.LogURL(linkContext)
+	if err != nil {
+		log.Logger().Warnf("failed to create the log URL for app %s version %s: %s", appName, version, err.Error())
+	}
+	environmentLink, err := linkResolver.EnvironmentURL(linkContext, env)
+	if err != nil {
+		log.Logger().Warnf("failed to create the environment link for app %s version %s: %s", appName, version, err.Error())
+	}
+
+	request := &deploystatus.Request{
+		FullName:    fullName,
+		AppName:     appName,
+		Version:     version,
+		Environment: environment,
+		Status: scm.DeploymentStatusInput{
+			State:           state,
+			TargetLink:      targetLink,
+			LogLink:         logLink,
+			Description:     description,
+			EnvironmentLink: environmentLink,
+			AutoInactive:    false,
+		},
+	}
+	if scmClient.Deployments != nil {
+		posted, err := deploystatus.Sync(ctx, scmClient, request)
+		if err == nil {
+			return posted, nil
 		}
-		deployment, _, err = scmClient.Deployments.Create(ctx, fullName, deploymentInput)
-		if err != nil {
-			return errors.Wrapf(err, "failed to create Deployment for server %s and release %s", server, r.Name)
+		if !deploystatus.IsNotSupported(err) {
+			return false, errors.Wrapf(err, "failed to update the deployment status for server %s and release %s", server, r.Name)
 		}
-		log.Logger().Infof("created Deployment for release %s at %s", r.Name, deployment.Link)
 	}
-
-	// lets create a new status
-	targetLink, err := services.FindServiceURL(kubeClient, ns, appName)
+	log.Logger().Debugf("the git server %s does not support Deployments so using commit statuses for release %s", server, r.Name)
+	posted, err := deploystatus.SyncCommitStatus(ctx, scmClient, request, r)
 	if err != nil {
-		log.Logger().Warnf("failed to find Target URL for app %s version %s: %s", appName, version, err.Error())
+		return false, errors.Wrapf(err, "failed to update the commit status for server %s and release %s", server, r.Name)
 	}
-	logLink := getLogURL(requirements, ns, appName)
-	description := fmt.Sprintf("Deployment %s", strings.TrimPrefix(version, "v"))
-
-	// TODO this could link to the UI?
-	environmentLink := ""
+	return posted, nil
+}
 
-	deploymentStatusInput := &scm.DeploymentStatusInput{
-		State:           "success",
-		TargetLink:      targetLink,
-		LogLink:         logLink,
-		Description:     description,
-		Environment:     environment,
-		EnvironmentLink: environmentLink,
-		AutoInactive:    false,
+// gitKind resolves the kind of the git server from the requirements, the local git auth config or the SaaS providers
+func (o *StatusOptions) gitKind(requirements *config.RequirementsConfig, server string) string {
+	c := &requirements.Cluster
+	if c.GitKind != "" && c.GitServer != "" && strings.TrimSuffix(c.GitServer, "/") == strings.TrimSuffix(server, "/") {
+		return c.GitKind
 	}
-	status, _, err := scmClient.Deployments.CreateStatus(ctx, fullName, deployment.ID, deploymentStatusInput)
+	kind, err := o.JXAdapter().GitKind(server)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create DeploymentStatus for server %s and release %s", server, r.Name)
+		log.Logger().Warnf("failed to find the git kind of server %s from the git auth config: %s", server, err.Error())
+		return gits.SaasGitKind(server)
 	}
-	log.Logger().Infof("created DeploymentStatus for release %s at %s with Logs URL %s and Target URL %s", r.Name, status.ID, logLink, targetLink)
-	return nil
+	return kind
 }
 
-// environmentLabel returns the environment label for the given target namespace
-func environmentLabel(jxClient versioned.Interface, devNS string, targetNS string) string {
+// findEnvironment returns the Environment for the given target namespace or nil if there is none
+func findEnvironment(jxClient versioned.Interface, devNS string, targetNS string) *v1.Environment {
 	list, err := jxClient.JenkinsV1().Environments(devNS).List(metav1.ListOptions{})
 	if err != nil && !apierrors.IsNotFound(err) {
 		log.Logger().Warnf("failed to find Environment CRDs in namespace %s", devNS)
 	}
 	if list != nil {
-		for _, e := range list.Items {
+		for i := range list.Items {
+			e := &list.Items[i]
 			if e.Spec.Namespace == targetNS {
-				answer := e.Spec.Label
-				if answer == "" {
-					answer = e.Name
-				}
-				return answer
+				return e
 			}
 		}
 	}
-	// use a default value of the namespace without a prefix
-	return strings.TrimPrefix(targetNS, "jx-")
-}
-
-func getLogURL(requirements *config.RequirementsConfig, ns string, appName string) string {
-	c := &requirements.Cluster
-	if c.Provider == cloud.GKE {
-		return ContainerLogsURL(c.ProjectID, c.ClusterName, appName, ns)
-	}
-	return ""
+	return nil
 }
 
-// ContainerLogsURL generates the URL for a container logs URL
-func ContainerLogsURL(projectName, clusterName, containerName, ns string) string {
-	if projectName != "" && clusterName != "" && containerName != "" {
-		return `https://console.cloud.google.com/logs/viewer?authuser=1&project=` + projectName + `&minLogLevel=0&expandAll=false&customFacets=&limitCustomFacetWidth=true&interval=PT1H&resource=k8s_container%2Fcluster_name%2F` + clusterName + `%2Fnamespace_name%2F` + ns + `%2Fcontainer_name%2F` + containerName + `&dateRangeUnbound=both`
+// environmentLabel returns the environment label for the given Environment or target namespace
+func environmentLabel(env *v1.Environment, targetNS string) string {
+	if env != nil {
+		if env.Spec.Label != "" {
+			return env.Spec.Label
+		}
+		return env.Name
 	}
-	return ""
+	// use a default value of the namespace without a prefix
+	return strings.TrimPrefix(targetNS, "jx-")
 }
 
 // JXAdapter creates an adapter to the jx code
@@ -0,0 +1,106 @@
+package step
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/deploystatus"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// leaderElectionLockName the name of the Lease used to elect the leader of the status controllers
+	leaderElectionLockName = "helmboot-step-status"
+
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// watch runs the controller updating the deployment statuses of the Releases in all namespaces until the process
+// is terminated. The leader election Lease is kept in the development namespace
+func (o *StatusOptions) watch(kubeClient kubernetes.Interface, jxClient versioned.Interface, ns string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Logger().Infof("shutting down")
+		cancel()
+	}()
+
+	metrics := deploystatus.NewMetrics()
+	if o.MetricsAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		server := &http.Server{Addr: o.MetricsAddress, Handler: mux}
+		go func() {
+			err := server.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				log.Logger().Errorf("failed to serve metrics on %s: %s", o.MetricsAddress, err.Error())
+			}
+		}()
+		defer server.Close()
+	}
+
+	sync := func(r *v1.Release) (bool, error) {
+		return o.updateStatus(r, kubeClient, jxClient, ns)
+	}
+	// promotions create the Releases in the environment namespaces such as jx-staging so lets watch all namespaces.
+	// This requires the ServiceAccount to list and watch Releases, Deployments, ReplicaSets and pods in all namespaces
+	controller := deploystatus.NewController(kubeClient, jxClient, metav1.NamespaceAll, o.ResyncPeriod, sync, metrics)
+	workers := o.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	if !o.LeaderElect {
+		return controller.Run(ctx, workers)
+	}
+
+	identity, err := os.Hostname()
+	if err != nil {
+		return errors.Wrap(err, "failed to find the hostname for the leader election identity")
+	}
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      leaderElectionLockName,
+			Namespace: ns,
+		},
+		Client: kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+	var runErr error
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: renewDeadline,
+		RetryPeriod:   retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Logger().Infof("%s is now the leader", identity)
+				runErr = controller.Run(ctx, workers)
+				cancel()
+			},
+			OnStoppedLeading: func() {
+				log.Logger().Infof("%s is no longer the leader", identity)
+				cancel()
+			},
+		},
+	})
+	return runErr
+}
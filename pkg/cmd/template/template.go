@@ -0,0 +1,175 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
+	"github.com/jenkins-x-labs/helmboot/pkg/common"
+	"github.com/jenkins-x-labs/helmboot/pkg/githelpers"
+	"github.com/jenkins-x-labs/helmboot/pkg/helmfiles"
+	"github.com/jenkins-x-labs/helmboot/pkg/secretmgr"
+	"github.com/jenkins-x/jx/pkg/cmd/helper"
+	"github.com/jenkins-x/jx/pkg/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/gits"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templateLong = templates.LongDesc(`
+		Renders the kubernetes resources that boot would apply into a directory so they can be reviewed, for example in a Pull Request pipeline.
+
+		The resources are grouped by helmfile, namespace and release
+`)
+
+	templateExample = templates.Examples(`
+		# renders the resources from the current directory into the 'output' directory using dummy secrets
+		%s template
+
+		# renders the resources from a git repository using real secrets
+		%s template --git-url https://github.com/myorg/environment-mycluster-dev.git --secrets-file /tmp/secrets.yaml --output-dir /tmp/manifests
+	`)
+)
+
+// TemplateOptions the options for rendering the boot resources
+type TemplateOptions struct {
+	Gitter      gits.Gitter
+	Dir         string
+	GitURL      string
+	OutDir      string
+	SecretsFile string
+	BatchMode   bool
+
+	// CommandRunner runs the helmfile commands so that they can be faked in tests
+	CommandRunner cmdrunner.CommandRunner
+
+	// HelmfileGenerator generates the helmfiles in the directory
+	HelmfileGenerator func(dir string) error
+
+	// Files the files written relative to the output directory
+	Files []string
+}
+
+// NewCmdTemplate creates a command object for the command
+func NewCmdTemplate() (*cobra.Command, *TemplateOptions) {
+	o := &TemplateOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "template",
+		Short:   "Renders the kubernetes resources boot would apply into a directory",
+		Long:    templateLong,
+		Example: fmt.Sprintf(templateExample, common.BinaryName, common.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory containing the development environment git repository if not using --git-url")
+	cmd.Flags().StringVarP(&o.GitURL, "git-url", "u", "", "the git URL of the development environment to clone")
+	cmd.Flags().StringVarP(&o.OutDir, "output-dir", "o", "output", "the directory to write the rendered resources")
+	cmd.Flags().StringVarP(&o.SecretsFile, "secrets-file", "s", "", "the secrets YAML file to use. If not specified dummy secrets are used")
+	cmd.Flags().BoolVarP(&o.BatchMode, "batch-mode", "b", false, "Runs in batch mode without prompting for user input")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *TemplateOptions) Run() error {
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.DefaultCommandRunner
+	}
+	if o.HelmfileGenerator == nil {
+		o.HelmfileGenerator = func(dir string) error {
+			return helmfiles.Generate(dir, o.BatchMode)
+		}
+	}
+
+	// lets work on a copy so we don't modify the source directory
+	dir, err := githelpers.CloneOrCopyToTempDir(o.Git(), o.GitURL, o.Dir)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	requirementsFile := filepath.Join(dir, config.RequirementsConfigFileName)
+	exists, err := util.FileExists(requirementsFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if file exists %s", requirementsFile)
+	}
+	if !exists {
+		return errors.Errorf("no %s file found in the development environment", config.RequirementsConfigFileName)
+	}
+
+	secretsFile := o.SecretsFile
+	if secretsFile == "" {
+		secretsFile = filepath.Join(dir, "secrets.yaml")
+		err = secretmgr.WriteDummySecretsYAML(secretsFile)
+		if err != nil {
+			return err
+		}
+	}
+	secretsFile, err = filepath.Abs(secretsFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the absolute path of %s", secretsFile)
+	}
+
+	err = o.HelmfileGenerator(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate the helmfiles")
+	}
+
+	env := map[string]string{
+		"JX_SECRETS_YAML": secretsFile,
+	}
+	skipDeps := false
+	for _, d := range helmfiles.Dirs {
+		helmfileDir := filepath.Join(dir, d)
+		releases, err := helmfiles.LoadReleases(filepath.Join(helmfileDir, helmfiles.HelmfileName))
+		if err != nil {
+			return err
+		}
+		for i := range releases {
+			r := &releases[i]
+			args := []string{"--selector", "name=" + r.Name, "template"}
+			if skipDeps {
+				args = append(args, "--skip-deps")
+			}
+			c := &util.Command{
+				Name: "helmfile",
+				Args: args,
+				Dir:  helmfileDir,
+				Env:  env,
+			}
+			output, err := o.CommandRunner(c)
+			if err != nil {
+				return errors.Wrapf(err, "failed to run command: %s %s in dir %s", c.Name, strings.Join(c.Args, " "), c.Dir)
+			}
+			skipDeps = true
+
+			releaseDir := filepath.Join(d, helmfiles.ReleaseDir(r))
+			paths, err := helmfiles.WriteManifests(filepath.Join(o.OutDir, releaseDir), helmfiles.SplitManifests(output))
+			if err != nil {
+				return errors.Wrapf(err, "failed to write the manifests of release %s", r.Name)
+			}
+			for _, p := range paths {
+				o.Files = append(o.Files, filepath.Join(releaseDir, p))
+			}
+		}
+	}
+
+	log.Logger().Infof("rendered %d files into %s", len(o.Files), util.ColorInfo(o.OutDir))
+	return nil
+}
+
+// Git lazily create a gitter if its not specified
+func (o *TemplateOptions) Git() gits.Gitter {
+	if o.Gitter == nil {
+		o.Gitter = gits.NewGitCLI()
+	}
+	return o.Gitter
+}
@@ -0,0 +1,45 @@
+package template_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/template"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakerunner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplate(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "helmboot-template-output-")
+	require.NoError(t, err, "failed to create temp dir")
+	defer os.RemoveAll(outDir)
+
+	runner := &fakerunner.FakeRunner{
+		Results: map[string]string{
+			"helmfile --selector name=cert-manager template":           "---\n# Source: cert-manager/templates/deployment.yaml\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: cert-manager\n",
+			"helmfile --selector name=lighthouse template --skip-deps": "---\n# Source: lighthouse/templates/service.yaml\napiVersion: v1\nkind: Service\nmetadata:\n  name: hook\n",
+		},
+	}
+
+	_, o := template.NewCmdTemplate()
+	o.Dir = filepath.Join("test_data", "dev-env")
+	o.OutDir = outDir
+	o.CommandRunner = runner.Run
+	o.HelmfileGenerator = func(dir string) error {
+		return nil
+	}
+	err = o.Run()
+	require.NoError(t, err, "failed to run template")
+
+	assert.Equal(t, []string{
+		filepath.Join("system", "cert-manager", "cert-manager", "templates", "deployment.yaml"),
+		filepath.Join("apps", "jx", "lighthouse", "templates", "service.yaml"),
+	}, o.Files, "files")
+
+	require.Len(t, runner.Commands, 2, "commands")
+	assert.NotEmpty(t, runner.Commands[0].Env["JX_SECRETS_YAML"], "should pass the secrets YAML to helmfile")
+	assert.FileExists(t, filepath.Join(outDir, "apps", "jx", "lighthouse", "templates", "service.yaml"), "rendered file")
+}
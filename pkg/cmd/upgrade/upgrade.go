@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
 	"github.com/jenkins-x-labs/helmboot/pkg/common"
 	"github.com/jenkins-x-labs/helmboot/pkg/envfactory"
 	"github.com/jenkins-x-labs/helmboot/pkg/githelpers"
@@ -41,11 +42,18 @@ var (
 		If your cluster was created via GitOps then a Pull Request is created to upgrade the git repository to use helmfile / helm 3.
 
 		Otherwise a new git repository is created
+
+		If you specify --dry-run then the cluster is only read from and the upgrade is performed in a scratch copy of the
+		git repository (of the --dir clone if specified). A diff of the changes is displayed (or written to a patch file)
+		without modifying the cluster, committing, pushing or creating any git repositories or Pull Requests.
 `)
 
 	upgradeExample = templates.Examples(`
 		# upgrades your current cluster of Jenkins X to helm 3 / helmfile
 		%s upgrade
+
+		# review the changes the upgrade would make to the development git repository
+		%s upgrade --dry-run --patch-file upgrade.patch
 	`)
 )
 
@@ -61,7 +69,18 @@ type UpgradeOptions struct {
 	branchName           string
 	UsePullRequest       bool
 	NoCommit             bool
-	gitRepositoryExisted bool // if we are modifying an existing git repository
+	PullRequestLabels    []string
+	PullRequestDraft     bool
+	NoReviewers          bool
+	DryRun               bool
+	ResourceFilter       upgrader.ResourceFilter
+	PatchFile            string
+	Report               upgrader.UpgradeReport
+	gitRepositoryExisted bool   // if we are modifying an existing git repository
+	scratchDir           string // the scratch copy or clone used by a dry run which is removed afterwards
+
+	// CommandRunner runs the git diff of a dry run so that it can be faked in tests
+	CommandRunner cmdrunner.CommandRunner
 }
 
 // NewCmdUpgrade creates a command object for the command
@@ -72,7 +91,7 @@ func NewCmdUpgrade() (*cobra.Command, *UpgradeOptions) {
 		Use:     "upgrade",
 		Short:   "Upgrades your Development environments git repository to use helmfile and helm 3",
 		Long:    upgradeLong,
-		Example: fmt.Sprintf(upgradeExample, common.BinaryName),
+		Example: fmt.Sprintf(upgradeExample, common.BinaryName, common.BinaryName),
 		Run: func(cmd *cobra.Command, args []string) {
 			err := o.Run()
 			helper.CheckErr(err)
@@ -87,6 +106,13 @@ func (o *UpgradeOptions) AddUpgradeOptions(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&o.GitCloneURL, "git-url", "g", "", "The git repository to clone to upgrade")
 	cmd.Flags().StringVarP(&o.InitialGitURL, "initial-git-url", "", common.DefaultBootHelmfileRepository, "The git URL to clone to fetch the initial set of files for a helm 3 / helmfile based git configuration if this command is not run inside a git clone or against a GitOps based cluster")
 	cmd.Flags().BoolVarP(&o.UsePullRequest, "use-pr", "", false, "If enabled lets force the use of a Pull Request rather than creating a new git repository for the helm 3 based configuration")
+	cmd.Flags().StringArrayVarP(&o.PullRequestLabels, "pr-label", "", nil, "the labels to add to the upgrade Pull Request")
+	cmd.Flags().BoolVarP(&o.PullRequestDraft, "pr-draft", "", false, "creates the upgrade Pull Request as a draft (or as a work in progress if the git provider does not support drafts) so that it cannot be merged until it is reviewed")
+	cmd.Flags().BoolVarP(&o.NoReviewers, "no-pr-reviewers", "", false, "disables requesting reviews of the upgrade Pull Request from the development environment approvers")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "performs the upgrade in a scratch clone and displays the diff without committing, pushing or creating any git repositories or Pull Requests")
+	cmd.Flags().StringVarP(&o.PatchFile, "patch-file", "", "", "if using --dry-run then the diff is written to this patch file rather than displayed on the terminal")
+	cmd.Flags().StringArrayVarP(&o.ResourceFilter.Include, "include-kind", "", upgrader.DefaultMigrateKinds, "the kinds of Jenkins X resources to migrate into the git repository. Possible values: "+strings.Join(upgrader.MigrateKindValues, ", "))
+	cmd.Flags().StringArrayVarP(&o.ResourceFilter.Exclude, "exclude-kind", "", nil, "the kinds of Jenkins X resources to not migrate into the git repository. Possible values: "+strings.Join(upgrader.MigrateKindValues, ", "))
 
 	reqhelpers.AddGitRequirementsOptions(cmd, &o.OverrideRequirements)
 
@@ -95,6 +121,10 @@ func (o *UpgradeOptions) AddUpgradeOptions(cmd *cobra.Command) {
 
 // Run implements the command
 func (o *UpgradeOptions) Run() error {
+	err := o.ResourceFilter.Validate()
+	if err != nil {
+		return err
+	}
 	u, jxClient, ns, err := o.createUpgrader()
 	if err != nil {
 		return err
@@ -109,7 +139,7 @@ func (o *UpgradeOptions) Run() error {
 		return err
 	}
 
-	if o.gitRepositoryExisted {
+	if o.gitRepositoryExisted && !o.DryRun {
 		o.branchName, err = githelpers.CreateBranch(o.Gitter, dir)
 		if err != nil {
 			return errors.Wrapf(err, "failed to create git branch in %s", dir)
@@ -117,6 +147,10 @@ func (o *UpgradeOptions) Run() error {
 	}
 
 	reqFile := filepath.Join(dir, config.RequirementsConfigFileName)
+	err = o.recordRequirementsChanges(reqFile, req)
+	if err != nil {
+		return err
+	}
 	err = req.SaveConfig(reqFile)
 	if err != nil {
 		return errors.Wrapf(err, "failed to save migrated requirements file %s", reqFile)
@@ -141,6 +175,12 @@ func (o *UpgradeOptions) Run() error {
 
 	log.Logger().Infof("generated the boot configuration from the current cluster into the directory: %s", util.ColorInfo(dir))
 
+	if o.DryRun {
+		// the dry run always uses a scratch directory
+		defer os.RemoveAll(o.scratchDir)
+		return o.reportDryRun(dir)
+	}
+
 	// now lets add the generated files to git
 	err = o.Gitter.Add(dir, "*")
 	if err != nil {
@@ -156,14 +196,34 @@ func (o *UpgradeOptions) Run() error {
 			}
 
 			if o.GitCloneURL != "" {
-				return o.createPullRequest(dir, u)
+				return o.createPullRequest(dir, u, req)
 			}
 
 			return o.EnvFactory.PrintBootJobInstructions(req, o.GitCloneURL)
 		}
 		return nil
 	}
-	return o.EnvFactory.CreateDevEnvGitRepository(dir, req.Cluster.EnvironmentGitPublic)
+	return o.EnvFactory.CreateDevEnvGitRepository(dir, req, req.Cluster.EnvironmentGitPublic)
+}
+
+// recordRequirementsChanges records the changes to the requirements file so we can describe them in the Pull Request
+func (o *UpgradeOptions) recordRequirementsChanges(reqFile string, req *config.RequirementsConfig) error {
+	exists, err := util.FileExists(reqFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check requirements file exists %s", reqFile)
+	}
+	var oldRequirements *config.RequirementsConfig
+	if exists {
+		oldRequirements, err = config.LoadRequirementsConfigFile(reqFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load requirements file %s", reqFile)
+		}
+	}
+	o.Report.RequirementsChanges, err = upgrader.RequirementsChanges(oldRequirements, req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to compare requirements file %s", reqFile)
+	}
+	return nil
 }
 
 func (o *UpgradeOptions) removeGeneratedRequirementsValuesFile(dir string) error {
@@ -254,6 +314,11 @@ func (o *UpgradeOptions) addMissingFiles(dir string) error {
 		}
 		return nil
 	}
+	defer func() {
+		if templateDir != "" {
+			os.RemoveAll(templateDir)
+		}
+	}()
 
 	files := []string{"environments.yaml", "helmfile.yaml", "jx-apps.yml"}
 	dirs := []string{"apps", "repositories", "system"}
@@ -272,6 +337,7 @@ func (o *UpgradeOptions) addMissingFiles(dir string) error {
 			if err != nil {
 				return errors.Wrapf(err, "failed to copy missing dir %s", d)
 			}
+			o.Report.AddedFiles = append(o.Report.AddedFiles, name+"/")
 		}
 	}
 	for _, name := range files {
@@ -289,6 +355,7 @@ func (o *UpgradeOptions) addMissingFiles(dir string) error {
 			if err != nil {
 				return errors.Wrapf(err, "failed to copy missing file %s", f)
 			}
+			o.Report.AddedFiles = append(o.Report.AddedFiles, name)
 		}
 	}
 	return nil
@@ -309,6 +376,7 @@ func (o *UpgradeOptions) removeOldDirs(dir string) error {
 				return errors.Wrapf(err, "failed to remove dir %s", oldDir)
 			}
 			log.Logger().Infof("removed old folder %s", oldDir)
+			o.Report.RemovedDirs = append(o.Report.RemovedDirs, od)
 		}
 	}
 	return nil
@@ -321,13 +389,105 @@ func (o *UpgradeOptions) writeAdditionalHelmTemplateFiles(jxClient versioned.Int
 	if err != nil && !apierrors.IsNotFound(err) {
 		return errors.Wrapf(err, "failed to query the SourceRepository resources in namespace %s", ns)
 	}
-	_, err = upgrader.WriteSourceRepositoriesToGitFolder(outDir, srList)
+	fileNames, err := upgrader.WriteSourceRepositoriesToGitFolder(outDir, srList)
 	if err != nil {
 		return errors.Wrapf(err, "failed to write SourceRepository resources to %s", outDir)
 	}
+	o.Report.SourceRepositoryCount = len(fileNames)
+
+	// lets write any other Jenkins X resources we are migrating
+	for _, kind := range upgrader.MigrateKindValues {
+		if !o.ResourceFilter.Matches(kind) {
+			continue
+		}
+		resources, err := o.findResources(jxClient, ns, kind)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join(outDir, upgrader.ResourceFolderName(kind))
+		fileNames, err := upgrader.WriteResourcesToGitFolder(dir, kind, resources)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write %s resources to %s", kind, dir)
+		}
+		if len(fileNames) > 0 {
+			if o.Report.ResourceCounts == nil {
+				o.Report.ResourceCounts = map[string]int{}
+			}
+			o.Report.ResourceCounts[kind] = len(fileNames)
+			log.Logger().Infof("migrated %d %s resources to %s", len(fileNames), kind, util.ColorInfo(dir))
+		}
+	}
 	return nil
 }
 
+// findResources finds the Jenkins X resources of the given kind which should be migrated
+func (o *UpgradeOptions) findResources(jxClient versioned.Interface, ns string, kind string) ([]metav1.Object, error) {
+	var answer []metav1.Object
+	var err error
+	api := jxClient.JenkinsV1()
+	switch kind {
+	case upgrader.KindUser:
+		var list *v1.UserList
+		list, err = api.Users(ns).List(metav1.ListOptions{})
+		if list != nil {
+			for i := range list.Items {
+				answer = append(answer, &list.Items[i])
+			}
+		}
+	case upgrader.KindTeam:
+		var list *v1.TeamList
+		list, err = api.Teams(ns).List(metav1.ListOptions{})
+		if list != nil {
+			for i := range list.Items {
+				answer = append(answer, &list.Items[i])
+			}
+		}
+	case upgrader.KindScheduler:
+		var list *v1.SchedulerList
+		list, err = api.Schedulers(ns).List(metav1.ListOptions{})
+		if list != nil {
+			for i := range list.Items {
+				answer = append(answer, &list.Items[i])
+			}
+		}
+	case upgrader.KindApp:
+		var list *v1.AppList
+		list, err = api.Apps(ns).List(metav1.ListOptions{})
+		if list != nil {
+			for i := range list.Items {
+				answer = append(answer, &list.Items[i])
+			}
+		}
+	case upgrader.KindEnvironmentRoleBinding:
+		var list *v1.EnvironmentRoleBindingList
+		list, err = api.EnvironmentRoleBindings(ns).List(metav1.ListOptions{})
+		if list != nil {
+			for i := range list.Items {
+				answer = append(answer, &list.Items[i])
+			}
+		}
+	case upgrader.KindEnvironment:
+		var list *v1.EnvironmentList
+		list, err = api.Environments(ns).List(metav1.ListOptions{})
+		if list != nil {
+			for i := range list.Items {
+				// the dev and preview environments are managed by boot and previews
+				e := &list.Items[i]
+				if e.Spec.Kind == v1.EnvironmentKindTypeDevelopment || e.Spec.Kind == v1.EnvironmentKindTypePreview {
+					continue
+				}
+				answer = append(answer, e)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported resource kind %s", kind)
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to query the %s resources in namespace %s", kind, ns)
+	}
+	return answer, nil
+}
+
 // writeAdditionalHelmTemplateFiles lets store to git any extra resources managed outside of the regular boot charts
 func (o *UpgradeOptions) writeNonHelmManagedResources(jxClient versioned.Interface, ns string, dir string) error {
 	paList, err := jxClient.JenkinsV1().PipelineActivities(ns).List(metav1.ListOptions{})
@@ -356,6 +516,7 @@ func (o *UpgradeOptions) writeNonHelmManagedResources(jxClient versioned.Interfa
 		return errors.Wrapf(err, "failed to write file %s", fileName)
 	}
 	log.Logger().Infof("wrote migration resources file: %s", util.ColorInfo(common.PipelineActivitiesYAMLFile))
+	o.Report.PipelineActivityCount = len(paList.Items)
 	return nil
 }
 
@@ -375,11 +536,32 @@ func (o *UpgradeOptions) gitCloneIfRequired(gitter gits.Gitter, devSource v1.Env
 	}
 	var err error
 	dir := o.Dir
-	if dir == "" {
+	if o.DryRun && dir != "" {
+		// lets copy the git root of an existing local clone into a scratch directory rather than cloning so we
+		// don't modify it and so that any changes already staged in the clone are included in the diff
+		gitRoot, _, err := gitter.FindGitConfigDir(dir)
+		if err != nil {
+			return "", errors.Wrapf(err, "there was a problem obtaining the git config dir of directory %s", dir)
+		}
+		if gitRoot != "" {
+			rel, err := filepath.Rel(gitRoot, dir)
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to find the path of %s in the git clone %s", dir, gitRoot)
+			}
+			o.scratchDir, err = githelpers.CloneOrCopyToTempDir(gitter, "", gitRoot)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(o.scratchDir, rel), nil
+		}
+	}
+	if dir == "" || o.DryRun {
+		// lets always use a scratch clone for a dry run so we don't modify the local clone
 		dir, err = ioutil.TempDir("", "helmboot-")
 		if err != nil {
 			return "", errors.Wrap(err, "failed to create temporary directory")
 		}
+		o.scratchDir = dir
 	} else {
 		// if you specify and it has a git clone inside lets just use it rather than cloning
 		// as you may be inside a fork or something
@@ -402,6 +584,43 @@ func (o *UpgradeOptions) gitCloneIfRequired(gitter gits.Gitter, devSource v1.Env
 	return dir, nil
 }
 
+// reportDryRun displays or saves the diff of the changes made in the scratch clone
+func (o *UpgradeOptions) reportDryRun(dir string) error {
+	// lets mark any new files as intended to be added so that they are included in the diff
+	err := o.Gitter.Add(dir, "--intent-to-add", ".")
+	if err != nil {
+		return errors.Wrapf(err, "failed to add files to git in dir %s", dir)
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.DefaultCommandRunner
+	}
+	// lets diff against HEAD rather than the index so that any changes already staged in the clone are included
+	diff, err := o.CommandRunner(&util.Command{
+		Name: "git",
+		Args: []string{"diff", "HEAD"},
+		Dir:  dir,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to diff the changes in dir %s", dir)
+	}
+	if strings.TrimSpace(diff) == "" {
+		log.Logger().Infof("the upgrade would not change the git repository %s", util.ColorInfo(o.GitCloneURL))
+		return nil
+	}
+	diff = strings.TrimSuffix(diff, "\n") + "\n"
+
+	if o.PatchFile != "" {
+		err = ioutil.WriteFile(o.PatchFile, []byte(diff), util.DefaultFileWritePermissions)
+		if err != nil {
+			return errors.Wrapf(err, "failed to save patch file %s", o.PatchFile)
+		}
+		log.Logger().Infof("saved the upgrade changes to the patch file %s", util.ColorInfo(o.PatchFile))
+		return nil
+	}
+	log.Logger().Infof("the upgrade would make the following changes to the git repository %s:\n\n%s", util.ColorInfo(o.GitCloneURL), diff)
+	return nil
+}
+
 // replacePipeline if the `jenkins-x.yml` file is missing or does use the helm 3 / helmfile style configuration
 // lets replace with the new pipeline file
 func (o *UpgradeOptions) replacePipeline(dir string) error {
@@ -422,7 +641,7 @@ func (o *UpgradeOptions) replacePipeline(dir string) error {
 	return nil
 }
 
-func (o *UpgradeOptions) createPullRequest(dir string, u *upgrader.HelmfileUpgrader) error {
+func (o *UpgradeOptions) createPullRequest(dir string, u *upgrader.HelmfileUpgrader, req *config.RequirementsConfig) error {
 	remote := "origin"
 	err := o.Gitter.Push(dir, remote, false)
 	if err != nil {
@@ -451,19 +670,50 @@ func (o *UpgradeOptions) createPullRequest(dir string, u *upgrader.HelmfileUpgra
 
 	head := headPrefix + o.branchName
 
+	title := "fix: upgrade to helmfile + helm 3"
+
+	// lets copy the labels so we don't modify the options
+	labels := append([]string{}, o.PullRequestLabels...)
+	draft := o.PullRequestDraft && kind == gits.KindGitHub
+	if o.PullRequestDraft && !draft {
+		// the git provider has no draft flag so lets use the WIP convention so that the Pull Request cannot be merged until it is reviewed
+		title = "WIP: " + title
+		labels = append(labels, common.WorkInProgressLabel)
+	}
+
 	ctx := context.Background()
 	pri := &scm.PullRequestInput{
-		Title: "fix: upgrade to helmfile + helm 3",
+		Title: title,
 		Head:  head,
 		Base:  "master",
-		Body:  "",
+		Body:  o.Report.PullRequestBody(common.BinaryName),
 	}
 	repoFullName := scm.Join(gitInfo.Organisation, gitInfo.Name)
-	pr, _, err := scmClient.PullRequests.Create(ctx, repoFullName, pri)
+	var pr *scm.PullRequest
+	if draft {
+		pr, err = githelpers.CreateGitHubDraftPullRequest(ctx, scmClient, repoFullName, pri)
+	} else {
+		pr, _, err = scmClient.PullRequests.Create(ctx, repoFullName, pri)
+	}
 	if err != nil {
 		return errors.Wrapf(err, "failed to create PullRequest on %s", gitURL)
 	}
 
+	for _, label := range labels {
+		_, err = scmClient.PullRequests.AddLabel(ctx, repoFullName, pr.Number, label)
+		if err != nil {
+			log.Logger().Warnf("failed to add label %s to PullRequest %d on %s: %s", label, pr.Number, gitURL, err.Error())
+		}
+	}
+
+	reviewers := req.Cluster.DevEnvApprovers
+	if len(reviewers) > 0 && !o.NoReviewers {
+		_, err = scmClient.PullRequests.RequestReview(ctx, repoFullName, pr.Number, reviewers)
+		if err != nil {
+			log.Logger().Warnf("failed to request reviews from %s on PullRequest %d: %s", strings.Join(reviewers, ", "), pr.Number, err.Error())
+		}
+	}
+
 	// the URL should not really end in .diff - fix in go-scm
 	link := strings.TrimSuffix(pr.Link, ".diff")
 	log.Logger().Infof("created Pull Request %s", util.ColorInfo(link))
@@ -3,14 +3,18 @@ package upgrade_test
 import (
 	"context"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/upgrade"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
 	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakegit"
 	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakejxfactory"
 	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/gits"
+	"github.com/jenkins-x/jx/pkg/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -35,25 +39,8 @@ func TestUpgrade(t *testing.T) {
 		testDir := filepath.Join(sourceDir, name)
 		envDir := filepath.Join(testDir, "env")
 
-		files, err := ioutil.ReadDir(envDir)
-		require.NoError(t, err, "failed to read dir %s", envDir)
-
 		kubeObjects := []runtime.Object{}
-		jxObjects := []runtime.Object{}
-		for _, f := range files {
-			if !f.IsDir() && filepath.Ext(f.Name()) == ".yaml" {
-				e := &v1.Environment{}
-				fileName := filepath.Join(envDir, f.Name())
-				t.Logf("loading environment %s", fileName)
-				data, err := ioutil.ReadFile(fileName)
-				require.NoError(t, err, "failed to load environment %s", fileName)
-
-				err = yaml.Unmarshal(data, e)
-				require.NoError(t, err, "failed to unmarshal environment %s", fileName)
-				e.Namespace = ns
-				jxObjects = append(jxObjects, e)
-			}
-		}
+		jxObjects := loadEnvironments(t, envDir, ns)
 
 		_, uo := upgrade.NewCmdUpgrade()
 		uo.BatchMode = true
@@ -95,3 +82,105 @@ func TestUpgrade(t *testing.T) {
 		}
 	}
 }
+
+func TestUpgradeDryRun(t *testing.T) {
+	ns := "jx"
+	envDir := filepath.Join("test_data", "jx-boot-gitops", "env")
+	jxObjects := loadEnvironments(t, envDir, ns)
+
+	// lets create a local clone with the boot configuration in a sub directory and a change already staged
+	gitDir, err := ioutil.TempDir("", "helmboot-upgrade-clone-")
+	require.NoError(t, err, "failed to create temp dir")
+	defer os.RemoveAll(gitDir)
+
+	dir := filepath.Join(gitDir, "boot")
+	for _, d := range []string{"apps", "repositories", "system"} {
+		writeFile(t, filepath.Join(dir, d, "README.md"), d+"\n")
+	}
+	for _, f := range []string{"environments.yaml", "helmfile.yaml", "jx-apps.yml"} {
+		writeFile(t, filepath.Join(dir, f), "{}\n")
+	}
+	writeFile(t, filepath.Join(gitDir, "README.md"), "old\n")
+	runGit(t, gitDir, "init")
+	runGit(t, gitDir, "add", ".")
+	runGit(t, gitDir, "commit", "-m", "initial")
+	writeFile(t, filepath.Join(gitDir, "README.md"), "staged\n")
+	runGit(t, gitDir, "add", "README.md")
+
+	patchFile, err := ioutil.TempFile("", "helmboot-upgrade-")
+	require.NoError(t, err, "failed to create temp file")
+	patchFileName := patchFile.Name()
+	defer os.Remove(patchFileName)
+
+	var diffDirs []string
+	_, uo := upgrade.NewCmdUpgrade()
+	uo.BatchMode = true
+	uo.UsePullRequest = true
+	uo.DryRun = true
+	uo.Dir = dir
+	uo.PatchFile = patchFileName
+	uo.Gitter = gits.NewGitCLI()
+	uo.JXFactory = fakejxfactory.NewFakeFactoryWithObjects(nil, jxObjects, ns)
+	uo.CommandRunner = func(c *util.Command) (string, error) {
+		diffDirs = append(diffDirs, c.Dir)
+		return cmdrunner.DefaultCommandRunner(c)
+	}
+
+	err = uo.Run()
+	require.NoError(t, err, "failed to dry run the upgrade")
+
+	assert.Nil(t, uo.EnvFactory.ScmClient, "should not have created an SCM client in dry run mode")
+	require.Len(t, diffDirs, 1, "should have diffed the scratch copy")
+	assert.Equal(t, "boot", filepath.Base(diffDirs[0]), "should diff the boot directory inside the scratch copy")
+	_, err = os.Stat(filepath.Dir(diffDirs[0]))
+	assert.True(t, os.IsNotExist(err), "should have removed the scratch copy of the git root %s", filepath.Dir(diffDirs[0]))
+
+	data, err := ioutil.ReadFile(patchFileName)
+	require.NoError(t, err, "failed to load patch file %s", patchFileName)
+	patch := string(data)
+	assert.Contains(t, patch, "boot/jx-requirements.yml", "the patch should add the requirements")
+	assert.Contains(t, patch, "+staged", "the patch should include the changes already staged in the clone")
+
+	status := runGit(t, gitDir, "status", "--porcelain")
+	assert.Equal(t, "M  README.md", strings.TrimSpace(status), "should not have modified the local clone")
+}
+
+func writeFile(t *testing.T, fileName string, text string) {
+	err := os.MkdirAll(filepath.Dir(fileName), util.DefaultWritePermissions)
+	require.NoError(t, err, "failed to create dir for %s", fileName)
+	err = ioutil.WriteFile(fileName, []byte(text), util.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to write file %s", fileName)
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	c := &util.Command{
+		Name: "git",
+		Args: append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...),
+		Dir:  dir,
+	}
+	text, err := c.RunWithoutRetry()
+	require.NoError(t, err, "failed to run git %s in dir %s", strings.Join(args, " "), dir)
+	return text
+}
+
+func loadEnvironments(t *testing.T, envDir string, ns string) []runtime.Object {
+	files, err := ioutil.ReadDir(envDir)
+	require.NoError(t, err, "failed to read dir %s", envDir)
+
+	jxObjects := []runtime.Object{}
+	for _, f := range files {
+		if !f.IsDir() && filepath.Ext(f.Name()) == ".yaml" {
+			e := &v1.Environment{}
+			fileName := filepath.Join(envDir, f.Name())
+			t.Logf("loading environment %s", fileName)
+			data, err := ioutil.ReadFile(fileName)
+			require.NoError(t, err, "failed to load environment %s", fileName)
+
+			err = yaml.Unmarshal(data, e)
+			require.NoError(t, err, "failed to unmarshal environment %s", fileName)
+			e.Namespace = ns
+			jxObjects = append(jxObjects, e)
+		}
+	}
+	return jxObjects
+}
@@ -0,0 +1,301 @@
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/bootjob"
+	"github.com/jenkins-x-labs/helmboot/pkg/common"
+	"github.com/jenkins-x-labs/helmboot/pkg/preflight"
+	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
+	"github.com/jenkins-x/jx/pkg/cloud"
+	"github.com/jenkins-x/jx/pkg/cmd/helper"
+	"github.com/jenkins-x/jx/pkg/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/jxfactory"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	verifyClusterLong = templates.LongDesc(`
+		Verifies the current kubernetes cluster has the prerequisites needed by the jx-requirements.yml such as
+		the kubernetes version, a default StorageClass, LoadBalancer support, the required CustomResourceDefinitions,
+		enough node capacity and the RBAC for the boot ServiceAccount
+`)
+
+	verifyClusterExample = templates.Examples(`
+		# verifies the current cluster against the requirements in the current directory
+		%s verify cluster
+
+		# verifies the cluster against the requirements in a clone of the development environment
+		%s verify cluster --dir environment-mycluster-dev
+
+		# verifies the boot ServiceAccount is bound to the ClusterRole in the boot Job values
+		%s verify cluster --job-values job-values.yaml
+	`)
+
+	// loadBalancerProviders the cluster providers which support LoadBalancer Services
+	loadBalancerProviders = []string{cloud.GKE, cloud.EKS, cloud.AKS, cloud.AWS}
+
+	// noLoadBalancerProviders the cluster providers which do not support LoadBalancer Services out of the box. Any other
+	// providers may support them so the LoadBalancer check only warns if it fails
+	noLoadBalancerProviders = []string{cloud.KUBERNETES, cloud.MINIKUBE, cloud.MINISHIFT, cloud.DOCKER, "kind"}
+)
+
+// requiredCRD the CustomResourceDefinitions required by a feature of the requirements and the app which installs them
+type requiredCRD struct {
+	feature string
+	names   []string
+	app     string
+}
+
+// VerifyClusterOptions the options for verifying the cluster
+type VerifyClusterOptions struct {
+	JXFactory jxfactory.Factory
+	Dir       string
+	Overlay   string
+	MinCPU    string
+	MinMemory string
+	JobValues string
+	Out       io.Writer
+
+	// APIExtensionsClient the client used to find CRDs. Lazily created if not specified
+	APIExtensionsClient apiextensionsclientset.Interface
+
+	// Results the results of the checks
+	Results []preflight.Result
+}
+
+// NewCmdVerifyCluster creates a command object for the command
+func NewCmdVerifyCluster() (*cobra.Command, *VerifyClusterOptions) {
+	o := &VerifyClusterOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "cluster",
+		Short:   "Verifies the kubernetes cluster has the prerequisites needed by the requirements",
+		Long:    verifyClusterLong,
+		Example: fmt.Sprintf(verifyClusterExample, common.BinaryName, common.BinaryName, common.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory containing the jx-requirements.yml and jx-apps.yml files")
+	cmd.Flags().StringVarP(&o.Overlay, "overlay", "", os.Getenv(reqhelpers.OverlayEnvVar), "the requirements overlay in requirements/clusters used to compose the requirements with requirements/base.yml. Defaults to $"+reqhelpers.OverlayEnvVar)
+	cmd.Flags().StringVarP(&o.MinCPU, "min-cpu", "", "4", "the minimum total allocatable CPU of the ready nodes")
+	cmd.Flags().StringVarP(&o.MinMemory, "min-memory", "", "12Gi", "the minimum total allocatable memory of the ready nodes")
+	cmd.Flags().StringVarP(&o.JobValues, "job-values", "", "", "the YAML file of additional values for the boot Job chart used to find the clusterRole bound to the boot ServiceAccount")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *VerifyClusterOptions) Run() error {
+	if o.JXFactory == nil {
+		o.JXFactory = jxfactory.NewFactory()
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	minCPU, err := resource.ParseQuantity(o.MinCPU)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse --min-cpu %s", o.MinCPU)
+	}
+	minMemory, err := resource.ParseQuantity(o.MinMemory)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse --min-memory %s", o.MinMemory)
+	}
+	requirements, _, err := reqhelpers.LoadRequirements(o.Dir, o.Overlay)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the requirements in dir %s", o.Dir)
+	}
+	apps, _, err := config.LoadAppConfig(o.Dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the apps in dir %s", o.Dir)
+	}
+	clusterRole, err := o.bootClusterRole()
+	if err != nil {
+		return err
+	}
+	kubeClient, ns, err := o.JXFactory.CreateKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create kube client")
+	}
+	if requirements.Cluster.Namespace != "" {
+		ns = requirements.Cluster.Namespace
+	}
+
+	checks := []preflight.Check{
+		{
+			Name: "kubernetes version",
+			Run: func() (string, error) {
+				return preflight.VerifyKubeVersion(kubeClient, preflight.MinKubeVersion)
+			},
+			Hint: fmt.Sprintf("upgrade the cluster to kubernetes %s or later", preflight.MinKubeVersion),
+		},
+		{
+			Name: "default StorageClass",
+			Run: func() (string, error) {
+				return preflight.VerifyDefaultStorageClass(kubeClient)
+			},
+			Hint: "mark a StorageClass as the default via the storageclass.kubernetes.io/is-default-class annotation",
+		},
+		{
+			Name: "LoadBalancer",
+			Run: func() (string, error) {
+				return o.verifyLoadBalancer(kubeClient, requirements)
+			},
+			Hint:    "use a cluster which supports LoadBalancer Services or set ingress.serviceType to NodePort in jx-requirements.yml",
+			Warning: isUnknownLoadBalancerProvider(requirements.Cluster.Provider),
+		},
+		{
+			Name: "node capacity",
+			Run: func() (string, error) {
+				return preflight.VerifyNodeCapacity(kubeClient, minCPU, minMemory)
+			},
+			Hint: "add more nodes or use larger nodes",
+		},
+		{
+			Name: "boot RBAC",
+			Run: func() (string, error) {
+				return verifyBootRBAC(kubeClient, ns, clusterRole)
+			},
+			Hint: fmt.Sprintf("ask a cluster administrator to create the ClusterRoleBinding %s to the ClusterRole %s for the ServiceAccount %s or run boot as a cluster administrator", bootjob.ClusterRoleBindingName(ns), clusterRole, bootjob.ServiceAccountName),
+		},
+	}
+	for _, crd := range requiredCRDs(requirements) {
+		c := crd
+		checks = append(checks, preflight.Check{
+			Name: c.feature + " CRDs",
+			Run: func() (string, error) {
+				return o.verifyCRDs(c, apps)
+			},
+			Hint: fmt.Sprintf("install %s into the cluster or add the %s app to jx-apps.yml", c.feature, c.app),
+		})
+	}
+
+	o.Results = preflight.RunChecks(checks)
+	preflight.RenderTable(o.Out, o.Results)
+
+	failed := preflight.Failed(o.Results)
+	if len(failed) > 0 {
+		return errors.Errorf("%d of %d cluster checks failed", len(failed), len(o.Results))
+	}
+	return nil
+}
+
+func (o *VerifyClusterOptions) verifyLoadBalancer(kubeClient kubernetes.Interface, requirements *config.RequirementsConfig) (string, error) {
+	serviceType := requirements.Ingress.ServiceType
+	if serviceType != "" && serviceType != "LoadBalancer" {
+		return fmt.Sprintf("not required for ingress service type %s", serviceType), nil
+	}
+	supported := util.StringArrayIndex(loadBalancerProviders, requirements.Cluster.Provider) >= 0
+	return preflight.VerifyLoadBalancer(kubeClient, supported)
+}
+
+// isUnknownLoadBalancerProvider returns true if we do not know if the cluster provider supports LoadBalancer Services
+func isUnknownLoadBalancerProvider(provider string) bool {
+	return util.StringArrayIndex(loadBalancerProviders, provider) < 0 && util.StringArrayIndex(noLoadBalancerProviders, provider) < 0
+}
+
+func (o *VerifyClusterOptions) verifyCRDs(crd requiredCRD, apps *config.AppConfig) (string, error) {
+	client, err := o.apiExtensionsClient()
+	if err != nil {
+		return "", err
+	}
+	message, err := preflight.VerifyCRDs(client, crd.names...)
+	if err == nil {
+		return message, nil
+	}
+	if apps != nil {
+		for _, a := range apps.Apps {
+			if a.Name == crd.app {
+				return fmt.Sprintf("will be installed by the %s app", crd.app), nil
+			}
+		}
+	}
+	return "", err
+}
+
+// requiredCRDs returns the CRDs required by the requirements
+func requiredCRDs(requirements *config.RequirementsConfig) []requiredCRD {
+	var answer []requiredCRD
+	if requirements.Ingress.TLS.Enabled && requirements.Ingress.TLS.SecretName == "" {
+		answer = append(answer, requiredCRD{
+			feature: "cert-manager",
+			names:   []string{"certificates.cert-manager.io", "certificates.certmanager.k8s.io"},
+			app:     "jetstack/cert-manager",
+		})
+	}
+	if requirements.Ingress.Kind == config.IngressTypeIstio {
+		answer = append(answer, requiredCRD{
+			feature: "istio",
+			names:   []string{"virtualservices.networking.istio.io"},
+			app:     "jx-labs/istio",
+		})
+	}
+	return answer
+}
+
+// bootClusterRole returns the ClusterRole bound to the boot ServiceAccount from the optional boot Job values file
+func (o *VerifyClusterOptions) bootClusterRole() (string, error) {
+	if o.JobValues == "" {
+		return bootjob.ClusterRoleName(nil), nil
+	}
+	values, err := reqhelpers.LoadJobValues(o.JobValues)
+	if err != nil {
+		return "", err
+	}
+	jv, err := reqhelpers.ToBootJobValues(values)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse job values file %s", o.JobValues)
+	}
+	return bootjob.ClusterRoleName(jv), nil
+}
+
+// verifyBootRBAC verifies the boot ServiceAccount is bound to the given ClusterRole or that the current user can bind it
+func verifyBootRBAC(kubeClient kubernetes.Interface, ns string, clusterRole string) (string, error) {
+	name := bootjob.ClusterRoleBindingName(ns)
+	crb, err := kubeClient.RbacV1().ClusterRoleBindings().Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", errors.Wrapf(err, "failed to get ClusterRoleBinding %s", name)
+		}
+		_, err = preflight.VerifyCanCreate(kubeClient, "", "rbac.authorization.k8s.io", "clusterrolebindings")
+		if err != nil {
+			return "", errors.Wrapf(err, "cannot bind the boot ServiceAccount %s to %s", bootjob.ServiceAccountName, clusterRole)
+		}
+		return fmt.Sprintf("can bind the boot ServiceAccount %s to %s", bootjob.ServiceAccountName, clusterRole), nil
+	}
+	if crb.RoleRef.Kind != "ClusterRole" || crb.RoleRef.Name != clusterRole {
+		return "", errors.Errorf("the ClusterRoleBinding %s refers to %s %s rather than ClusterRole %s", name, crb.RoleRef.Kind, crb.RoleRef.Name, clusterRole)
+	}
+	for _, s := range crb.Subjects {
+		if s.Kind == "ServiceAccount" && s.Name == bootjob.ServiceAccountName && s.Namespace == ns {
+			return fmt.Sprintf("the boot ServiceAccount %s is bound to %s", bootjob.ServiceAccountName, clusterRole), nil
+		}
+	}
+	return "", errors.Errorf("the ClusterRoleBinding %s does not include the ServiceAccount %s in namespace %s", name, bootjob.ServiceAccountName, ns)
+}
+
+func (o *VerifyClusterOptions) apiExtensionsClient() (apiextensionsclientset.Interface, error) {
+	if o.APIExtensionsClient != nil {
+		return o.APIExtensionsClient, nil
+	}
+	cfg, err := o.JXFactory.CreateKubeConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes config")
+	}
+	o.APIExtensionsClient, err = apiextensionsclientset.NewForConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create API extensions client")
+	}
+	return o.APIExtensionsClient, nil
+}
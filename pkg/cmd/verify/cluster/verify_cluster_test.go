@@ -0,0 +1,216 @@
+package cluster_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/bootjob"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/verify/cluster"
+	"github.com/jenkins-x-labs/helmboot/pkg/fakes/fakejxfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestVerifyCluster(t *testing.T) {
+	ns := "jx"
+
+	testCases := []struct {
+		name           string
+		kubeVersion    string
+		overlay        string
+		jobValues      string
+		objects        []runtime.Object
+		expectChecks   []string
+		expectFailed   []string
+		expectWarnings []string
+	}{
+		{
+			name:        "valid",
+			kubeVersion: "v1.15.9-gke.24",
+			objects: append(validObjects(ns),
+				createLoadBalancer("kube-system", "nginx-ingress-controller", true),
+			),
+			expectChecks: []string{"kubernetes version", "default StorageClass", "LoadBalancer", "node capacity", "boot RBAC", "cert-manager CRDs"},
+		},
+		{
+			name:           "unknown-provider",
+			kubeVersion:    "v1.15.9",
+			objects:        validObjects(ns),
+			expectChecks:   []string{"kubernetes version", "default StorageClass", "LoadBalancer", "node capacity", "boot RBAC", "cert-manager CRDs"},
+			expectWarnings: []string{"LoadBalancer"},
+		},
+		{
+			name:        "overlay",
+			kubeVersion: "v1.15.9-gke.24",
+			overlay:     "mycluster",
+			objects: append(validObjects(ns),
+				createLoadBalancer("kube-system", "nginx-ingress-controller", true),
+			),
+			expectChecks: []string{"kubernetes version", "default StorageClass", "LoadBalancer", "node capacity", "boot RBAC", "cert-manager CRDs"},
+		},
+		{
+			name:        "custom-cluster-role",
+			kubeVersion: "v1.15.9-gke.24",
+			jobValues:   "job-values.yaml",
+			objects: append(validObjects(ns),
+				createLoadBalancer("kube-system", "nginx-ingress-controller", true),
+			),
+			expectChecks: []string{"kubernetes version", "default StorageClass", "LoadBalancer", "node capacity", "boot RBAC", "cert-manager CRDs"},
+			expectFailed: []string{"boot RBAC"},
+		},
+		{
+			name:        "invalid",
+			kubeVersion: "v1.12.10",
+			objects: []runtime.Object{
+				&storagev1.StorageClass{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "standard",
+					},
+				},
+				createNode("node1", "2", "4Gi", true),
+				createLoadBalancer("kube-system", "nginx-ingress-controller", false),
+			},
+			expectChecks: []string{"kubernetes version", "default StorageClass", "LoadBalancer", "node capacity", "boot RBAC", "istio CRDs"},
+			expectFailed: []string{"kubernetes version", "default StorageClass", "LoadBalancer", "node capacity", "boot RBAC", "istio CRDs"},
+		},
+	}
+
+	for _, tc := range testCases {
+		f := fakejxfactory.NewFakeFactoryWithObjects(tc.objects, nil, ns)
+		kubeClient := f.(*fakejxfactory.FakeFactory).KubeClient.(*fake.Clientset)
+		kubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+			GitVersion: tc.kubeVersion,
+		}
+		kubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			review.Status.Allowed = false
+			review.Status.Reason = "not a cluster administrator"
+			return true, review, nil
+		})
+
+		out := &bytes.Buffer{}
+		_, o := cluster.NewCmdVerifyCluster()
+		o.JXFactory = f
+		o.Dir = filepath.Join("test_data", tc.name)
+		o.Out = out
+		o.Overlay = tc.overlay
+		if tc.jobValues != "" {
+			o.JobValues = filepath.Join(o.Dir, tc.jobValues)
+		}
+		o.APIExtensionsClient = apiextensionsfake.NewSimpleClientset()
+
+		err := o.Run()
+		var checks []string
+		var failed []string
+		var warnings []string
+		for _, r := range o.Results {
+			checks = append(checks, r.Name)
+			if !r.Passed() {
+				if r.Warning {
+					warnings = append(warnings, r.Name)
+				} else {
+					failed = append(failed, r.Name)
+				}
+				t.Logf("test %s check %s failed: %s", tc.name, r.Name, r.Error.Error())
+			}
+		}
+		assert.Equal(t, tc.expectChecks, checks, "checks for test %s", tc.name)
+		assert.Equal(t, tc.expectFailed, failed, "failed checks for test %s", tc.name)
+		assert.Equal(t, tc.expectWarnings, warnings, "warnings for test %s", tc.name)
+		if len(tc.expectFailed) > 0 {
+			require.Error(t, err, "should have failed for test %s", tc.name)
+			assert.Contains(t, out.String(), "HINT", "should have rendered hints for test %s", tc.name)
+		} else {
+			require.NoError(t, err, "failed to verify cluster for test %s", tc.name)
+		}
+	}
+}
+
+// validObjects returns the resources of a cluster which passes the checks other than the LoadBalancer check
+func validObjects(ns string) []runtime.Object {
+	return []runtime.Object{
+		&storagev1.StorageClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "standard",
+				Annotations: map[string]string{
+					"storageclass.kubernetes.io/is-default-class": "true",
+				},
+			},
+		},
+		createNode("node1", "4", "16Gi", true),
+		createNode("node2", "4", "16Gi", true),
+		createNode("node3", "4", "16Gi", false),
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: bootjob.ClusterRoleBindingName(ns),
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "ClusterRole",
+				Name:     "cluster-admin",
+			},
+			Subjects: []rbacv1.Subject{
+				{
+					Kind:      "ServiceAccount",
+					Name:      bootjob.ServiceAccountName,
+					Namespace: ns,
+				},
+			},
+		},
+	}
+}
+
+func createNode(name string, cpu string, memory string, ready bool) *corev1.Node {
+	status := corev1.ConditionTrue
+	if !ready {
+		status = corev1.ConditionFalse
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+			Conditions: []corev1.NodeCondition{
+				{
+					Type:   corev1.NodeReady,
+					Status: status,
+				},
+			},
+		},
+	}
+}
+
+func createLoadBalancer(ns string, name string, ready bool) *corev1.Service {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+		},
+	}
+	if ready {
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{
+			{IP: "1.2.3.4"},
+		}
+	}
+	return svc
+}
@@ -3,12 +3,21 @@ package git
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/jenkins-x-labs/helmboot/pkg/common"
 	"github.com/jenkins-x-labs/helmboot/pkg/envfactory"
+	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
+	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/jx/pkg/cmd/helper"
 	"github.com/jenkins-x/jx/pkg/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/gits"
 	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/table"
+	"github.com/jenkins-x/jx/pkg/util"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -16,17 +25,43 @@ import (
 var (
 	verifyGitConfigLong = templates.LongDesc(`
 		Verifies the git configuration
+
+		Checks the git token of every git server referenced by the requirements reporting the user, the token scopes
+		and whether the user can create repositories in the environment git owner
 `)
 
 	verifyGitConfigExample = templates.Examples(`
-		# verifies the git config and token
-		%s step verify git config
+		# verifies the git config and tokens of the requirements in the current directory
+		%s verify git config
+
+		# verifies the git config and tokens of the requirements in a directory
+		%s verify git config --dir environment-mycluster-dev
 	`)
 )
 
-// VerifyGitTokenOptions the options for viewing running PRs
+// VerifyGitTokenOptions the options for verifying the git tokens
 type VerifyGitTokenOptions struct {
 	envfactory.EnvFactory
+	Dir     string
+	Overlay string
+	Out     io.Writer
+
+	// ScmClientFactory optionally creates the SCM client and returns the token for a git server
+	ScmClientFactory func(serverURL, owner, kind string) (*scm.Client, string, error)
+
+	// Results the results for each git server and owner
+	Results []*GitServerResult
+}
+
+// GitServerResult the result of verifying the token of a git server
+type GitServerResult struct {
+	Server      string
+	Kind        string
+	Owner       string
+	User        string
+	Scopes      string
+	CreateRepos string
+	Error       error
 }
 
 // NewCmdVerifyGitToken creates a command object for the command
@@ -37,29 +72,158 @@ func NewCmdVerifyGitToken() (*cobra.Command, *VerifyGitTokenOptions) {
 		Use:     "git config",
 		Short:   "Verifies the git configuration",
 		Long:    verifyGitConfigLong,
-		Example: fmt.Sprintf(verifyGitConfigExample, common.BinaryName),
+		Example: fmt.Sprintf(verifyGitConfigExample, common.BinaryName, common.BinaryName),
 		Run: func(cmd *cobra.Command, args []string) {
 			err := o.Run()
 			helper.CheckErr(err)
 		},
 	}
 	o.EnvFactory.AddFlags(cmd)
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory containing the jx-requirements.yml file")
+	cmd.Flags().StringVarP(&o.Overlay, "overlay", "", os.Getenv(reqhelpers.OverlayEnvVar), "the requirements overlay in requirements/clusters used to compose the requirements with requirements/base.yml. Defaults to $"+reqhelpers.OverlayEnvVar)
 
 	return cmd, o
 }
 
 // Run implements the command
 func (o *VerifyGitTokenOptions) Run() error {
-	scmClient, _, err := o.EnvFactory.CreateScmClient("https://github.com", "", "github")
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	requirements, _, err := reqhelpers.LoadRequirements(o.Dir, o.Overlay)
 	if err != nil {
-		return err
+		return errors.Wrapf(err, "failed to load the requirements in dir %s", o.Dir)
 	}
+
 	ctx := context.Background()
+	o.Results = nil
+	for _, r := range GitServers(requirements) {
+		o.verify(ctx, r)
+		o.Results = append(o.Results, r)
+	}
+	o.render()
 
-	user, _, err := scmClient.Users.Find(ctx)
-	if err != nil {
-		return errors.Wrap(err, "failed to lookup the current user name")
+	failed := 0
+	for _, r := range o.Results {
+		if r.Error != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d git servers failed to verify", failed, len(o.Results))
 	}
-	log.Logger().Infof("current git user is %s", user.Login)
 	return nil
 }
+
+// GitServers returns the git servers, kinds and owners referenced by the requirements
+func GitServers(requirements *config.RequirementsConfig) []*GitServerResult {
+	var answer []*GitServerResult
+	keys := map[string]bool{}
+	add := func(server, kind, owner string) {
+		server = strings.TrimSuffix(server, "/")
+		if server == "" {
+			server = gits.GitHubURL
+		}
+		if kind == "" {
+			kind = gits.SaasGitKind(server)
+		}
+		key := server + "|" + kind + "|" + owner
+		if keys[key] {
+			return
+		}
+		keys[key] = true
+		answer = append(answer, &GitServerResult{
+			Server: server,
+			Kind:   kind,
+			Owner:  owner,
+		})
+	}
+
+	c := &requirements.Cluster
+	add(c.GitServer, c.GitKind, c.EnvironmentGitOwner)
+	for _, e := range requirements.Environments {
+		if e.RemoteCluster && e.GitServer == "" {
+			continue
+		}
+		server := e.GitServer
+		kind := e.GitKind
+		if server == "" {
+			server = c.GitServer
+			if kind == "" {
+				kind = c.GitKind
+			}
+		}
+		owner := e.Owner
+		if owner == "" {
+			owner = c.EnvironmentGitOwner
+		}
+		add(server, kind, owner)
+	}
+	return answer
+}
+
+func (o *VerifyGitTokenOptions) verify(ctx context.Context, r *GitServerResult) {
+	scmClient, _, err := o.scmClient(r.Server, r.Owner, r.Kind)
+	if err != nil {
+		r.Error = errors.Wrapf(err, "failed to create the SCM client for server %s", r.Server)
+		return
+	}
+	user, res, err := scmClient.Users.Find(ctx)
+	if err != nil {
+		r.Error = errors.Wrapf(err, "failed to lookup the current user on server %s", r.Server)
+		return
+	}
+	r.User = user.Login
+	if res != nil && res.Header != nil {
+		// only GitHub exposes the scopes of the token
+		r.Scopes = res.Header.Get("X-OAuth-Scopes")
+	}
+	r.CreateRepos = canCreateRepositories(ctx, scmClient, r.Owner, user.Login)
+	log.Logger().Debugf("git user on server %s is %s", r.Server, user.Login)
+}
+
+// canCreateRepositories describes whether the user can create repositories in the owner
+func canCreateRepositories(ctx context.Context, scmClient *scm.Client, owner string, login string) string {
+	if owner == "" || owner == login {
+		return "yes (user account)"
+	}
+	if scmClient.Organizations == nil {
+		return "unknown"
+	}
+	admin, _, err := scmClient.Organizations.IsAdmin(ctx, owner, login)
+	if err == nil && admin {
+		return "yes (organisation admin)"
+	}
+	member, _, err := scmClient.Organizations.IsMember(ctx, owner, login)
+	if err != nil {
+		if errors.Cause(err) == scm.ErrNotSupported {
+			return "unknown"
+		}
+		return fmt.Sprintf("unknown: %s", err.Error())
+	}
+	if member {
+		// members can only create repositories if the organisation allows it which we cannot check in a portable way
+		return "unknown (organisation member)"
+	}
+	return "no (not a member of the organisation)"
+}
+
+func (o *VerifyGitTokenOptions) render() {
+	t := table.CreateTable(o.Out)
+	t.AddRow("SERVER", "KIND", "OWNER", "USER", "SCOPES", "CREATE REPOS", "STATUS")
+	for _, r := range o.Results {
+		status := util.ColorInfo("OK")
+		if r.Error != nil {
+			status = util.ColorError(r.Error.Error())
+		}
+		t.AddRow(r.Server, r.Kind, r.Owner, r.User, r.Scopes, r.CreateRepos, status)
+	}
+	t.Render()
+}
+
+func (o *VerifyGitTokenOptions) scmClient(server, owner, kind string) (*scm.Client, string, error) {
+	if o.ScmClientFactory != nil {
+		return o.ScmClientFactory(server, owner, kind)
+	}
+	return o.EnvFactory.CreateScmClient(server, owner, kind)
+}
@@ -0,0 +1,101 @@
+package git_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/verify/git"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserService a fake SCM user service which returns the current user and token scopes
+type fakeUserService struct {
+	scm.UserService
+	login  string
+	scopes string
+}
+
+func (s *fakeUserService) Find(ctx context.Context) (*scm.User, *scm.Response, error) {
+	if s.login == "" {
+		return nil, nil, errors.New("401 unauthorized")
+	}
+	res := &scm.Response{Header: http.Header{}}
+	if s.scopes != "" {
+		res.Header.Set("X-OAuth-Scopes", s.scopes)
+	}
+	return &scm.User{Login: s.login}, res, nil
+}
+
+// fakeOrganizationService a fake SCM organization service with the members of each organisation
+type fakeOrganizationService struct {
+	scm.OrganizationService
+	members map[string][]string
+}
+
+func (s *fakeOrganizationService) IsAdmin(ctx context.Context, org string, user string) (bool, *scm.Response, error) {
+	return false, nil, nil
+}
+
+func (s *fakeOrganizationService) IsMember(ctx context.Context, org string, user string) (bool, *scm.Response, error) {
+	for _, m := range s.members[org] {
+		if m == user {
+			return true, nil, nil
+		}
+	}
+	return false, nil, nil
+}
+
+func TestVerifyGitConfig(t *testing.T) {
+	clients := map[string]*scm.Client{
+		"https://github.com": {
+			Users:         &fakeUserService{login: "myuser", scopes: "repo, read:org"},
+			Organizations: &fakeOrganizationService{members: map[string][]string{"myorg": {"myuser"}}},
+		},
+		"https://gitlab.mycompany.com": {
+			Users:         &fakeUserService{login: "produser"},
+			Organizations: &fakeOrganizationService{},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	_, o := git.NewCmdVerifyGitToken()
+	o.Dir = filepath.Join("test_data", "multiple")
+	o.Out = out
+	o.ScmClientFactory = func(serverURL, owner, kind string) (*scm.Client, string, error) {
+		return clients[serverURL], "mytoken", nil
+	}
+
+	err := o.Run()
+	require.NoError(t, err, "failed to verify git config")
+	require.Len(t, o.Results, 2, "results")
+
+	r := o.Results[0]
+	assert.Equal(t, "https://github.com", r.Server, "server")
+	assert.Equal(t, "github", r.Kind, "kind")
+	assert.Equal(t, "myorg", r.Owner, "owner")
+	assert.Equal(t, "myuser", r.User, "user")
+	assert.Equal(t, "repo, read:org", r.Scopes, "scopes")
+	assert.Equal(t, "unknown (organisation member)", r.CreateRepos, "create repos")
+
+	r = o.Results[1]
+	assert.Equal(t, "https://gitlab.mycompany.com", r.Server, "server")
+	assert.Equal(t, "gitlab", r.Kind, "kind")
+	assert.Equal(t, "myprodgroup", r.Owner, "owner")
+	assert.Equal(t, "produser", r.User, "user")
+	assert.Equal(t, "no (not a member of the organisation)", r.CreateRepos, "create repos")
+
+	t.Logf("%s\n", out.String())
+
+	// now lets fail the token of one git server
+	clients["https://gitlab.mycompany.com"].Users = &fakeUserService{}
+	err = o.Run()
+	require.Error(t, err, "should have failed to verify git config")
+	assert.Error(t, o.Results[1].Error, "should have failed the gitlab server")
+	assert.NoError(t, o.Results[0].Error, "should not have failed the github server")
+}
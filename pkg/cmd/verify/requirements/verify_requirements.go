@@ -3,6 +3,7 @@ package requirements
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/jenkins-x-labs/helmboot/pkg/common"
 	"github.com/jenkins-x-labs/helmboot/pkg/envfactory"
@@ -29,6 +30,12 @@ var (
 	verifyExample = templates.Examples(`
 		# verifies the staging repository is setup correctly
 		%s verify requirements --git-url=https://github.com/myorg/environment-mycluster-staging.git
+
+		# verifies the repository explaining which apps were added or removed based on the requirements
+		%s verify requirements --git-url=https://github.com/myorg/environment-mycluster-staging.git --explain
+
+		# verifies the repository using the requirements composed from the requirements/clusters/prod-eu.yml overlay
+		%s verify requirements --git-url=https://github.com/myorg/environment-mycluster-dev.git --overlay prod-eu
 	`)
 )
 
@@ -42,6 +49,8 @@ type VerifyOptions struct {
 	Args                  []string
 	GitCloneURL           string
 	Dir                   string
+	Overlay               string
+	Explain               bool
 }
 
 // NewCmdRequirements creates a command object for the command
@@ -53,7 +62,7 @@ func NewCmdRequirements() (*cobra.Command, *VerifyOptions) {
 		Short:   "Verifies the given environment git repository requirements are setup correctly",
 		Aliases: []string{"req", "requirement"},
 		Long:    verifyLong,
-		Example: fmt.Sprintf(verifyExample, common.BinaryName),
+		Example: fmt.Sprintf(verifyExample, common.BinaryName, common.BinaryName, common.BinaryName),
 		Run: func(cmd *cobra.Command, args []string) {
 			o.Cmd = cmd
 			o.Args = args
@@ -70,6 +79,8 @@ func (o *VerifyOptions) AddVerifyOptions(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&o.BatchMode, "batch-mode", "b", false, "Enables batch mode which avoids prompting for user input")
 	cmd.Flags().StringVarP(&o.Dir, "dir", "", "", "The directory used to clone the git repository. If no directory is specified a temporary directory will be used")
 	cmd.Flags().StringVarP(&o.GitCloneURL, "git-url", "", "", "The git repository to clone to upgrade")
+	cmd.Flags().BoolVarP(&o.Explain, "explain", "", false, "Explains which rules added or removed apps in the jx-apps.yml file")
+	cmd.Flags().StringVarP(&o.Overlay, "overlay", "", os.Getenv(reqhelpers.OverlayEnvVar), "the requirements overlay in requirements/clusters used to compose the requirements with requirements/base.yml. Defaults to $"+reqhelpers.OverlayEnvVar)
 
 	reqhelpers.AddRequirementsOptions(cmd, &o.OverrideRequirements)
 	reqhelpers.AddRequirementsFlagsOptions(cmd, &o.Flags)
@@ -86,11 +97,19 @@ func (o *VerifyOptions) Run() error {
 		return err
 	}
 
-	err = reqhelpers.OverrideRequirements(o.Cmd, o.Args, dir, &o.OverrideRequirements, &o.Flags)
+	err = reqhelpers.OverrideRequirements(o.Cmd, o.Args, dir, o.Overlay, &o.OverrideRequirements, &o.Flags)
 	if err != nil {
 		return errors.Wrapf(err, "failed to override requirements in dir %s", dir)
 	}
 
+	_, _, appChanges, err := reqhelpers.ValidateAppsWithRules(dir, &o.OverrideRequirements, "")
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate the apps based on requirements in dir %s", dir)
+	}
+	if o.Explain {
+		reqhelpers.ExplainAppChanges(appChanges)
+	}
+
 	err = o.EnvFactory.VerifyPreInstall(o.DisableVerifyPackages, dir)
 	if err != nil {
 		return errors.Wrapf(err, "failed to verify requirements in dir %s", dir)
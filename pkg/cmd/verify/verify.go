@@ -1,6 +1,7 @@
 package verify
 
 import (
+	"github.com/jenkins-x-labs/helmboot/pkg/cmd/verify/cluster"
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/verify/git"
 	"github.com/jenkins-x-labs/helmboot/pkg/cmd/verify/requirements"
 	"github.com/jenkins-x-labs/helmboot/pkg/common"
@@ -22,5 +23,6 @@ func NewCmdVerify() *cobra.Command {
 	}
 	command.AddCommand(common.SplitCommand(git.NewCmdVerifyGitToken()))
 	command.AddCommand(common.SplitCommand(requirements.NewCmdRequirements()))
+	command.AddCommand(common.SplitCommand(cluster.NewCmdVerifyCluster()))
 	return command
 }
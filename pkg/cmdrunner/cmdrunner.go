@@ -0,0 +1,17 @@
+package cmdrunner
+
+import (
+	"strings"
+
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+)
+
+// CommandRunner runs a command returning its output so that commands can be faked in tests
+type CommandRunner func(*util.Command) (string, error)
+
+// DefaultCommandRunner runs the command using the binaries on the $PATH
+func DefaultCommandRunner(c *util.Command) (string, error) {
+	log.Logger().Debugf("running command %s %s in dir %s", c.Name, strings.Join(c.Args, " "), c.Dir)
+	return c.RunWithoutRetry()
+}
@@ -15,4 +15,7 @@ const (
 
 	// PipelineActivitiesYAMLFile the name of the YAML file to help migrate PipelineActivity resources to a new cluster
 	PipelineActivitiesYAMLFile = "pipelineActivities.yaml"
+
+	// WorkInProgressLabel the label used to mark a Pull Request as a work in progress
+	WorkInProgressLabel = "do-not-merge/work-in-progress"
 )
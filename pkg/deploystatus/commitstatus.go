@@ -0,0 +1,153 @@
+package deploystatus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/envfactory"
+	"github.com/jenkins-x/go-scm/scm"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// commitStates maps the deployment states to commit status states
+var commitStates = map[string]scm.State{
+	StatePending:    scm.StatePending,
+	StateInProgress: scm.StateRunning,
+	StateSuccess:    scm.StateSuccess,
+	StateFailure:    scm.StateFailure,
+}
+
+// IsNotSupported returns true if the git provider does not support the API
+func IsNotSupported(err error) bool {
+	return err != nil && errors.Cause(err) == scm.ErrNotSupported
+}
+
+// CommitStatusLabel returns the label of the commit status for the given environment
+func CommitStatusLabel(environment string) string {
+	return "deployment/" + strings.ToLower(environment)
+}
+
+// SyncCommitStatus posts the deployment state as a commit status on the release commit for git providers
+// without a Deployments API. If commit statuses are not supported either a comment is added to the last
+// pull request of the release once the deployment succeeds or fails. Returns true if a status or comment was posted
+func SyncCommitStatus(ctx context.Context, scmClient *scm.Client, r *Request, release *v1.Release) (bool, error) {
+	state, ok := commitStates[r.Status.State]
+	if !ok {
+		return false, nil
+	}
+	fullName := r.FullName
+	ref, err := FindReleaseRef(ctx, scmClient, fullName, release)
+	if err != nil {
+		return false, err
+	}
+	if ref == "" {
+		log.Logger().Warnf("cannot update the commit status of %s version %s as the release commit could not be found", fullName, r.Version)
+		return false, nil
+	}
+
+	label := CommitStatusLabel(r.Environment)
+	statuses, _, err := scmClient.Repositories.ListStatus(ctx, fullName, ref, scm.ListOptions{})
+	if IsNotSupported(err) {
+		return syncComment(ctx, scmClient, r, release)
+	}
+	if err != nil && !envfactory.IsScmNotFound(err) {
+		return false, errors.Wrapf(err, "failed to list the commit statuses of %s ref %s", fullName, ref)
+	}
+	for _, s := range statuses {
+		if s.Label == label {
+			if s.State == state {
+				log.Logger().Debugf("the latest commit status %s of %s version %s is already %s", label, fullName, r.Version, r.Status.State)
+				return false, nil
+			}
+			break
+		}
+	}
+
+	target := r.Status.TargetLink
+	if target == "" {
+		target = r.Status.LogLink
+	}
+	_, _, err = scmClient.Repositories.CreateStatus(ctx, fullName, ref, &scm.StatusInput{
+		State:  state,
+		Label:  label,
+		Desc:   r.Status.Description,
+		Target: target,
+	})
+	if IsNotSupported(err) {
+		return syncComment(ctx, scmClient, r, release)
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to create commit status %s for %s ref %s", label, fullName, ref)
+	}
+	log.Logger().Infof("created commit status %s for %s version %s with state %s", label, fullName, r.Version, r.Status.State)
+	return true, nil
+}
+
+// FindReleaseRef returns the commit SHA of the release tag or the latest commit of the release
+func FindReleaseRef(ctx context.Context, scmClient *scm.Client, fullName string, release *v1.Release) (string, error) {
+	version := strings.TrimPrefix(release.Spec.Version, "v")
+	if version != "" && scmClient.Git != nil {
+		for _, name := range []string{"v" + version, version} {
+			tag, _, err := scmClient.Git.FindTag(ctx, fullName, name)
+			if err == nil && tag != nil && tag.Sha != "" {
+				return tag.Sha, nil
+			}
+			if err != nil && !envfactory.IsScmNotFound(err) && !IsNotSupported(err) {
+				return "", errors.Wrapf(err, "failed to find tag %s of %s", name, fullName)
+			}
+		}
+	}
+	for _, c := range release.Spec.Commits {
+		if c.SHA != "" {
+			return c.SHA, nil
+		}
+	}
+	return "", nil
+}
+
+// syncComment comments on the last pull request of the release when the deployment succeeds or fails
+func syncComment(ctx context.Context, scmClient *scm.Client, r *Request, release *v1.Release) (bool, error) {
+	if r.Status.State != StateSuccess && r.Status.State != StateFailure {
+		return false, nil
+	}
+	fullName := r.FullName
+	prs := release.Spec.PullRequests
+	if len(prs) == 0 {
+		log.Logger().Warnf("cannot comment on the deployment of %s version %s as the release has no pull requests", fullName, r.Version)
+		return false, nil
+	}
+	number, err := strconv.Atoi(prs[len(prs)-1].ID)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to parse pull request number %s of %s", prs[len(prs)-1].ID, fullName)
+	}
+	title := fmt.Sprintf("Deployment of version %s to **%s**: %s", strings.TrimPrefix(r.Version, "v"), r.Environment, r.Status.State)
+	comments, _, err := scmClient.PullRequests.ListComments(ctx, fullName, number, scm.ListOptions{})
+	if err != nil && !envfactory.IsScmNotFound(err) {
+		return false, errors.Wrapf(err, "failed to list the comments of pull request %d of %s", number, fullName)
+	}
+	for _, c := range comments {
+		if strings.HasPrefix(c.Body, title) {
+			log.Logger().Debugf("pull request %d of %s already has the comment: %s", number, fullName, title)
+			return false, nil
+		}
+	}
+	body := title
+	if r.Status.Description != "" {
+		body += "\n\n" + r.Status.Description
+	}
+	for _, link := range []string{r.Status.TargetLink, r.Status.LogLink} {
+		if link != "" {
+			body += "\n\n" + link
+		}
+	}
+	_, _, err = scmClient.PullRequests.CreateComment(ctx, fullName, number, &scm.CommentInput{Body: body})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to comment on pull request %d of %s", number, fullName)
+	}
+	log.Logger().Infof("commented on pull request %d of %s with the deployment state %s", number, fullName, r.Status.State)
+	return true, nil
+}
@@ -0,0 +1,178 @@
+package deploystatus_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/deploystatus"
+	"github.com/jenkins-x/go-scm/scm"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeStatusRepositoryService a fake SCM repository service which stores commit statuses in memory
+type fakeStatusRepositoryService struct {
+	scm.RepositoryService
+	notSupported bool
+	statuses     map[string][]*scm.Status
+}
+
+func (s *fakeStatusRepositoryService) ListStatus(ctx context.Context, repo string, ref string, opts scm.ListOptions) ([]*scm.Status, *scm.Response, error) {
+	if s.notSupported {
+		return nil, nil, scm.ErrNotSupported
+	}
+	return s.statuses[ref], nil, nil
+}
+
+func (s *fakeStatusRepositoryService) CreateStatus(ctx context.Context, repo string, ref string, input *scm.StatusInput) (*scm.Status, *scm.Response, error) {
+	status := &scm.Status{
+		State:  input.State,
+		Label:  input.Label,
+		Desc:   input.Desc,
+		Target: input.Target,
+	}
+	// the most recent status is returned first
+	s.statuses[ref] = append([]*scm.Status{status}, s.statuses[ref]...)
+	return status, nil, nil
+}
+
+// fakeGitService a fake SCM git service which returns the tags
+type fakeGitService struct {
+	scm.GitService
+	tags map[string]string
+}
+
+func (s *fakeGitService) FindTag(ctx context.Context, repo string, name string) (*scm.Reference, *scm.Response, error) {
+	sha := s.tags[name]
+	if sha == "" {
+		return nil, nil, scm.ErrNotFound
+	}
+	return &scm.Reference{Name: name, Sha: sha}, nil, nil
+}
+
+// fakePullRequestService a fake SCM pull request service which stores comments in memory
+type fakePullRequestService struct {
+	scm.PullRequestService
+	comments map[int][]*scm.Comment
+}
+
+func (s *fakePullRequestService) ListComments(ctx context.Context, repo string, number int, opts scm.ListOptions) ([]*scm.Comment, *scm.Response, error) {
+	return s.comments[number], nil, nil
+}
+
+func (s *fakePullRequestService) CreateComment(ctx context.Context, repo string, number int, input *scm.CommentInput) (*scm.Comment, *scm.Response, error) {
+	c := &scm.Comment{Body: input.Body}
+	s.comments[number] = append(s.comments[number], c)
+	return c, nil, nil
+}
+
+func TestSyncCommitStatus(t *testing.T) {
+	ctx := context.Background()
+	rs := &fakeStatusRepositoryService{
+		statuses: map[string][]*scm.Status{},
+	}
+	scmClient := &scm.Client{
+		Repositories: rs,
+		Git: &fakeGitService{
+			tags: map[string]string{"v1.2.3": "abc123"},
+		},
+	}
+	release := createRelease("1.2.3")
+
+	request := createRequest(deploystatus.StateInProgress)
+	posted, err := deploystatus.SyncCommitStatus(ctx, scmClient, request, release)
+	require.NoError(t, err, "failed to sync commit status")
+	assert.True(t, posted, "should have posted the in progress status")
+
+	posted, err = deploystatus.SyncCommitStatus(ctx, scmClient, request, release)
+	require.NoError(t, err, "failed to sync commit status")
+	assert.False(t, posted, "should not post the same state again")
+
+	request = createRequest(deploystatus.StateSuccess)
+	posted, err = deploystatus.SyncCommitStatus(ctx, scmClient, request, release)
+	require.NoError(t, err, "failed to sync commit status")
+	assert.True(t, posted, "should have posted the success status")
+
+	statuses := rs.statuses["abc123"]
+	require.Len(t, statuses, 2, "statuses of the release tag")
+	assert.Equal(t, scm.StateSuccess, statuses[0].State, "latest state")
+	assert.Equal(t, "deployment/staging", statuses[0].Label, "latest label")
+	assert.Equal(t, "http://myapp.jx-staging.myorg.com", statuses[0].Target, "latest target")
+	assert.Equal(t, scm.StateRunning, statuses[1].State, "previous state")
+
+	// without a tag the latest commit of the release is used
+	release.Spec.Version = "1.2.4"
+	posted, err = deploystatus.SyncCommitStatus(ctx, scmClient, request, release)
+	require.NoError(t, err, "failed to sync commit status")
+	assert.True(t, posted, "should have posted the status on the release commit")
+	assert.Len(t, rs.statuses["def456"], 1, "statuses of the release commit")
+}
+
+func TestSyncCommitStatusFallsBackToComment(t *testing.T) {
+	ctx := context.Background()
+	prs := &fakePullRequestService{
+		comments: map[int][]*scm.Comment{},
+	}
+	scmClient := &scm.Client{
+		Repositories: &fakeStatusRepositoryService{
+			notSupported: true,
+		},
+		Git: &fakeGitService{
+			tags: map[string]string{"v1.2.3": "abc123"},
+		},
+		PullRequests: prs,
+	}
+	release := createRelease("1.2.3")
+
+	posted, err := deploystatus.SyncCommitStatus(ctx, scmClient, createRequest(deploystatus.StateInProgress), release)
+	require.NoError(t, err, "failed to sync commit status")
+	assert.False(t, posted, "should only comment on success or failure")
+
+	for i := 0; i < 2; i++ {
+		posted, err = deploystatus.SyncCommitStatus(ctx, scmClient, createRequest(deploystatus.StateSuccess), release)
+		require.NoError(t, err, "failed to sync commit status")
+		assert.Equal(t, i == 0, posted, "should only comment once for attempt %d", i)
+	}
+
+	comments := prs.comments[7]
+	require.Len(t, comments, 1, "comments on the pull request")
+	assert.Contains(t, comments[0].Body, "Deployment of version 1.2.3 to **staging**: success", "comment")
+	assert.Contains(t, comments[0].Body, "http://myapp.jx-staging.myorg.com", "comment")
+}
+
+func createRelease(version string) *v1.Release {
+	return &v1.Release{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myapp-" + version,
+			Namespace: "jx-staging",
+		},
+		Spec: v1.ReleaseSpec{
+			GitOwner:      "myorg",
+			GitRepository: "myapp",
+			Version:       version,
+			Commits: []v1.CommitSummary{
+				{SHA: "def456"},
+			},
+			PullRequests: []v1.IssueSummary{
+				{ID: "5"},
+				{ID: "7"},
+			},
+		},
+	}
+}
+
+func createRequest(state string) *deploystatus.Request {
+	return &deploystatus.Request{
+		FullName:    "myorg/myapp",
+		AppName:     "myapp",
+		Version:     "1.2.3",
+		Environment: "staging",
+		Status: scm.DeploymentStatusInput{
+			State:       state,
+			TargetLink:  "http://myapp.jx-staging.myorg.com",
+			Description: "Deployment 1.2.3: " + state,
+		},
+	}
+}
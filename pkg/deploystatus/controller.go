@@ -0,0 +1,198 @@
+package deploystatus
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/client/clientset/versioned"
+	jxinformers "github.com/jenkins-x/jx/pkg/client/informers/externalversions"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// DefaultResyncPeriod the default period to re-process all Releases
+	DefaultResyncPeriod = 5 * time.Minute
+
+	// maxRetries the number of times a Release is retried before it is dropped until the next change or resync
+	maxRetries = 5
+)
+
+// SyncFunc updates the deployment status of a Release returning true if a status was posted
+type SyncFunc func(release *v1.Release) (bool, error)
+
+// Controller watches Release resources and Deployments updating the git deployment status of each Release
+type Controller struct {
+	kubeClient   kubernetes.Interface
+	jxClient     versioned.Interface
+	namespace    string
+	resyncPeriod time.Duration
+	sync         SyncFunc
+	metrics      *Metrics
+	queue        workqueue.RateLimitingInterface
+	releases     cache.Indexer
+}
+
+// NewController creates a controller for the Releases in the namespace or all namespaces if it is empty
+func NewController(kubeClient kubernetes.Interface, jxClient versioned.Interface, ns string, resyncPeriod time.Duration, sync SyncFunc, metrics *Metrics) *Controller {
+	if resyncPeriod <= 0 {
+		resyncPeriod = DefaultResyncPeriod
+	}
+	if metrics == nil {
+		metrics = NewMetrics()
+	}
+	return &Controller{
+		kubeClient:   kubeClient,
+		jxClient:     jxClient,
+		namespace:    ns,
+		resyncPeriod: resyncPeriod,
+		sync:         sync,
+		metrics:      metrics,
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "releases"),
+	}
+}
+
+// Run starts the informers and processes Releases until the context is cancelled
+func (c *Controller) Run(ctx context.Context, workers int) error {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	jxFactory := jxinformers.NewSharedInformerFactoryWithOptions(c.jxClient, c.resyncPeriod, jxinformers.WithNamespace(c.namespace))
+	releaseInformer := jxFactory.Jenkins().V1().Releases().Informer()
+	c.releases = releaseInformer.GetIndexer()
+	releaseInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		UpdateFunc: func(old, new interface{}) {
+			c.enqueue(new)
+		},
+	})
+
+	// lets watch the Deployments in the same namespaces as the Releases
+	kubeFactory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, c.resyncPeriod, informers.WithNamespace(c.namespace))
+	deploymentInformer := kubeFactory.Apps().V1().Deployments().Informer()
+	deploymentInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueDeploymentReleases,
+		UpdateFunc: func(old, new interface{}) {
+			c.enqueueDeploymentReleases(new)
+		},
+	})
+
+	stop := ctx.Done()
+	jxFactory.Start(stop)
+	kubeFactory.Start(stop)
+	if !cache.WaitForCacheSync(stop, releaseInformer.HasSynced, deploymentInformer.HasSynced) {
+		return errors.Errorf("failed to sync the informer caches")
+	}
+
+	log.Logger().Infof("watching Releases to update the git deployment statuses")
+	for i := 0; i < workers; i++ {
+		go wait.Until(c.runWorker, time.Second, stop)
+	}
+	<-stop
+	return nil
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// enqueueDeploymentReleases enqueues the Releases of the app of the Deployment
+func (c *Controller) enqueueDeploymentReleases(obj interface{}) {
+	d, ok := obj.(*appsv1.Deployment)
+	if !ok || c.releases == nil {
+		return
+	}
+	for _, item := range c.releases.List() {
+		r, ok := item.(*v1.Release)
+		if ok && isReleaseDeployment(r, d) {
+			c.enqueue(r)
+		}
+	}
+}
+
+func (c *Controller) runWorker() {
+	for c.processNextItem() {
+	}
+}
+
+func (c *Controller) processNextItem() bool {
+	item, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(item)
+
+	key := item.(string)
+	err := c.syncRelease(key)
+	if err == nil {
+		c.queue.Forget(item)
+		return true
+	}
+	c.metrics.Failures.Inc()
+	if c.queue.NumRequeues(item) < maxRetries {
+		log.Logger().Warnf("failed to update the deployment status of Release %s, retrying: %s", key, err.Error())
+		c.queue.AddRateLimited(item)
+		return true
+	}
+	log.Logger().Errorf("failed to update the deployment status of Release %s: %s", key, err.Error())
+	c.queue.Forget(item)
+	return true
+}
+
+func (c *Controller) syncRelease(key string) error {
+	obj, exists, err := c.releases.GetByKey(key)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find Release %s", key)
+	}
+	if !exists {
+		return nil
+	}
+	r, ok := obj.(*v1.Release)
+	if !ok {
+		return nil
+	}
+	c.metrics.Syncs.Inc()
+	posted, err := c.sync(r.DeepCopy())
+	if err != nil {
+		return err
+	}
+	if posted {
+		c.metrics.Updates.Inc()
+	}
+	return nil
+}
+
+// isReleaseDeployment returns true if the Deployment runs the app of the Release
+func isReleaseDeployment(r *v1.Release, d *appsv1.Deployment) bool {
+	ns := r.Namespace
+	if ns != "" && ns != d.Namespace {
+		return false
+	}
+	appName := r.Spec.GitRepository
+	if appName == "" {
+		return false
+	}
+	if d.Name == appName || strings.TrimPrefix(d.Name, "jx-") == appName {
+		return true
+	}
+	for _, l := range appLabels {
+		if d.Labels[l] == appName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package deploystatus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/deploystatus"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	jxfake "github.com/jenkins-x/jx/pkg/client/clientset/versioned/fake"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestController(t *testing.T) {
+	ns := "jx-staging"
+	release := &v1.Release{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myapp-1.0.0",
+			Namespace: ns,
+		},
+		Spec: v1.ReleaseSpec{
+			GitRepository: "myapp",
+			Version:       "1.0.0",
+		},
+	}
+	broken := &v1.Release{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "broken-1.0.0",
+			Namespace: ns,
+		},
+		Spec: v1.ReleaseSpec{
+			GitRepository: "broken",
+			Version:       "1.0.0",
+		},
+	}
+	kubeClient := fake.NewSimpleClientset()
+	jxClient := jxfake.NewSimpleClientset(release, broken)
+
+	lock := sync.Mutex{}
+	synced := map[string]int{}
+	syncFn := func(r *v1.Release) (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		synced[r.Name]++
+		if r.Spec.GitRepository == "broken" {
+			return false, errors.Errorf("failed to update")
+		}
+		return true, nil
+	}
+	syncCount := func(name string) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return synced[name]
+	}
+
+	metrics := deploystatus.NewMetrics()
+	controller := deploystatus.NewController(kubeClient, jxClient, ns, time.Hour, syncFn, metrics)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		err := controller.Run(ctx, 1)
+		assert.NoError(t, err, "failed to run controller")
+	}()
+
+	err := wait.Poll(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return syncCount(release.Name) > 0 && syncCount(broken.Name) > 1, nil
+	})
+	require.NoError(t, err, "should have synced the releases")
+
+	// a change to the Deployment of the app should trigger a new sync
+	_, err = kubeClient.AppsV1().Deployments(ns).Create(createDeployment(ns, "jx-myapp", 1, 1, 1, 1))
+	require.NoError(t, err, "failed to create Deployment")
+	err = wait.Poll(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return syncCount(release.Name) > 1, nil
+	})
+	require.NoError(t, err, "should have synced the release after the Deployment changed")
+
+	assert.True(t, testutil.ToFloat64(metrics.Updates) >= 2, "updates metric")
+	assert.True(t, testutil.ToFloat64(metrics.Failures) >= 2, "failures metric")
+	assert.True(t, testutil.ToFloat64(metrics.Syncs) >= 4, "syncs metric")
+}
+
+func TestControllerAllNamespaces(t *testing.T) {
+	ns := "jx-staging"
+	release := &v1.Release{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myapp-1.0.0",
+			Namespace: ns,
+		},
+		Spec: v1.ReleaseSpec{
+			GitRepository: "myapp",
+			Version:       "1.0.0",
+		},
+	}
+	kubeClient := fake.NewSimpleClientset()
+	jxClient := jxfake.NewSimpleClientset(release)
+
+	lock := sync.Mutex{}
+	synced := map[string]int{}
+	syncFn := func(r *v1.Release) (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		synced[r.Namespace+"/"+r.Name]++
+		return true, nil
+	}
+	syncCount := func(key string) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return synced[key]
+	}
+	key := ns + "/" + release.Name
+
+	// the controller runs in the development namespace but watches the Releases in all namespaces
+	controller := deploystatus.NewController(kubeClient, jxClient, metav1.NamespaceAll, time.Hour, syncFn, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		err := controller.Run(ctx, 1)
+		assert.NoError(t, err, "failed to run controller")
+	}()
+
+	err := wait.Poll(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return syncCount(key) > 0, nil
+	})
+	require.NoError(t, err, "should have synced the Release in namespace %s", ns)
+
+	_, err = kubeClient.AppsV1().Deployments(ns).Create(createDeployment(ns, "jx-myapp", 1, 1, 1, 1))
+	require.NoError(t, err, "failed to create Deployment")
+	err = wait.Poll(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return syncCount(key) > 1, nil
+	})
+	require.NoError(t, err, "should have synced the Release after the Deployment in namespace %s changed", ns)
+}
@@ -0,0 +1,185 @@
+package deploystatus
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/cloud"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// LinksConfigMapName the optional ConfigMap in the development namespace containing the links configuration
+	LinksConfigMapName = "jx-deployment-links"
+
+	// LinksLogURLKey the ConfigMap key of the log URL template
+	LinksLogURLKey = "logURL"
+
+	// LinksEnvironmentURLKey the ConfigMap key of the environment URL template
+	LinksEnvironmentURLKey = "environmentURL"
+
+	// LinksAzureResourceGroupKey the ConfigMap key of the resource group of an AKS cluster
+	LinksAzureResourceGroupKey = "azureResourceGroup"
+)
+
+// LinkContext the information about a deployed app used to create the links of a deployment status
+type LinkContext struct {
+	Provider    string
+	ProjectID   string
+	ClusterName string
+	Region      string
+	Namespace   string
+	AppName     string
+	Version     string
+	Environment string
+}
+
+// LinksConfig the optional configuration of the deployment status links
+type LinksConfig struct {
+	// LogURL a go template to generate the log URL which overrides the cluster provider
+	LogURL string `json:"logURL,omitempty"`
+
+	// EnvironmentURL a go template to generate the environment link such as a dashboard URL
+	EnvironmentURL string `json:"environmentURL,omitempty"`
+
+	// AzureResourceGroup the resource group of an AKS cluster used to link to Log Analytics
+	AzureResourceGroup string `json:"azureResourceGroup,omitempty"`
+}
+
+// LogURLResolver returns the URL to view the logs of an app or an empty string if there is none
+type LogURLResolver func(config *LinksConfig, c *LinkContext) (string, error)
+
+// LogURLResolvers the log URL resolvers indexed by cluster provider
+var LogURLResolvers = map[string]LogURLResolver{
+	cloud.GKE: GKELogURL,
+	cloud.EKS: EKSLogURL,
+	cloud.AKS: AKSLogURL,
+}
+
+// LinkResolver creates the log and environment links of deployment statuses
+type LinkResolver struct {
+	Config    LinksConfig
+	Resolvers map[string]LogURLResolver
+}
+
+// NewLinkResolver creates a link resolver from the optional links ConfigMap in the development namespace. Any values
+// specified in the overrides, such as from command line arguments, replace the values in the ConfigMap
+func NewLinkResolver(kubeClient kubernetes.Interface, ns string, overrides LinksConfig) (*LinkResolver, error) {
+	answer := &LinkResolver{
+		Resolvers: LogURLResolvers,
+	}
+	cm, err := kubeClient.CoreV1().ConfigMaps(ns).Get(LinksConfigMapName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return answer, errors.Wrapf(err, "failed to get ConfigMap %s in namespace %s", LinksConfigMapName, ns)
+	}
+	if err == nil && cm != nil && cm.Data != nil {
+		answer.Config = LinksConfig{
+			LogURL:             cm.Data[LinksLogURLKey],
+			EnvironmentURL:     cm.Data[LinksEnvironmentURLKey],
+			AzureResourceGroup: cm.Data[LinksAzureResourceGroupKey],
+		}
+	}
+	if overrides.LogURL != "" {
+		answer.Config.LogURL = overrides.LogURL
+	}
+	if overrides.EnvironmentURL != "" {
+		answer.Config.EnvironmentURL = overrides.EnvironmentURL
+	}
+	if overrides.AzureResourceGroup != "" {
+		answer.Config.AzureResourceGroup = overrides.AzureResourceGroup
+	}
+	return answer, nil
+}
+
+// NewLinkContext creates the link context for the app in the given namespace
+func NewLinkContext(requirements *config.RequirementsConfig, ns, appName, version, environment string) *LinkContext {
+	c := &requirements.Cluster
+	return &LinkContext{
+		Provider:    c.Provider,
+		ProjectID:   c.ProjectID,
+		ClusterName: c.ClusterName,
+		Region:      c.Region,
+		Namespace:   ns,
+		AppName:     appName,
+		Version:     strings.TrimPrefix(version, "v"),
+		Environment: environment,
+	}
+}
+
+// LogURL returns the log URL from the configured template or the resolver of the cluster provider
+func (r *LinkResolver) LogURL(c *LinkContext) (string, error) {
+	if r.Config.LogURL != "" {
+		return evaluateTemplate("logURL", r.Config.LogURL, c)
+	}
+	fn := r.Resolvers[c.Provider]
+	if fn == nil {
+		log.Logger().Debugf("no log URL resolver for provider %s", c.Provider)
+		return "", nil
+	}
+	return fn(&r.Config, c)
+}
+
+// EnvironmentURL returns the environment link from the configured template or the git repository of the Environment
+func (r *LinkResolver) EnvironmentURL(c *LinkContext, env *v1.Environment) (string, error) {
+	if r.Config.EnvironmentURL != "" {
+		return evaluateTemplate("environmentURL", r.Config.EnvironmentURL, c)
+	}
+	if env == nil || env.Spec.Source.URL == "" {
+		return "", nil
+	}
+	return strings.TrimSuffix(env.Spec.Source.URL, ".git"), nil
+}
+
+// GKELogURL returns the Cloud Logging URL for the container of the app
+func GKELogURL(_ *LinksConfig, c *LinkContext) (string, error) {
+	if c.ProjectID == "" || c.ClusterName == "" || c.AppName == "" {
+		return "", nil
+	}
+	return `https://console.cloud.google.com/logs/viewer?authuser=1&project=` + c.ProjectID + `&minLogLevel=0&expandAll=false&customFacets=&limitCustomFacetWidth=true&interval=PT1H&resource=k8s_container%2Fcluster_name%2F` + c.ClusterName + `%2Fnamespace_name%2F` + c.Namespace + `%2Fcontainer_name%2F` + c.AppName + `&dateRangeUnbound=both`, nil
+}
+
+// EKSLogURL returns the CloudWatch URL of the Container Insights application logs of the app
+func EKSLogURL(_ *LinksConfig, c *LinkContext) (string, error) {
+	if c.Region == "" || c.ClusterName == "" || c.AppName == "" {
+		return "", nil
+	}
+	group := fmt.Sprintf("/aws/containerinsights/%s/application", c.ClusterName)
+	filter := fmt.Sprintf(`{ $.kubernetes.namespace_name = "%s" && $.kubernetes.container_name = "%s" }`, c.Namespace, c.AppName)
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/cloudwatch/home?region=%s#logEventViewer:group=%s;filter=%s",
+		c.Region, c.Region, url.QueryEscape(group), url.QueryEscape(filter)), nil
+}
+
+// AKSLogURL returns the Log Analytics URL querying the container logs of the app
+func AKSLogURL(config *LinksConfig, c *LinkContext) (string, error) {
+	if c.ProjectID == "" || config.AzureResourceGroup == "" || c.ClusterName == "" || c.AppName == "" {
+		return "", nil
+	}
+	resourceID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s",
+		c.ProjectID, config.AzureResourceGroup, c.ClusterName)
+	query := fmt.Sprintf(`KubePodInventory | where Namespace == "%s" and ContainerName endswith "/%s" | distinct ContainerID | join kind=inner ContainerLog on ContainerID | project TimeGenerated, LogEntry | order by TimeGenerated desc`,
+		c.Namespace, c.AppName)
+	return "https://portal.azure.com/#blade/Microsoft_Azure_Monitoring_Logs/LogsBlade/resourceId/" + url.PathEscape(resourceID) +
+		"/source/LogsBlade.AnalyticsShareLinkToQuery/query/" + url.PathEscape(query), nil
+}
+
+func evaluateTemplate(name string, text string, c *LinkContext) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the %s template %s", name, text)
+	}
+	buf := &bytes.Buffer{}
+	err = t.Execute(buf, c)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to evaluate the %s template %s", name, text)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,137 @@
+package deploystatus_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/deploystatus"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/cloud"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLinkResolverLogURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		provider string
+		links    map[string]string
+		expected string
+	}{
+		{
+			name:     "gke",
+			provider: cloud.GKE,
+			expected: "https://console.cloud.google.com/logs/viewer?authuser=1&project=myproject&minLogLevel=0&expandAll=false&customFacets=&limitCustomFacetWidth=true&interval=PT1H&resource=k8s_container%2Fcluster_name%2Fmycluster%2Fnamespace_name%2Fjx-staging%2Fcontainer_name%2Fmyapp&dateRangeUnbound=both",
+		},
+		{
+			name:     "eks",
+			provider: cloud.EKS,
+			expected: "https://us-east-1.console.aws.amazon.com/cloudwatch/home?region=us-east-1#logEventViewer:group=%2Faws%2Fcontainerinsights%2Fmycluster%2Fapplication;filter=%7B+%24.kubernetes.namespace_name+%3D+%22jx-staging%22+%26%26+%24.kubernetes.container_name+%3D+%22myapp%22+%7D",
+		},
+		{
+			name:     "aks",
+			provider: cloud.AKS,
+			links:    map[string]string{deploystatus.LinksAzureResourceGroupKey: "myrg"},
+			expected: "https://portal.azure.com/#blade/Microsoft_Azure_Monitoring_Logs/LogsBlade/resourceId/%2Fsubscriptions%2Fmyproject%2FresourceGroups%2Fmyrg%2Fproviders%2FMicrosoft.ContainerService%2FmanagedClusters%2Fmycluster/source/LogsBlade.AnalyticsShareLinkToQuery/query/KubePodInventory%20%7C%20where%20Namespace%20==%20%22jx-staging%22%20and%20ContainerName%20endswith%20%22%2Fmyapp%22%20%7C%20distinct%20ContainerID%20%7C%20join%20kind=inner%20ContainerLog%20on%20ContainerID%20%7C%20project%20TimeGenerated%2C%20LogEntry%20%7C%20order%20by%20TimeGenerated%20desc",
+		},
+		{
+			name:     "aks-without-resource-group",
+			provider: cloud.AKS,
+			expected: "",
+		},
+		{
+			name:     "kubernetes",
+			provider: cloud.KUBERNETES,
+			expected: "",
+		},
+		{
+			name:     "template",
+			provider: cloud.GKE,
+			links:    map[string]string{deploystatus.LinksLogURLKey: "https://logs.myorg.com/{{ .ClusterName }}/{{ .Namespace }}/{{ .AppName }}?version={{ .Version }}"},
+			expected: "https://logs.myorg.com/mycluster/jx-staging/myapp?version=1.2.3",
+		},
+	}
+
+	for _, tc := range testCases {
+		requirements := config.NewRequirementsConfig()
+		requirements.Cluster.Provider = tc.provider
+		requirements.Cluster.ProjectID = "myproject"
+		requirements.Cluster.ClusterName = "mycluster"
+		requirements.Cluster.Region = "us-east-1"
+
+		resolver, err := deploystatus.NewLinkResolver(createLinksClient(tc.links), "jx", deploystatus.LinksConfig{})
+		require.NoError(t, err, "failed to create link resolver for %s", tc.name)
+
+		c := deploystatus.NewLinkContext(requirements, "jx-staging", "myapp", "v1.2.3", "Staging")
+		actual, err := resolver.LogURL(c)
+		require.NoError(t, err, "failed to resolve log URL for %s", tc.name)
+		assert.Equal(t, tc.expected, actual, "log URL for %s", tc.name)
+	}
+}
+
+func TestLinkResolverEnvironmentURL(t *testing.T) {
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "staging",
+		},
+		Spec: v1.EnvironmentSpec{
+			Source: v1.EnvironmentRepository{
+				URL: "https://github.com/myorg/environment-mycluster-staging.git",
+			},
+		},
+	}
+	c := deploystatus.NewLinkContext(config.NewRequirementsConfig(), "jx-staging", "myapp", "1.2.3", "Staging")
+
+	resolver, err := deploystatus.NewLinkResolver(createLinksClient(nil), "jx", deploystatus.LinksConfig{})
+	require.NoError(t, err, "failed to create link resolver")
+	actual, err := resolver.EnvironmentURL(c, env)
+	require.NoError(t, err, "failed to resolve environment URL")
+	assert.Equal(t, "https://github.com/myorg/environment-mycluster-staging", actual, "environment URL")
+
+	actual, err = resolver.EnvironmentURL(c, nil)
+	require.NoError(t, err, "failed to resolve environment URL without an Environment")
+	assert.Equal(t, "", actual, "environment URL without an Environment")
+
+	kubeClient := createLinksClient(map[string]string{
+		deploystatus.LinksEnvironmentURLKey: "https://dashboard.myorg.com/environments/{{ .Environment }}",
+	})
+	resolver, err = deploystatus.NewLinkResolver(kubeClient, "jx", deploystatus.LinksConfig{})
+	require.NoError(t, err, "failed to create link resolver")
+	actual, err = resolver.EnvironmentURL(c, env)
+	require.NoError(t, err, "failed to resolve environment URL")
+	assert.Equal(t, "https://dashboard.myorg.com/environments/Staging", actual, "dashboard URL")
+
+	// lets check the command line arguments override the ConfigMap
+	resolver, err = deploystatus.NewLinkResolver(kubeClient, "jx", deploystatus.LinksConfig{
+		EnvironmentURL: "https://dashboard.myorg.com/{{ .Namespace }}",
+	})
+	require.NoError(t, err, "failed to create link resolver")
+	actual, err = resolver.EnvironmentURL(c, env)
+	require.NoError(t, err, "failed to resolve environment URL")
+	assert.Equal(t, "https://dashboard.myorg.com/jx-staging", actual, "overridden dashboard URL")
+
+	resolver, err = deploystatus.NewLinkResolver(createLinksClient(nil), "jx", deploystatus.LinksConfig{
+		EnvironmentURL: "https://dashboard.myorg.com/{{ .Cheese }}",
+	})
+	require.NoError(t, err, "failed to create link resolver")
+	_, err = resolver.EnvironmentURL(c, env)
+	require.Error(t, err, "should fail for an unknown template field")
+}
+
+// createLinksClient creates a kubernetes client with the links ConfigMap if there is any data
+func createLinksClient(data map[string]string) kubernetes.Interface {
+	if data == nil {
+		return fake.NewSimpleClientset()
+	}
+	return fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploystatus.LinksConfigMapName,
+			Namespace: "jx",
+		},
+		Data: data,
+	})
+}
@@ -0,0 +1,49 @@
+package deploystatus
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics the metrics of the deployment status controller
+type Metrics struct {
+	// Syncs the number of times a Release has been processed
+	Syncs prometheus.Counter
+
+	// Updates the number of deployment statuses posted to the git provider
+	Updates prometheus.Counter
+
+	// Failures the number of times processing a Release failed
+	Failures prometheus.Counter
+
+	// Registry the registry containing the metrics
+	Registry *prometheus.Registry
+}
+
+// NewMetrics creates the metrics in a new registry
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		Syncs: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "helmboot_deployment_status_syncs_total",
+			Help: "The number of times a Release has been processed",
+		}),
+		Updates: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "helmboot_deployment_status_updates_total",
+			Help: "The number of deployment statuses posted to the git provider",
+		}),
+		Failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "helmboot_deployment_status_failures_total",
+			Help: "The number of times processing a Release failed",
+		}),
+		Registry: prometheus.NewRegistry(),
+	}
+	m.Registry.MustRegister(m.Syncs, m.Updates, m.Failures)
+	return m
+}
+
+// Handler returns the HTTP handler to expose the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,49 @@
+package deploystatus
+
+import (
+	"sync"
+	"time"
+
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/config"
+)
+
+// RequirementsLoader loads the dev Environment and its requirements
+type RequirementsLoader func() (*v1.Environment, *config.RequirementsConfig, error)
+
+// RequirementsCache caches the dev Environment and its requirements for a period such as the resync period of the
+// controller so that they are not loaded for every Release
+type RequirementsCache struct {
+	period       time.Duration
+	load         RequirementsLoader
+	lock         sync.Mutex
+	devEnv       *v1.Environment
+	requirements *config.RequirementsConfig
+	loaded       time.Time
+}
+
+// NewRequirementsCache creates a cache which reloads the requirements once they are older than the period
+func NewRequirementsCache(period time.Duration, load RequirementsLoader) *RequirementsCache {
+	return &RequirementsCache{
+		period: period,
+		load:   load,
+	}
+}
+
+// Get returns the cached dev Environment and requirements loading them if they are missing or expired
+func (c *RequirementsCache) Get() (*v1.Environment, *config.RequirementsConfig, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.devEnv != nil && time.Since(c.loaded) < c.period {
+		return c.devEnv, c.requirements, nil
+	}
+	devEnv, requirements, err := c.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	c.devEnv = devEnv
+	c.requirements = requirements
+	c.loaded = time.Now()
+	return devEnv, requirements, nil
+}
@@ -0,0 +1,47 @@
+package deploystatus_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/deploystatus"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/kube"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequirementsCache(t *testing.T) {
+	loads := 0
+	var loadErr error
+	loader := func() (*v1.Environment, *config.RequirementsConfig, error) {
+		loads++
+		if loadErr != nil {
+			return nil, nil, loadErr
+		}
+		return kube.CreateDefaultDevEnvironment("jx"), config.NewRequirementsConfig(), nil
+	}
+
+	c := deploystatus.NewRequirementsCache(time.Hour, loader)
+	for i := 0; i < 3; i++ {
+		devEnv, requirements, err := c.Get()
+		require.NoError(t, err, "failed to get the requirements")
+		assert.NotNil(t, devEnv, "dev Environment")
+		assert.NotNil(t, requirements, "requirements")
+	}
+	assert.Equal(t, 1, loads, "should only load the requirements once per period")
+
+	c = deploystatus.NewRequirementsCache(0, loader)
+	loads = 0
+	_, _, err := c.Get()
+	require.NoError(t, err, "failed to get the requirements")
+	_, _, err = c.Get()
+	require.NoError(t, err, "failed to get the requirements")
+	assert.Equal(t, 2, loads, "should reload the requirements once they expire")
+
+	loadErr = errors.Errorf("failed to load")
+	_, _, err = c.Get()
+	require.Error(t, err, "should return the load error")
+}
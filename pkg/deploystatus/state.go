@@ -0,0 +1,222 @@
+package deploystatus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/clienthelpers"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// StatePending the app has not been deployed yet
+	StatePending = "pending"
+
+	// StateInProgress the app is rolling out
+	StateInProgress = "in_progress"
+
+	// StateSuccess the app has rolled out and is ready
+	StateSuccess = "success"
+
+	// StateFailure the app failed to roll out
+	StateFailure = "failure"
+
+	// StateInactive the deployment has been superseded by a newer version
+	StateInactive = "inactive"
+
+	// ImagePullGracePeriod the time a pod can fail to pull its image before the rollout is considered failed
+	ImagePullGracePeriod = clienthelpers.DefaultImagePullGracePeriod
+
+	// revisionAnnotation the annotation on a ReplicaSet containing the revision of its Deployment
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+
+	// podTemplateHashLabel the label on a ReplicaSet and its pods identifying the pod template
+	podTemplateHashLabel = "pod-template-hash"
+)
+
+// appLabels the labels charts use to indicate the app name of a Deployment
+var appLabels = []string{"app", "app.kubernetes.io/name"}
+
+// versionLabels the labels charts use on the pod template to indicate the version of the app
+var versionLabels = []string{"version", "app.kubernetes.io/version"}
+
+// crashLoopWaitingReason the container waiting reason which indicates the rollout has failed as the container keeps crashing
+const crashLoopWaitingReason = "CrashLoopBackOff"
+
+// State returns the deployment state of the given version of the app in the namespace along with a description
+func State(kubeClient kubernetes.Interface, ns string, appName string, version string) (string, string, error) {
+	deployment, err := FindDeployment(kubeClient, ns, appName)
+	if err != nil {
+		return "", "", err
+	}
+	if deployment == nil {
+		return StatePending, fmt.Sprintf("no Deployment found for %s in namespace %s", appName, ns), nil
+	}
+	return DeploymentState(kubeClient, deployment, version)
+}
+
+// FindDeployment finds the kubernetes Deployment for the app in the namespace or returns nil if it cannot be found
+func FindDeployment(kubeClient kubernetes.Interface, ns string, appName string) (*appsv1.Deployment, error) {
+	list, err := kubeClient.AppsV1().Deployments(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list Deployments in namespace %s", ns)
+	}
+	names := []string{appName, "jx-" + appName}
+	for _, name := range names {
+		for i := range list.Items {
+			if list.Items[i].Name == name {
+				return &list.Items[i], nil
+			}
+		}
+	}
+	for i := range list.Items {
+		d := &list.Items[i]
+		for _, l := range appLabels {
+			if d.Labels[l] == appName {
+				return d, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// DeploymentState returns the state of the rollout of the given version of the Deployment along with a description.
+// If the version is specified the state is pending until the pod template of the Deployment has been updated to the version
+func DeploymentState(kubeClient kubernetes.Interface, d *appsv1.Deployment, version string) (string, string, error) {
+	if version != "" {
+		current := DeploymentVersion(d)
+		if current != "" && !sameVersion(current, version) {
+			return StatePending, fmt.Sprintf("Deployment %s has version %s rather than %s", d.Name, current, version), nil
+		}
+	}
+
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return StateFailure, fmt.Sprintf("Deployment %s exceeded its progress deadline", d.Name), nil
+		}
+	}
+
+	reason, err := podFailure(kubeClient, d)
+	if err != nil {
+		return "", "", err
+	}
+	if reason != "" {
+		return StateFailure, reason, nil
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	s := &d.Status
+	switch {
+	case d.Generation > s.ObservedGeneration:
+		return StateInProgress, fmt.Sprintf("waiting for Deployment %s to be observed", d.Name), nil
+	case s.UpdatedReplicas < replicas:
+		return StateInProgress, fmt.Sprintf("%d of %d updated replicas", s.UpdatedReplicas, replicas), nil
+	case s.Replicas > s.UpdatedReplicas:
+		return StateInProgress, fmt.Sprintf("%d old replicas are pending termination", s.Replicas-s.UpdatedReplicas), nil
+	case s.AvailableReplicas < s.UpdatedReplicas:
+		return StateInProgress, fmt.Sprintf("%d of %d updated replicas are available", s.AvailableReplicas, s.UpdatedReplicas), nil
+	}
+	return StateSuccess, fmt.Sprintf("%d of %d replicas are ready", s.ReadyReplicas, replicas), nil
+}
+
+// DeploymentVersion returns the version of the app in the pod template of the Deployment from its version label or
+// otherwise the tag of its first container image. Returns an empty string if the version cannot be found
+func DeploymentVersion(d *appsv1.Deployment) string {
+	template := &d.Spec.Template
+	for _, l := range versionLabels {
+		if v := template.Labels[l]; v != "" {
+			return v
+		}
+	}
+	for _, c := range template.Spec.Containers {
+		image := c.Image
+		if idx := strings.Index(image, "@"); idx >= 0 {
+			image = image[:idx]
+		}
+		idx := strings.LastIndex(image, ":")
+		if idx >= 0 && !strings.Contains(image[idx+1:], "/") {
+			return image[idx+1:]
+		}
+	}
+	return ""
+}
+
+// sameVersion returns true if the versions are the same ignoring any 'v' prefix
+func sameVersion(v1 string, v2 string) bool {
+	return strings.TrimPrefix(v1, "v") == strings.TrimPrefix(v2, "v")
+}
+
+// podFailure returns the reason a pod of the newest ReplicaSet of the Deployment cannot become ready or an empty
+// string. Pods of older ReplicaSets are ignored as they are being replaced by the rollout
+func podFailure(kubeClient kubernetes.Interface, d *appsv1.Deployment) (string, error) {
+	if d.Spec.Selector == nil {
+		return "", nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the selector of Deployment %s", d.Name)
+	}
+	rs, err := newestReplicaSet(kubeClient, d, selector.String())
+	if err != nil {
+		return "", err
+	}
+	if rs == nil {
+		return "", nil
+	}
+	hash := rs.Labels[podTemplateHashLabel]
+	if hash == "" {
+		return "", nil
+	}
+	podSelector := fmt.Sprintf("%s,%s=%s", selector.String(), podTemplateHashLabel, hash)
+	pods, err := kubeClient.CoreV1().Pods(d.Namespace).List(metav1.ListOptions{
+		LabelSelector: podSelector,
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list pods in namespace %s for Deployment %s", d.Namespace, d.Name)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodFailed {
+			return fmt.Sprintf("pod %s failed: %s", pod.Name, pod.Status.Reason), nil
+		}
+		if cs := clienthelpers.WaitingFailure(pod, ImagePullGracePeriod, crashLoopWaitingReason); cs != nil {
+			return fmt.Sprintf("container %s of pod %s is %s", cs.Name, pod.Name, cs.State.Waiting.Reason), nil
+		}
+	}
+	return "", nil
+}
+
+// newestReplicaSet returns the ReplicaSet of the Deployment with the highest revision or nil if there is none
+func newestReplicaSet(kubeClient kubernetes.Interface, d *appsv1.Deployment, selector string) (*appsv1.ReplicaSet, error) {
+	list, err := kubeClient.AppsV1().ReplicaSets(d.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list ReplicaSets in namespace %s for Deployment %s", d.Namespace, d.Name)
+	}
+	var answer *appsv1.ReplicaSet
+	answerRevision := int64(-1)
+	for i := range list.Items {
+		rs := &list.Items[i]
+		if !metav1.IsControlledBy(rs, d) {
+			continue
+		}
+		revision, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+		if err != nil {
+			revision = 0
+		}
+		if answer == nil || revision > answerRevision || (revision == answerRevision && answer.CreationTimestamp.Before(&rs.CreationTimestamp)) {
+			answer = rs
+			answerRevision = revision
+		}
+	}
+	return answer, nil
+}
@@ -0,0 +1,238 @@
+package deploystatus_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/deploystatus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestState(t *testing.T) {
+	ns := "jx-staging"
+	appName := "myapp"
+	version := "1.0.0"
+
+	crashing := createPod(ns, "myapp-abc", &corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{
+			Reason: "CrashLoopBackOff",
+		},
+	})
+
+	pulling := createPod(ns, "myapp-def", &corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{
+			Reason: "ImagePullBackOff",
+		},
+	})
+	pulling.CreationTimestamp = metav1.Now()
+
+	pullFailed := createPod(ns, "myapp-ghi", &corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{
+			Reason: "ErrImagePull",
+		},
+	})
+	pullFailed.CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * deploystatus.ImagePullGracePeriod))
+
+	// a pod of the previous ReplicaSet which is crashing should not fail the rollout of the new ReplicaSet
+	oldCrashing := createPod(ns, "myapp-old", &corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{
+			Reason: "CrashLoopBackOff",
+		},
+	})
+	oldCrashing.Labels["pod-template-hash"] = "old"
+
+	rollingOut := createDeployment(ns, "jx-myapp", 1, 1, 1, 0)
+
+	oldVersion := createDeployment(ns, "jx-myapp", 1, 1, 1, 1)
+	oldVersion.Spec.Template.Labels["version"] = "0.0.9"
+
+	oldImage := createDeployment(ns, "jx-myapp", 1, 1, 1, 1)
+	oldImage.Spec.Template.Labels = map[string]string{"app": "myapp"}
+	oldImage.Spec.Template.Spec.Containers[0].Image = "gcr.io/myorg/myapp:0.0.9"
+
+	newImage := createDeployment(ns, "jx-myapp", 1, 1, 1, 1)
+	newImage.Spec.Template.Labels = map[string]string{"app": "myapp"}
+	newImage.Spec.Template.Spec.Containers[0].Image = "localhost:5000/myorg/myapp:v1.0.0"
+
+	testCases := []struct {
+		name          string
+		objects       []runtime.Object
+		expectState   string
+		expectMessage string
+	}{
+		{
+			name:          "missing",
+			expectState:   deploystatus.StatePending,
+			expectMessage: "no Deployment found",
+		},
+		{
+			name:          "ready",
+			objects:       []runtime.Object{createDeployment(ns, "jx-myapp", 2, 2, 2, 2)},
+			expectState:   deploystatus.StateSuccess,
+			expectMessage: "2 of 2 replicas are ready",
+		},
+		{
+			name:          "rolling-out",
+			objects:       []runtime.Object{createDeployment(ns, "jx-myapp", 2, 3, 1, 1)},
+			expectState:   deploystatus.StateInProgress,
+			expectMessage: "1 of 2 updated replicas",
+		},
+		{
+			name:          "crash-loop",
+			objects:       []runtime.Object{rollingOut, createReplicaSet(rollingOut, "new", "2"), crashing},
+			expectState:   deploystatus.StateFailure,
+			expectMessage: "CrashLoopBackOff",
+		},
+		{
+			name:          "pulling-image",
+			objects:       []runtime.Object{rollingOut, createReplicaSet(rollingOut, "new", "2"), pulling},
+			expectState:   deploystatus.StateInProgress,
+			expectMessage: "0 of 1 updated replicas are available",
+		},
+		{
+			name:          "image-pull-failed",
+			objects:       []runtime.Object{rollingOut, createReplicaSet(rollingOut, "new", "2"), pullFailed},
+			expectState:   deploystatus.StateFailure,
+			expectMessage: "ErrImagePull",
+		},
+		{
+			name:          "old-replicaset-crash-loop",
+			objects:       []runtime.Object{rollingOut, createReplicaSet(rollingOut, "old", "1"), createReplicaSet(rollingOut, "new", "2"), oldCrashing},
+			expectState:   deploystatus.StateInProgress,
+			expectMessage: "0 of 1 updated replicas are available",
+		},
+		{
+			name:          "old-version-label",
+			objects:       []runtime.Object{oldVersion},
+			expectState:   deploystatus.StatePending,
+			expectMessage: "has version 0.0.9 rather than 1.0.0",
+		},
+		{
+			name:          "old-image-tag",
+			objects:       []runtime.Object{oldImage},
+			expectState:   deploystatus.StatePending,
+			expectMessage: "has version 0.0.9 rather than 1.0.0",
+		},
+		{
+			name:          "new-image-tag",
+			objects:       []runtime.Object{newImage},
+			expectState:   deploystatus.StateSuccess,
+			expectMessage: "1 of 1 replicas are ready",
+		},
+	}
+
+	for _, tc := range testCases {
+		kubeClient := fake.NewSimpleClientset(tc.objects...)
+		state, message, err := deploystatus.State(kubeClient, ns, appName, version)
+		require.NoError(t, err, "failed to get state for test %s", tc.name)
+		assert.Equal(t, tc.expectState, state, "state for test %s", tc.name)
+		assert.Contains(t, message, tc.expectMessage, "message for test %s", tc.name)
+	}
+}
+
+func TestStateProgressDeadlineExceeded(t *testing.T) {
+	ns := "jx-staging"
+	d := createDeployment(ns, "myapp", 1, 1, 1, 0)
+	d.Status.Conditions = []appsv1.DeploymentCondition{
+		{
+			Type:   appsv1.DeploymentProgressing,
+			Status: corev1.ConditionFalse,
+			Reason: "ProgressDeadlineExceeded",
+		},
+	}
+	kubeClient := fake.NewSimpleClientset(d)
+	state, _, err := deploystatus.State(kubeClient, ns, "myapp", "1.0.0")
+	require.NoError(t, err, "failed to get state")
+	assert.Equal(t, deploystatus.StateFailure, state, "state")
+}
+
+func createDeployment(ns, name string, replicas, statusReplicas, updated, available int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			UID:       types.UID(name + "-uid"),
+			Labels: map[string]string{
+				"app": "myapp",
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": "myapp",
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":     "myapp",
+						"version": "1.0.0",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "myapp",
+							Image: "gcr.io/myorg/myapp:1.0.0",
+						},
+					},
+				},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas:          statusReplicas,
+			UpdatedReplicas:   updated,
+			AvailableReplicas: available,
+			ReadyReplicas:     available,
+		},
+	}
+}
+
+func createPod(ns, name string, state *corev1.ContainerState) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels: map[string]string{
+				"app":               "myapp",
+				"pod-template-hash": "new",
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:  "myapp",
+					State: *state,
+				},
+			},
+		},
+	}
+}
+
+func createReplicaSet(d *appsv1.Deployment, hash string, revision string) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      d.Name + "-" + hash,
+			Namespace: d.Namespace,
+			Labels: map[string]string{
+				"app":               "myapp",
+				"pod-template-hash": hash,
+			},
+			Annotations: map[string]string{
+				"deployment.kubernetes.io/revision": revision,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(d, appsv1.SchemeGroupVersion.WithKind("Deployment")),
+			},
+		},
+	}
+}
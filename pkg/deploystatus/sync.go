@@ -0,0 +1,135 @@
+package deploystatus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/envfactory"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// Request the deployment status of a version of an app in an environment to post to the git provider
+type Request struct {
+	// FullName the full name of the git repository
+	FullName string
+
+	// AppName the name of the app
+	AppName string
+
+	// Version the version being deployed
+	Version string
+
+	// Environment the environment label
+	Environment string
+
+	// Status the status to post
+	Status scm.DeploymentStatusInput
+}
+
+// Sync creates the git deployment for the version if required and posts the status unless the latest status
+// already has the same state. Once a version succeeds the deployments of previous versions are marked inactive.
+// Returns true if a status was posted
+func Sync(ctx context.Context, scmClient *scm.Client, r *Request) (bool, error) {
+	fullName := r.FullName
+	deployments, _, err := scmClient.Deployments.List(ctx, fullName, scm.ListOptions{})
+	if err != nil && !envfactory.IsScmNotFound(err) {
+		return false, errors.Wrapf(err, "failed to list the deployments of %s", fullName)
+	}
+	var deployment *scm.Deployment
+	for _, d := range deployments {
+		if d.Ref == r.Version && d.Environment == r.Environment {
+			deployment = d
+			break
+		}
+	}
+
+	if deployment == nil {
+		deploymentInput := &scm.DeploymentInput{
+			Ref:                   r.Version,
+			Task:                  "deploy",
+			Environment:           r.Environment,
+			Description:           fmt.Sprintf("release %s for version %s", r.AppName, r.Version),
+			RequiredContexts:      nil,
+			AutoMerge:             false,
+			TransientEnvironment:  false,
+			ProductionEnvironment: strings.HasPrefix(strings.ToLower(r.Environment), "prod"),
+		}
+		deployment, _, err = scmClient.Deployments.Create(ctx, fullName, deploymentInput)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to create Deployment for %s version %s", fullName, r.Version)
+		}
+		log.Logger().Infof("created Deployment for %s version %s at %s", fullName, r.Version, deployment.Link)
+	}
+
+	posted := false
+	latest, err := latestStatus(ctx, scmClient, fullName, deployment.ID)
+	if err != nil {
+		return false, err
+	}
+	if latest != nil && latest.State == r.Status.State {
+		log.Logger().Debugf("the latest status of %s version %s is already %s", fullName, r.Version, latest.State)
+	} else {
+		input := r.Status
+		input.Environment = r.Environment
+		status, _, err := scmClient.Deployments.CreateStatus(ctx, fullName, deployment.ID, &input)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to create DeploymentStatus for %s version %s", fullName, r.Version)
+		}
+		posted = true
+		log.Logger().Infof("created DeploymentStatus %s for %s version %s with state %s", status.ID, fullName, r.Version, input.State)
+	}
+
+	if r.Status.State != StateSuccess {
+		return posted, nil
+	}
+	for _, d := range deployments {
+		if d.ID == deployment.ID || d.Environment != r.Environment || d.Ref == r.Version {
+			continue
+		}
+		err = markInactive(ctx, scmClient, fullName, d, r.Environment)
+		if err != nil {
+			return posted, err
+		}
+	}
+	return posted, nil
+}
+
+// markInactive marks a superseded deployment as inactive if it is not already
+func markInactive(ctx context.Context, scmClient *scm.Client, fullName string, d *scm.Deployment, environment string) error {
+	latest, err := latestStatus(ctx, scmClient, fullName, d.ID)
+	if err != nil {
+		return err
+	}
+	if latest == nil || latest.State == StateInactive {
+		return nil
+	}
+	input := &scm.DeploymentStatusInput{
+		State:       StateInactive,
+		Description: fmt.Sprintf("superseded version %s", strings.TrimPrefix(d.Ref, "v")),
+		Environment: environment,
+	}
+	_, _, err = scmClient.Deployments.CreateStatus(ctx, fullName, d.ID, input)
+	if err != nil {
+		return errors.Wrapf(err, "failed to mark Deployment %s of %s inactive", d.ID, fullName)
+	}
+	log.Logger().Infof("marked the Deployment of %s version %s as inactive", fullName, d.Ref)
+	return nil
+}
+
+// latestStatus returns the most recent status of the deployment or nil if there are none
+func latestStatus(ctx context.Context, scmClient *scm.Client, fullName string, deploymentID string) (*scm.DeploymentStatus, error) {
+	statuses, _, err := scmClient.Deployments.ListStatus(ctx, fullName, deploymentID, scm.ListOptions{})
+	if err != nil && !envfactory.IsScmNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to list the statuses of Deployment %s of %s", deploymentID, fullName)
+	}
+	var answer *scm.DeploymentStatus
+	for _, s := range statuses {
+		if answer == nil || s.Created.After(answer.Created) {
+			answer = s
+		}
+	}
+	return answer, nil
+}
@@ -0,0 +1,93 @@
+package deploystatus_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/deploystatus"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDeploymentService a fake SCM deployment service which stores deployments and statuses in memory
+type fakeDeploymentService struct {
+	scm.DeploymentService
+	deployments []*scm.Deployment
+	statuses    map[string][]*scm.DeploymentStatus
+}
+
+func (s *fakeDeploymentService) List(ctx context.Context, repo string, opts scm.ListOptions) ([]*scm.Deployment, *scm.Response, error) {
+	return s.deployments, nil, nil
+}
+
+func (s *fakeDeploymentService) Create(ctx context.Context, repo string, input *scm.DeploymentInput) (*scm.Deployment, *scm.Response, error) {
+	d := &scm.Deployment{
+		ID:          fmt.Sprintf("%d", len(s.deployments)+1),
+		Ref:         input.Ref,
+		Environment: input.Environment,
+	}
+	s.deployments = append(s.deployments, d)
+	return d, nil, nil
+}
+
+func (s *fakeDeploymentService) ListStatus(ctx context.Context, repo string, id string, opts scm.ListOptions) ([]*scm.DeploymentStatus, *scm.Response, error) {
+	return s.statuses[id], nil, nil
+}
+
+func (s *fakeDeploymentService) CreateStatus(ctx context.Context, repo string, id string, input *scm.DeploymentStatusInput) (*scm.DeploymentStatus, *scm.Response, error) {
+	status := &scm.DeploymentStatus{
+		ID:          fmt.Sprintf("%s-%d", id, len(s.statuses[id])+1),
+		State:       input.State,
+		Environment: input.Environment,
+	}
+	// the most recent status is returned first
+	s.statuses[id] = append([]*scm.DeploymentStatus{status}, s.statuses[id]...)
+	return status, nil, nil
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	ds := &fakeDeploymentService{
+		deployments: []*scm.Deployment{
+			{ID: "1", Ref: "1.0.0", Environment: "staging"},
+			{ID: "2", Ref: "0.9.0", Environment: "production"},
+		},
+		statuses: map[string][]*scm.DeploymentStatus{
+			"1": {{ID: "1-1", State: deploystatus.StateSuccess}},
+			"2": {{ID: "2-1", State: deploystatus.StateSuccess}},
+		},
+	}
+	scmClient := &scm.Client{Deployments: ds}
+
+	request := func(state string) *deploystatus.Request {
+		return &deploystatus.Request{
+			FullName:    "myorg/myapp",
+			AppName:     "myapp",
+			Version:     "1.1.0",
+			Environment: "staging",
+			Status: scm.DeploymentStatusInput{
+				State: state,
+			},
+		}
+	}
+
+	posted, err := deploystatus.Sync(ctx, scmClient, request(deploystatus.StateInProgress))
+	require.NoError(t, err, "failed to sync in progress")
+	assert.True(t, posted, "should have posted the in progress status")
+	require.Len(t, ds.deployments, 3, "should have created a deployment")
+	assert.Equal(t, deploystatus.StateSuccess, ds.statuses["1"][0].State, "previous version should still be active while rolling out")
+
+	posted, err = deploystatus.Sync(ctx, scmClient, request(deploystatus.StateInProgress))
+	require.NoError(t, err, "failed to sync in progress again")
+	assert.False(t, posted, "should not post the same state again")
+	assert.Len(t, ds.statuses["3"], 1, "statuses of the new deployment")
+
+	posted, err = deploystatus.Sync(ctx, scmClient, request(deploystatus.StateSuccess))
+	require.NoError(t, err, "failed to sync success")
+	assert.True(t, posted, "should have posted the success status")
+	assert.Equal(t, deploystatus.StateSuccess, ds.statuses["3"][0].State, "new version state")
+	assert.Equal(t, deploystatus.StateInactive, ds.statuses["1"][0].State, "superseded version should be inactive")
+	assert.Equal(t, deploystatus.StateSuccess, ds.statuses["2"][0].State, "other environments should not change")
+}
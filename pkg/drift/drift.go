@@ -0,0 +1,192 @@
+package drift
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/helmer"
+	"github.com/jenkins-x-labs/helmboot/pkg/helmfiles"
+	"github.com/pkg/errors"
+)
+
+const (
+	// KindMissing a release in git which is not installed
+	KindMissing = "Missing"
+
+	// KindExtra a release installed which is not in git
+	KindExtra = "Extra"
+
+	// KindVersionSkew a release installed with a different chart or version to git
+	KindVersionSkew = "VersionSkew"
+
+	// KindFailed a release in git which is installed but failed
+	KindFailed = "Failed"
+)
+
+// chartVersionRegex splits the chart name and version at the first dash followed by a semantic version so that
+// pre-release versions such as jx-boot-1.0.0-rc.1 and build metadata such as 1.0.0+abc keep their dashes
+var chartVersionRegex = regexp.MustCompile(`^(.+?)-(v?\d+\.\d+\.\d+(?:[-+][0-9A-Za-z.+-]*)?)$`)
+
+// Difference a difference between the desired state in git and the cluster
+type Difference struct {
+	Kind             string `json:"kind"`
+	Release          string `json:"release"`
+	Namespace        string `json:"namespace"`
+	Chart            string `json:"chart,omitempty"`
+	DesiredVersion   string `json:"desiredVersion,omitempty"`
+	InstalledChart   string `json:"installedChart,omitempty"`
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	Status           string `json:"status,omitempty"`
+}
+
+// Report the drift between git and the cluster
+type Report struct {
+	Differences []Difference `json:"differences"`
+
+	// ManifestDiffs the optional manifest level differences indexed by helmfile directory
+	ManifestDiffs map[string]string `json:"manifestDiffs,omitempty"`
+}
+
+// HasDrift returns true if there are any differences
+func (r *Report) HasDrift() bool {
+	if len(r.Differences) > 0 {
+		return true
+	}
+	for _, v := range r.ManifestDiffs {
+		if strings.TrimSpace(v) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare compares the desired releases with the installed releases
+func Compare(desired []helmfiles.Release, installed []helmer.ReleaseSummary) *Report {
+	report := &Report{}
+	installedMap := map[string]helmer.ReleaseSummary{}
+	for _, r := range installed {
+		installedMap[releaseKey(r.Namespace, r.ReleaseName)] = r
+	}
+	desiredKeys := map[string]bool{}
+
+	for _, d := range desired {
+		key := releaseKey(d.Namespace, d.Name)
+		desiredKeys[key] = true
+		i, ok := installedMap[key]
+		if !ok {
+			report.Differences = append(report.Differences, Difference{
+				Kind:           KindMissing,
+				Release:        d.Name,
+				Namespace:      d.Namespace,
+				Chart:          d.Chart,
+				DesiredVersion: d.Version,
+			})
+			continue
+		}
+		diff := Difference{
+			Release:          d.Name,
+			Namespace:        d.Namespace,
+			Chart:            d.Chart,
+			DesiredVersion:   d.Version,
+			InstalledChart:   i.Chart,
+			InstalledVersion: i.ChartVersion,
+			Status:           i.Status,
+		}
+		if chartName(d.Chart) != i.Chart || (d.Version != "" && strings.TrimPrefix(d.Version, "v") != strings.TrimPrefix(i.ChartVersion, "v")) {
+			diff.Kind = KindVersionSkew
+			report.Differences = append(report.Differences, diff)
+		} else if strings.ToLower(i.Status) == "failed" {
+			diff.Kind = KindFailed
+			report.Differences = append(report.Differences, diff)
+		}
+	}
+
+	for _, i := range installed {
+		if desiredKeys[releaseKey(i.Namespace, i.ReleaseName)] {
+			continue
+		}
+		report.Differences = append(report.Differences, Difference{
+			Kind:             KindExtra,
+			Release:          i.ReleaseName,
+			Namespace:        i.Namespace,
+			InstalledChart:   i.Chart,
+			InstalledVersion: i.ChartVersion,
+			Status:           i.Status,
+		})
+	}
+
+	sort.Slice(report.Differences, func(i, j int) bool {
+		d1 := report.Differences[i]
+		d2 := report.Differences[j]
+		if d1.Namespace != d2.Namespace {
+			return d1.Namespace < d2.Namespace
+		}
+		return d1.Release < d2.Release
+	})
+	return report
+}
+
+// helmListRelease a release in the output of 'helm list --output json'
+type helmListRelease struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Revision   string `json:"revision"`
+	Updated    string `json:"updated"`
+	Status     string `json:"status"`
+	Chart      string `json:"chart"`
+	AppVersion string `json:"app_version"`
+}
+
+// ParseHelmList parses the output of 'helm list --output json' into the release summaries
+func ParseHelmList(output string) ([]helmer.ReleaseSummary, error) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, nil
+	}
+	var releases []helmListRelease
+	err := json.Unmarshal([]byte(output), &releases)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the helm list JSON output")
+	}
+	var answer []helmer.ReleaseSummary
+	for _, r := range releases {
+		chart, version := SplitChartVersion(r.Chart)
+		answer = append(answer, helmer.ReleaseSummary{
+			ReleaseName:   r.Name,
+			Namespace:     r.Namespace,
+			Revision:      r.Revision,
+			Updated:       r.Updated,
+			Status:        strings.ToUpper(r.Status),
+			ChartFullName: r.Chart,
+			Chart:         chart,
+			ChartVersion:  version,
+			AppVersion:    r.AppVersion,
+		})
+	}
+	return answer, nil
+}
+
+// SplitChartVersion splits the chart column of helm list such as jx-boot-1.0.0-rc.1 into the chart name and version.
+// If there is no version the chart is returned with an empty version
+func SplitChartVersion(chartFullName string) (string, string) {
+	groups := chartVersionRegex.FindStringSubmatch(chartFullName)
+	if groups == nil {
+		return chartFullName, ""
+	}
+	return groups[1], groups[2]
+}
+
+// chartName returns the chart name without the repository prefix
+func chartName(chart string) string {
+	idx := strings.LastIndex(chart, "/")
+	if idx >= 0 {
+		return chart[idx+1:]
+	}
+	return chart
+}
+
+func releaseKey(ns, name string) string {
+	return ns + "/" + name
+}
@@ -0,0 +1,95 @@
+package drift_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/drift"
+	"github.com/jenkins-x-labs/helmboot/pkg/helmer"
+	"github.com/jenkins-x-labs/helmboot/pkg/helmfiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	desired := []helmfiles.Release{
+		{Name: "cert-manager", Namespace: "cert-manager", Chart: "jetstack/cert-manager", Version: "v0.11.0"},
+		{Name: "lighthouse", Namespace: "jx", Chart: "jenkins-x/lighthouse", Version: "0.0.633"},
+		{Name: "tekton", Namespace: "jx", Chart: "jenkins-x/tekton", Version: "0.0.55"},
+		{Name: "nginx-ingress", Namespace: "nginx", Chart: "stable/nginx-ingress", Version: "1.24.0"},
+	}
+	installed := []helmer.ReleaseSummary{
+		{ReleaseName: "cert-manager", Namespace: "cert-manager", Chart: "cert-manager", ChartVersion: "0.11.0", Status: "DEPLOYED"},
+		{ReleaseName: "lighthouse", Namespace: "jx", Chart: "lighthouse", ChartVersion: "0.0.600", Status: "DEPLOYED"},
+		{ReleaseName: "nginx-ingress", Namespace: "nginx", Chart: "nginx-ingress", ChartVersion: "1.24.0", Status: "FAILED"},
+		{ReleaseName: "old-app", Namespace: "jx", Chart: "old-app", ChartVersion: "1.0.0", Status: "DEPLOYED"},
+	}
+
+	report := drift.Compare(desired, installed)
+	assert.True(t, report.HasDrift(), "should have drift")
+
+	var kinds []string
+	for _, d := range report.Differences {
+		kinds = append(kinds, d.Namespace+"/"+d.Release+"="+d.Kind)
+	}
+	assert.Equal(t, []string{
+		"jx/lighthouse=" + drift.KindVersionSkew,
+		"jx/old-app=" + drift.KindExtra,
+		"jx/tekton=" + drift.KindMissing,
+		"nginx/nginx-ingress=" + drift.KindFailed,
+	}, kinds, "differences")
+}
+
+func TestCompareNoDrift(t *testing.T) {
+	desired := []helmfiles.Release{
+		{Name: "lighthouse", Namespace: "jx", Chart: "jenkins-x/lighthouse", Version: "0.0.633"},
+	}
+	installed := []helmer.ReleaseSummary{
+		{ReleaseName: "lighthouse", Namespace: "jx", Chart: "lighthouse", ChartVersion: "0.0.633", Status: "DEPLOYED"},
+	}
+	report := drift.Compare(desired, installed)
+	assert.False(t, report.HasDrift(), "should not have drift")
+	assert.Empty(t, report.Differences, "differences")
+}
+
+func TestParseHelmList(t *testing.T) {
+	output := `[{"name":"lighthouse","namespace":"jx","revision":"3","updated":"2020-04-01 12:00:00","status":"deployed","chart":"lighthouse-0.0.600","app_version":""},
+{"name":"cert-manager","namespace":"cert-manager","revision":"1","updated":"2020-04-01 12:00:00","status":"failed","chart":"cert-manager-v0.11.0","app_version":"v0.11.0"}]`
+
+	releases, err := drift.ParseHelmList(output)
+	require.NoError(t, err, "failed to parse the helm list output")
+	require.Len(t, releases, 2, "releases")
+	assert.Equal(t, helmer.ReleaseSummary{
+		ReleaseName:   "cert-manager",
+		Namespace:     "cert-manager",
+		Revision:      "1",
+		Updated:       "2020-04-01 12:00:00",
+		Status:        "FAILED",
+		ChartFullName: "cert-manager-v0.11.0",
+		Chart:         "cert-manager",
+		ChartVersion:  "v0.11.0",
+		AppVersion:    "v0.11.0",
+	}, releases[1], "release")
+
+	testCases := []struct {
+		chart   string
+		name    string
+		version string
+	}{
+		{chart: "lighthouse-0.0.600", name: "lighthouse", version: "0.0.600"},
+		{chart: "cert-manager-v0.11.0", name: "cert-manager", version: "v0.11.0"},
+		{chart: "jx-boot-1.0.0-rc.1", name: "jx-boot", version: "1.0.0-rc.1"},
+		{chart: "jx-boot-1.0.0-rc.1+build.5", name: "jx-boot", version: "1.0.0-rc.1+build.5"},
+		{chart: "nginx-ingress-1.2.3+sha-abc123", name: "nginx-ingress", version: "1.2.3+sha-abc123"},
+		{chart: "tekton-2-1.0.0", name: "tekton-2", version: "1.0.0"},
+		{chart: "no-version", name: "no-version", version: ""},
+	}
+	for _, tc := range testCases {
+		name, version := drift.SplitChartVersion(tc.chart)
+		assert.Equal(t, tc.name, name, "chart name of %s", tc.chart)
+		assert.Equal(t, tc.version, version, "chart version of %s", tc.chart)
+	}
+
+	releases, err = drift.ParseHelmList("")
+	require.NoError(t, err, "failed to parse empty helm list output")
+	assert.Empty(t, releases, "releases")
+}
@@ -41,17 +41,13 @@ func (o *EnvFactory) AddFlags(cmd *cobra.Command) {
 
 }
 
-// CreateDevEnvGitRepository creates the dev environment git repository from the given directory
-func (o *EnvFactory) CreateDevEnvGitRepository(dir string, gitPublic bool) error {
+// CreateDevEnvGitRepository creates the dev environment git repository from the given directory and requirements.
+// The requirements are passed in as they may have been composed from a requirements overlay or overridden on the CLI
+func (o *EnvFactory) CreateDevEnvGitRepository(dir string, requirements *config.RequirementsConfig, gitPublic bool) error {
 	o.OutDir = dir
-	requirements, fileName, err := config.LoadRequirementsConfig(dir)
-	if err != nil {
-		return errors.Wrapf(err, "failed to load requirements from %s", dir)
-	}
-
 	dev := reqhelpers.GetDevEnvironmentConfig(requirements)
 	if dev == nil {
-		return fmt.Errorf("the file %s does not contain a development environment", fileName)
+		return fmt.Errorf("the requirements in dir %s do not contain a development environment", dir)
 	}
 
 	cr := &CreateRepository{
@@ -69,7 +65,7 @@ func (o *EnvFactory) CreateDevEnvGitRepository(dir string, gitPublic bool) error
 	}
 
 	handles := jxadapt.ToIOHandles(o.IOFileHandles)
-	err = cr.ConfirmValues(o.BatchMode, handles)
+	err := cr.ConfirmValues(o.BatchMode, handles)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,46 @@
+package fakerunner
+
+import (
+	"strings"
+
+	"github.com/jenkins-x/jx/pkg/util"
+)
+
+// FakeRunner a fake command runner which records the commands rather than running them
+type FakeRunner struct {
+	// Commands the commands which have been run
+	Commands []*util.Command
+
+	// Results the optional output returned for a command line such as "helm version"
+	Results map[string]string
+
+	// Errors the optional errors returned for a command line
+	Errors map[string]error
+}
+
+// Run records the command and returns any registered output or error
+func (f *FakeRunner) Run(c *util.Command) (string, error) {
+	f.Commands = append(f.Commands, c)
+	line := CommandLine(c)
+	if f.Errors != nil && f.Errors[line] != nil {
+		return "", f.Errors[line]
+	}
+	if f.Results != nil {
+		return f.Results[line], nil
+	}
+	return "", nil
+}
+
+// CommandLines returns the command lines which have been run
+func (f *FakeRunner) CommandLines() []string {
+	var answer []string
+	for _, c := range f.Commands {
+		answer = append(answer, CommandLine(c))
+	}
+	return answer
+}
+
+// CommandLine returns the command name and arguments as a single line
+func CommandLine(c *util.Command) string {
+	return strings.TrimSpace(c.Name + " " + strings.Join(c.Args, " "))
+}
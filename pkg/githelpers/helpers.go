@@ -99,3 +99,20 @@ func AddUserTokenToURLIfRequired(gitURL, username, token string) (string, error)
 	u.User = url.UserPassword(username, token)
 	return u.String(), nil
 }
+
+// CloneOrCopyToTempDir clones the git URL if specified otherwise copies the directory into a new temporary
+// directory so that the source directory is not modified
+func CloneOrCopyToTempDir(gitter gits.Gitter, gitURL string, dir string) (string, error) {
+	tmpDir, err := ioutil.TempDir("", "helmboot-")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create temporary directory")
+	}
+	if gitURL != "" {
+		return GitCloneToTempDir(gitter, gitURL, tmpDir)
+	}
+	err = util.CopyDir(dir, tmpDir, true)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to copy %s to %s", dir, tmpDir)
+	}
+	return tmpDir, nil
+}
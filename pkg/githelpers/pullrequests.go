@@ -0,0 +1,65 @@
+package githelpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/pkg/errors"
+)
+
+// gitHubDraftMediaType the GitHub API preview media type required to create draft Pull Requests
+const gitHubDraftMediaType = "application/vnd.github.shadow-cat-preview+json"
+
+// CreateGitHubDraftPullRequest creates a draft Pull Request on GitHub. We use the REST API directly as go-scm
+// does not support the draft flag
+func CreateGitHubDraftPullRequest(ctx context.Context, scmClient *scm.Client, repo string, input *scm.PullRequestInput) (*scm.PullRequest, error) {
+	body := map[string]interface{}{
+		"title": input.Title,
+		"head":  input.Head,
+		"base":  input.Base,
+		"body":  input.Body,
+		"draft": true,
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the Pull Request")
+	}
+	req := &scm.Request{
+		Method: http.MethodPost,
+		Path:   fmt.Sprintf("repos/%s/pulls", repo),
+		Header: http.Header{
+			"Accept":       []string{gitHubDraftMediaType},
+			"Content-Type": []string{"application/json"},
+		},
+		Body: bytes.NewReader(data),
+	}
+	res, err := scmClient.Do(ctx, req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create draft Pull Request on %s", repo)
+	}
+	defer res.Body.Close()
+
+	if res.Status >= http.StatusMultipleChoices {
+		message, _ := ioutil.ReadAll(res.Body)
+		return nil, errors.Errorf("failed to create draft Pull Request on %s: status %d: %s", repo, res.Status, string(message))
+	}
+	out := &struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+	}{}
+	err = json.NewDecoder(res.Body).Decode(out)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the draft Pull Request on %s", repo)
+	}
+	return &scm.PullRequest{
+		Number: out.Number,
+		Title:  out.Title,
+		Link:   out.HTMLURL,
+	}, nil
+}
@@ -0,0 +1,42 @@
+package githelpers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/githelpers"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateGitHubDraftPullRequest(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/myorg/myrepo/pulls", r.URL.Path, "path")
+		assert.Equal(t, "application/vnd.github.shadow-cat-preview+json", r.Header.Get("Accept"), "accept header")
+		err := json.NewDecoder(r.Body).Decode(&body)
+		assert.NoError(t, err, "failed to decode body")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"number": 12, "title": "fix: upgrade", "html_url": "https://github.com/myorg/myrepo/pull/12"}`))
+	}))
+	defer server.Close()
+
+	scmClient, err := github.New(server.URL)
+	require.NoError(t, err, "failed to create client")
+
+	pr, err := githelpers.CreateGitHubDraftPullRequest(context.Background(), scmClient, "myorg/myrepo", &scm.PullRequestInput{
+		Title: "fix: upgrade",
+		Head:  "mybranch",
+		Base:  "master",
+	})
+	require.NoError(t, err, "failed to create draft Pull Request")
+	assert.Equal(t, 12, pr.Number, "pr.Number")
+	assert.Equal(t, "https://github.com/myorg/myrepo/pull/12", pr.Link, "pr.Link")
+	assert.Equal(t, true, body["draft"], "draft flag")
+	assert.Equal(t, "mybranch", body["head"], "head")
+}
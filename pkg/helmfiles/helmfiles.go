@@ -0,0 +1,128 @@
+package helmfiles
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx/pkg/cmd/clients"
+	"github.com/jenkins-x/jx/pkg/cmd/opts"
+	"github.com/jenkins-x/jx/pkg/cmd/step/create/helmfile"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// HelmfileName the name of the generated helmfile in each helmfile directory
+	HelmfileName = "helmfile.yaml"
+
+	// sourcePrefix the comment helm adds to each rendered document
+	sourcePrefix = "# Source: "
+
+	// defaultManifestFile the file name used for documents without a source comment
+	defaultManifestFile = "manifests.yaml"
+)
+
+// Dirs the directories containing the generated helmfiles in the order they are applied
+var Dirs = []string{"system", "apps"}
+
+// Helmfile the subset of a helmfile we need to process its releases
+type Helmfile struct {
+	Releases []Release `json:"releases,omitempty"`
+}
+
+// Release a release in a helmfile
+type Release struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Chart     string `json:"chart,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
+// LoadReleases loads the releases from the given helmfile
+func LoadReleases(fileName string) ([]Release, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load helmfile %s", fileName)
+	}
+	helmfile := &Helmfile{}
+	err = yaml.Unmarshal(data, helmfile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal helmfile %s", fileName)
+	}
+	return helmfile.Releases, nil
+}
+
+// SplitManifests splits the rendered output of a release into files indexed by the template path without the chart name
+func SplitManifests(output string) map[string]string {
+	answer := map[string]string{}
+	for _, doc := range strings.Split("\n"+output, "\n---") {
+		doc = strings.TrimSpace(doc)
+		if doc == "" {
+			continue
+		}
+		path := defaultManifestFile
+		lines := strings.SplitN(doc, "\n", 2)
+		if strings.HasPrefix(lines[0], sourcePrefix) {
+			path = strings.TrimSpace(strings.TrimPrefix(lines[0], sourcePrefix))
+
+			// lets remove the chart name
+			idx := strings.Index(path, "/")
+			if idx > 0 {
+				path = path[idx+1:]
+			}
+			if len(lines) < 2 || strings.TrimSpace(lines[1]) == "" {
+				continue
+			}
+		}
+		if answer[path] != "" {
+			answer[path] += "---\n"
+		}
+		answer[path] += doc + "\n"
+	}
+	return answer
+}
+
+// WriteManifests writes the manifest files into the given directory returning the sorted paths written
+func WriteManifests(dir string, files map[string]string) ([]string, error) {
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fileName := filepath.Join(dir, filepath.FromSlash(path))
+		if !strings.HasPrefix(filepath.Clean(fileName), filepath.Clean(dir)+string(os.PathSeparator)) {
+			return nil, errors.Errorf("invalid manifest path %s", path)
+		}
+		err := os.MkdirAll(filepath.Dir(fileName), util.DefaultWritePermissions)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create directory for %s", fileName)
+		}
+		err = ioutil.WriteFile(fileName, []byte(files[path]), util.DefaultFileWritePermissions)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to save file %s", fileName)
+		}
+	}
+	return paths, nil
+}
+
+// ReleaseDir returns the relative directory used for the rendered manifests of a release
+func ReleaseDir(r *Release) string {
+	return filepath.Join(r.Namespace, r.Name)
+}
+
+// Generate generates the helmfiles from the jx-apps.yml file in the given directory resolving the versions
+// from the version stream in the requirements
+func Generate(dir string, batchMode bool) error {
+	ho := &helmfile.CreateHelmfileOptions{}
+	f := clients.NewFactory()
+	ho.CommonOptions = opts.NewCommonOptionsWithTerm(f, os.Stdin, os.Stdout, os.Stderr)
+	ho.BatchMode = batchMode
+	ho.Dir = dir
+	ho.IgnoreNamespaceCheck = true
+	return ho.Run()
+}
@@ -0,0 +1,64 @@
+package helmfiles_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/helmfiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const renderedOutput = `---
+# Source: lighthouse/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: lighthouse-webhooks
+---
+# Source: lighthouse/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: hook
+---
+# Source: lighthouse/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: lighthouse-keeper
+---
+# Source: lighthouse/templates/empty.yaml
+`
+
+func TestSplitManifests(t *testing.T) {
+	files := helmfiles.SplitManifests(renderedOutput)
+	require.Len(t, files, 2, "files")
+	assert.Contains(t, files, "templates/deployment.yaml", "files")
+	assert.Contains(t, files, "templates/service.yaml", "files")
+	assert.Contains(t, files["templates/deployment.yaml"], "name: lighthouse-keeper", "deployment file")
+	assert.Contains(t, files["templates/deployment.yaml"], "---\n", "deployment file should have multiple documents")
+
+	dir, err := ioutil.TempDir("", "helmboot-helmfiles-")
+	require.NoError(t, err, "failed to create temp dir")
+	defer os.RemoveAll(dir)
+
+	paths, err := helmfiles.WriteManifests(dir, files)
+	require.NoError(t, err, "failed to write manifests")
+	assert.Equal(t, []string{"templates/deployment.yaml", "templates/service.yaml"}, paths, "paths")
+	assert.FileExists(t, filepath.Join(dir, "templates", "service.yaml"), "service file")
+
+	_, err = helmfiles.WriteManifests(dir, map[string]string{"../escape.yaml": "foo: bar\n"})
+	assert.Error(t, err, "should not be able to write outside of the directory")
+}
+
+func TestLoadReleases(t *testing.T) {
+	fileName := filepath.Join("..", "cmd", "template", "test_data", "dev-env", "apps", helmfiles.HelmfileName)
+	releases, err := helmfiles.LoadReleases(fileName)
+	require.NoError(t, err, "failed to load releases from %s", fileName)
+	require.Len(t, releases, 1, "releases")
+	assert.Equal(t, "lighthouse", releases[0].Name, "release name")
+	assert.Equal(t, filepath.Join("jx", "lighthouse"), helmfiles.ReleaseDir(&releases[0]), "release dir")
+}
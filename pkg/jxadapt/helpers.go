@@ -104,21 +104,26 @@ func (a *JXAdapter) ScmClientForRepository(gitInfo *gits.GitRepository) (*scm.Cl
 	return client, token, err
 }
 
+// GitKind returns the kind of the given git server from the local git auth config or the well known SaaS providers
+func (a *JXAdapter) GitKind(serverURL string) (string, error) {
+	_, cfg, err := a.loadGitAuthConfig()
+	if err != nil {
+		return "", err
+	}
+	kind := cfg.GetOrCreateServer(serverURL).Kind
+	if kind == "" {
+		kind = gits.SaasGitKind(serverURL)
+	}
+	return kind, nil
+}
+
 // findGitTokenForServer finds the git token and kind for the given server URL
 func (a *JXAdapter) findGitTokenForServer(serverURL string, owner string) (string, string, error) {
-	co := a.NewCommonOptions()
-	authSvc, err := co.GitLocalAuthConfigService()
 	token := ""
 	kind := ""
+	co, cfg, err := a.loadGitAuthConfig()
 	if err != nil {
-		return token, kind, errors.Wrapf(err, "failed to load local git auth")
-	}
-	cfg, err := authSvc.LoadConfig()
-	if err != nil {
-		return token, kind, errors.Wrapf(err, "failed to load local git auth config")
-	}
-	if cfg == nil {
-		cfg = &auth.AuthConfig{}
+		return token, kind, err
 	}
 	server := cfg.GetOrCreateServer(serverURL)
 	kind = server.Kind
@@ -140,3 +145,20 @@ func (a *JXAdapter) findGitTokenForServer(serverURL string, owner string) (strin
 	}
 	return token, kind, nil
 }
+
+// loadGitAuthConfig loads the local git auth config
+func (a *JXAdapter) loadGitAuthConfig() (*opts.CommonOptions, *auth.AuthConfig, error) {
+	co := a.NewCommonOptions()
+	authSvc, err := co.GitLocalAuthConfigService()
+	if err != nil {
+		return co, nil, errors.Wrapf(err, "failed to load local git auth")
+	}
+	cfg, err := authSvc.LoadConfig()
+	if err != nil {
+		return co, nil, errors.Wrapf(err, "failed to load local git auth config")
+	}
+	if cfg == nil {
+		cfg = &auth.AuthConfig{}
+	}
+	return co, cfg, nil
+}
@@ -0,0 +1,181 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/jenkins-x-labs/helmboot/pkg/cmdrunner"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// VerifyKubeContext verifies the current kubernetes context is for the cluster in the requirements by matching
+// the cluster name against the name of the context, the name of its cluster or the host name of its server
+func VerifyKubeContext(config *api.Config, clusterName string) (string, error) {
+	if config == nil || config.CurrentContext == "" {
+		return "", errors.Errorf("no current kubernetes context")
+	}
+	current := config.CurrentContext
+	if clusterName == "" {
+		return fmt.Sprintf("using context %s as there is no cluster name in the requirements", current), nil
+	}
+	names := []string{current}
+	ctx := config.Contexts[current]
+	if ctx != nil && ctx.Cluster != "" {
+		names = append(names, ctx.Cluster)
+		cluster := config.Clusters[ctx.Cluster]
+		if cluster != nil && cluster.Server != "" {
+			u, err := url.Parse(cluster.Server)
+			if err == nil && u.Hostname() != "" {
+				names = append(names, u.Hostname())
+			}
+		}
+	}
+	for _, name := range names {
+		if name == clusterName || name == "kind-"+clusterName || strings.HasSuffix(name, "_"+clusterName) || strings.HasSuffix(name, "/"+clusterName) || strings.HasPrefix(name, clusterName+".") {
+			return fmt.Sprintf("context %s is for cluster %s", current, clusterName), nil
+		}
+	}
+	return "", errors.Errorf("the current context %s does not look like the cluster %s in the requirements", current, clusterName)
+}
+
+// VerifyNamespace verifies the namespace exists
+func VerifyNamespace(kubeClient kubernetes.Interface, ns string) (string, error) {
+	_, err := kubeClient.CoreV1().Namespaces().Get(ns, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", errors.Errorf("namespace %s does not exist", ns)
+		}
+		return "", errors.Wrapf(err, "failed to get namespace %s", ns)
+	}
+	return fmt.Sprintf("namespace %s exists", ns), nil
+}
+
+// VerifyCanCreate verifies the current user can create the given resource in the namespace
+func VerifyCanCreate(kubeClient kubernetes.Interface, ns string, group string, resource string) (string, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: ns,
+				Verb:      "create",
+				Group:     group,
+				Resource:  resource,
+			},
+		},
+	}
+	result, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(review)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to check if we can create %s in namespace %s", resource, ns)
+	}
+	if !result.Status.Allowed {
+		reason := result.Status.Reason
+		if reason == "" {
+			reason = "access denied"
+		}
+		return "", errors.Errorf("cannot create %s in namespace %s: %s", resource, ns, reason)
+	}
+	return fmt.Sprintf("can create %s in namespace %s", resource, ns), nil
+}
+
+// VerifyGitURL verifies the git repository can be accessed with the credentials in the git URL.
+// Git is not allowed to prompt for credentials so that the check fails rather than hangs if they are invalid
+func VerifyGitURL(runner cmdrunner.CommandRunner, gitURL string) (string, error) {
+	if runner == nil {
+		runner = cmdrunner.DefaultCommandRunner
+	}
+	safeURL := util.SanitizeURL(gitURL)
+	c := &util.Command{
+		Name: "git",
+		Args: []string{"ls-remote", "--heads", gitURL},
+		Env: map[string]string{
+			"GIT_TERMINAL_PROMPT": "0",
+		},
+	}
+	_, err := runner(c)
+	if err != nil {
+		return "", errors.Errorf("cannot access git repository %s with the stored credentials", safeURL)
+	}
+	return fmt.Sprintf("can access git repository %s", safeURL), nil
+}
+
+// versionRegex matches the semantic version in the output of a version command
+var versionRegex = regexp.MustCompile(`v?\d+\.\d+\.\d+`)
+
+// VerifyKubeVersion verifies the kubernetes cluster can be reached and is at least the given version
+func VerifyKubeVersion(kubeClient kubernetes.Interface, minVersion string) (string, error) {
+	info, err := kubeClient.Discovery().ServerVersion()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to connect to the kubernetes cluster")
+	}
+	err = verifyMinVersion("kubernetes", info.GitVersion, minVersion)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("connected to kubernetes %s", info.GitVersion), nil
+}
+
+// VerifyBinaryVersion verifies the binary is on the PATH and is at least the given version
+func VerifyBinaryVersion(runner cmdrunner.CommandRunner, name string, args []string, minVersion string) (string, error) {
+	if runner == nil {
+		runner = cmdrunner.DefaultCommandRunner
+	}
+	c := &util.Command{
+		Name: name,
+		Args: args,
+	}
+	text, err := runner(c)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to run %s %s", name, strings.Join(args, " "))
+	}
+	version := versionRegex.FindString(text)
+	if version == "" {
+		return "", errors.Errorf("could not find the version of %s in: %s", name, strings.TrimSpace(text))
+	}
+	err = verifyMinVersion(name, version, minVersion)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s version %s", name, version), nil
+}
+
+// VerifyRepositoryPush verifies the current git user can push to the repository
+func VerifyRepositoryPush(scmClient *scm.Client, fullName string) (string, error) {
+	perms, _, err := scmClient.Repositories.FindPerms(context.Background(), fullName)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find the permissions of git repository %s", fullName)
+	}
+	if perms == nil || !perms.Push {
+		return "", errors.Errorf("the git token cannot push to repository %s", fullName)
+	}
+	return fmt.Sprintf("the git token can push to repository %s", fullName), nil
+}
+
+func verifyMinVersion(name string, version string, minVersion string) error {
+	if minVersion == "" {
+		return nil
+	}
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s version %s", name, version)
+	}
+	min, err := semver.ParseTolerant(minVersion)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse minimum %s version %s", name, minVersion)
+	}
+	// ignore any pre-release such as v1.15.9-gke.24
+	v.Pre = nil
+	if v.LT(min) {
+		return errors.Errorf("%s version %s is older than the minimum version %s", name, version, minVersion)
+	}
+	return nil
+}
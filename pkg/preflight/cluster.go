@@ -0,0 +1,116 @@
+package preflight
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// MinKubeVersion the minimum supported kubernetes version
+	MinKubeVersion = "1.13.0"
+
+	// annotationDefaultStorageClass the annotation marking the default StorageClass
+	annotationDefaultStorageClass = "storageclass.kubernetes.io/is-default-class"
+
+	// annotationBetaDefaultStorageClass the beta annotation marking the default StorageClass
+	annotationBetaDefaultStorageClass = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+// VerifyDefaultStorageClass verifies there is a default StorageClass so that PersistentVolumeClaims can be bound
+func VerifyDefaultStorageClass(kubeClient kubernetes.Interface) (string, error) {
+	list, err := kubeClient.StorageV1().StorageClasses().List(metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list StorageClasses")
+	}
+	for _, sc := range list.Items {
+		for _, a := range []string{annotationDefaultStorageClass, annotationBetaDefaultStorageClass} {
+			if sc.Annotations[a] == "true" {
+				return fmt.Sprintf("the default StorageClass is %s", sc.Name), nil
+			}
+		}
+	}
+	return "", errors.Errorf("there is no default StorageClass")
+}
+
+// VerifyLoadBalancer verifies that LoadBalancer services are supported. If there are any LoadBalancer services
+// they must have an address otherwise the cloud provider is used to decide if they are supported
+func VerifyLoadBalancer(kubeClient kubernetes.Interface, supportedProvider bool) (string, error) {
+	list, err := kubeClient.CoreV1().Services("").List(metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list Services")
+	}
+	var pending []string
+	for _, svc := range list.Items {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		if len(svc.Status.LoadBalancer.Ingress) > 0 {
+			return fmt.Sprintf("the LoadBalancer Service %s in namespace %s has an address", svc.Name, svc.Namespace), nil
+		}
+		pending = append(pending, svc.Namespace+"/"+svc.Name)
+	}
+	if len(pending) > 0 {
+		sort.Strings(pending)
+		return "", errors.Errorf("the LoadBalancer Services have no address: %s", strings.Join(pending, ", "))
+	}
+	if !supportedProvider {
+		return "", errors.Errorf("the cluster provider may not support LoadBalancer Services")
+	}
+	return "the cluster provider supports LoadBalancer Services", nil
+}
+
+// VerifyCRDs verifies at least one of the given CustomResourceDefinitions exists
+func VerifyCRDs(client apiextensionsclientset.Interface, names ...string) (string, error) {
+	for _, name := range names {
+		_, err := client.ApiextensionsV1beta1().CustomResourceDefinitions().Get(name, metav1.GetOptions{})
+		if err == nil {
+			return fmt.Sprintf("found CustomResourceDefinition %s", name), nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return "", errors.Wrapf(err, "failed to get CustomResourceDefinition %s", name)
+		}
+	}
+	return "", errors.Errorf("missing CustomResourceDefinition %s", strings.Join(names, " or "))
+}
+
+// VerifyNodeCapacity verifies the ready nodes have at least the given allocatable CPU and memory in total
+func VerifyNodeCapacity(kubeClient kubernetes.Interface, minCPU resource.Quantity, minMemory resource.Quantity) (string, error) {
+	list, err := kubeClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list Nodes")
+	}
+	cpu := resource.Quantity{}
+	memory := resource.Quantity{}
+	count := 0
+	for _, node := range list.Items {
+		if !isNodeReady(&node) {
+			continue
+		}
+		count++
+		cpu.Add(node.Status.Allocatable[corev1.ResourceCPU])
+		memory.Add(node.Status.Allocatable[corev1.ResourceMemory])
+	}
+	message := fmt.Sprintf("%d ready nodes with %s CPU and %s memory", count, cpu.String(), memory.String())
+	if cpu.Cmp(minCPU) < 0 || memory.Cmp(minMemory) < 0 {
+		return "", errors.Errorf("%s but at least %s CPU and %s memory are required", message, minCPU.String(), minMemory.String())
+	}
+	return message, nil
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
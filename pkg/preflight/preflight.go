@@ -0,0 +1,96 @@
+package preflight
+
+import (
+	"io"
+
+	"github.com/jenkins-x/jx/pkg/table"
+	"github.com/jenkins-x/jx/pkg/util"
+)
+
+// Check a pre-flight check which returns a message describing what was checked or an error if it failed
+type Check struct {
+	Name string
+	Run  func() (string, error)
+
+	// Hint an optional hint on how to fix the failure
+	Hint string
+
+	// Warning if the check fails it is reported as a warning rather than a failure
+	Warning bool
+}
+
+// Result the result of a pre-flight check
+type Result struct {
+	Name    string
+	Message string
+	Error   error
+	Hint    string
+	Warning bool
+}
+
+// Passed returns true if the check passed
+func (r *Result) Passed() bool {
+	return r.Error == nil
+}
+
+// RunChecks runs all of the checks returning their results
+func RunChecks(checks []Check) []Result {
+	var answer []Result
+	for _, c := range checks {
+		message, err := c.Run()
+		answer = append(answer, Result{
+			Name:    c.Name,
+			Message: message,
+			Error:   err,
+			Hint:    c.Hint,
+			Warning: c.Warning,
+		})
+	}
+	return answer
+}
+
+// Failed returns the results which failed ignoring any warnings
+func Failed(results []Result) []Result {
+	var answer []Result
+	for _, r := range results {
+		if !r.Passed() && !r.Warning {
+			answer = append(answer, r)
+		}
+	}
+	return answer
+}
+
+// RenderTable renders the results as a pass/fail table including the hints of any failures or warnings
+func RenderTable(out io.Writer, results []Result) {
+	hints := false
+	for _, r := range results {
+		if !r.Passed() && r.Hint != "" {
+			hints = true
+		}
+	}
+	t := table.CreateTable(out)
+	if hints {
+		t.AddRow("CHECK", "STATUS", "MESSAGE", "HINT")
+	} else {
+		t.AddRow("CHECK", "STATUS", "MESSAGE")
+	}
+	for _, r := range results {
+		status := util.ColorInfo("PASS")
+		message := r.Message
+		hint := ""
+		if !r.Passed() {
+			status = util.ColorError("FAIL")
+			if r.Warning {
+				status = util.ColorWarning("WARN")
+			}
+			message = r.Error.Error()
+			hint = r.Hint
+		}
+		if hints {
+			t.AddRow(r.Name, status, message, hint)
+		} else {
+			t.AddRow(r.Name, status, message)
+		}
+	}
+	t.Render()
+}
@@ -0,0 +1,298 @@
+package reqhelpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// AppRulesFileName the optional file in the development environment git repository or version stream
+	// containing additional rules for adding or removing apps
+	AppRulesFileName = "jx-app-rules.yml"
+
+	// VersionStreamDirName the directory in the development environment git repository containing the version stream
+	VersionStreamDirName = "versionStream"
+)
+
+// AppRules the rules for adding or removing apps based on the requirements
+type AppRules struct {
+	Rules []AppRule `json:"rules,omitempty"`
+}
+
+// AppRule a declarative rule which adds or removes apps if its condition matches the requirements
+type AppRule struct {
+	// Name the unique name of the rule. A rule with the same name as an earlier rule replaces it
+	Name string `json:"name"`
+	// Description the optional description of why the rule exists
+	Description string `json:"description,omitempty"`
+	// Disabled disables an earlier rule with the same name
+	Disabled bool `json:"disabled,omitempty"`
+	// When the condition on the requirements. An empty condition always matches
+	When AppRuleCondition `json:"when,omitempty"`
+	// Remove the names of the apps to remove
+	Remove []string `json:"remove,omitempty"`
+	// Add the apps to add
+	Add []AppRuleAdd `json:"add,omitempty"`
+}
+
+// AppRuleAdd an app added by a rule
+type AppRuleAdd struct {
+	// Name the name of the app chart
+	Name string `json:"name"`
+	// Before the optional app to add the app before. If not present the app is added at the end
+	Before string `json:"before,omitempty"`
+}
+
+// AppRuleCondition a condition on the requirements
+type AppRuleCondition struct {
+	// Field the dotted path of a field in jx-requirements.yml such as cluster.provider or ingress.tls.enabled
+	Field string `json:"field,omitempty"`
+	// In matches if the value of the field is one of these values. A missing field has the value ""
+	In []string `json:"in,omitempty"`
+	// NotIn matches if the value of the field is not one of these values
+	NotIn []string `json:"notIn,omitempty"`
+	// All matches if all of the conditions match
+	All []AppRuleCondition `json:"all,omitempty"`
+	// Any matches if any of the conditions match
+	Any []AppRuleCondition `json:"any,omitempty"`
+}
+
+// AppChange describes an app added or removed by a rule
+type AppChange struct {
+	Action string
+	App    string
+	Rule   *AppRule
+}
+
+// String returns the explanation of the change
+func (c *AppChange) String() string {
+	reason := c.Rule.When.String()
+	if c.Rule.Description != "" {
+		reason = c.Rule.Description
+	}
+	return fmt.Sprintf("%s %s due to rule %s: %s", c.Action, c.App, c.Rule.Name, reason)
+}
+
+// BuiltInAppRules returns the built in rules for adding or removing apps
+func BuiltInAppRules() []AppRule {
+	return []AppRule{
+		{
+			Name:        "nexus",
+			Description: "nexus is only used if the repository is nexus",
+			When:        AppRuleCondition{Field: "repository", NotIn: []string{string(config.RepositoryTypeNexus)}},
+			Remove:      []string{"jenkins-x/nexus"},
+		},
+		{
+			Name:        "bucketrepo",
+			Description: "bucketrepo replaces chartmuseum if the repository is bucketrepo",
+			When:        AppRuleCondition{Field: "repository", In: []string{string(config.RepositoryTypeBucketRepo)}},
+			Remove:      []string{"jenkins-x/chartmuseum"},
+			Add:         []AppRuleAdd{{Name: "jenkins-x/bucketrepo", Before: "repositories"}},
+		},
+		{
+			Name:        "istio",
+			Description: "istio replaces nginx if the ingress kind is istio",
+			When:        AppRuleCondition{Field: "ingress.kind", In: []string{string(config.IngressTypeIstio)}},
+			Remove:      []string{"stable/nginx-ingress"},
+			Add:         []AppRuleAdd{{Name: "jx-labs/istio", Before: "jenkins-x/jxboot-helmfile-resources"}},
+		},
+		{
+			Name:        "docker-registry",
+			Description: "a docker registry is required for the kubernetes provider",
+			When:        AppRuleCondition{Field: "cluster.provider", In: []string{"kubernetes"}},
+			Add:         []AppRuleAdd{{Name: "stable/docker-registry", Before: "jenkins-x/jxboot-helmfile-resources"}},
+		},
+		{
+			Name:        "cert-manager",
+			Description: "cert-manager and acme are required if TLS is enabled without a custom TLS secret",
+			When: AppRuleCondition{
+				All: []AppRuleCondition{
+					{Field: "ingress.tls.enabled", In: []string{"true"}},
+					{Field: "ingress.tls.secretName", In: []string{""}},
+				},
+			},
+			Add: []AppRuleAdd{
+				{Name: "jetstack/cert-manager", Before: "jenkins-x/jxboot-helmfile-resources"},
+				{Name: "jenkins-x/acme", Before: "jenkins-x/jxboot-helmfile-resources"},
+			},
+		},
+	}
+}
+
+// LoadAppRules loads the built in rules then any rules in the version stream and development environment directories
+func LoadAppRules(versionStreamDir string, dir string) ([]AppRule, error) {
+	rules := BuiltInAppRules()
+	for _, d := range []string{versionStreamDir, dir} {
+		if d == "" {
+			continue
+		}
+		fileName := filepath.Join(d, AppRulesFileName)
+		exists, err := util.FileExists(fileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check if file exists %s", fileName)
+		}
+		if !exists {
+			continue
+		}
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load file %s", fileName)
+		}
+		appRules := &AppRules{}
+		err = yaml.Unmarshal(data, appRules)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal YAML file %s", fileName)
+		}
+		for _, r := range appRules.Rules {
+			if r.Name == "" {
+				return nil, errors.Errorf("missing rule name in file %s", fileName)
+			}
+			rules = mergeAppRule(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// mergeAppRule replaces the rule with the same name or appends it
+func mergeAppRule(rules []AppRule, rule AppRule) []AppRule {
+	for i := range rules {
+		if rules[i].Name == rule.Name {
+			if rule.Disabled {
+				return append(rules[0:i], rules[i+1:]...)
+			}
+			rules[i] = rule
+			return rules
+		}
+	}
+	if rule.Disabled {
+		return rules
+	}
+	return append(rules, rule)
+}
+
+// ApplyAppRules applies the rules to the apps returning the changes
+func ApplyAppRules(rules []AppRule, requirements *config.RequirementsConfig, apps *config.AppConfig) ([]AppChange, error) {
+	values, err := toValues(requirements)
+	if err != nil {
+		return nil, err
+	}
+	var changes []AppChange
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.When.Matches(values) {
+			continue
+		}
+		for _, name := range rule.Remove {
+			if removeApp(apps, name) {
+				changes = append(changes, AppChange{Action: "remove", App: name, Rule: rule})
+			}
+		}
+		for _, a := range rule.Add {
+			if addApp(apps, a.Name, a.Before) {
+				changes = append(changes, AppChange{Action: "add", App: a.Name, Rule: rule})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Matches returns true if the condition matches the requirements values
+func (c *AppRuleCondition) Matches(values map[string]interface{}) bool {
+	if c.Field != "" {
+		value := fieldValue(values, c.Field)
+		if len(c.In) > 0 && util.StringArrayIndex(c.In, value) < 0 {
+			return false
+		}
+		if len(c.NotIn) > 0 && util.StringArrayIndex(c.NotIn, value) >= 0 {
+			return false
+		}
+	}
+	for i := range c.All {
+		if !c.All[i].Matches(values) {
+			return false
+		}
+	}
+	if len(c.Any) > 0 {
+		for i := range c.Any {
+			if c.Any[i].Matches(values) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// String returns a description of the condition
+func (c *AppRuleCondition) String() string {
+	var parts []string
+	if c.Field != "" {
+		if len(c.In) > 0 {
+			parts = append(parts, fmt.Sprintf("%s in [%s]", c.Field, strings.Join(c.In, ", ")))
+		}
+		if len(c.NotIn) > 0 {
+			parts = append(parts, fmt.Sprintf("%s not in [%s]", c.Field, strings.Join(c.NotIn, ", ")))
+		}
+	}
+	for i := range c.All {
+		parts = append(parts, c.All[i].String())
+	}
+	if len(c.Any) > 0 {
+		var anyParts []string
+		for i := range c.Any {
+			anyParts = append(anyParts, c.Any[i].String())
+		}
+		parts = append(parts, "("+strings.Join(anyParts, " or ")+")")
+	}
+	if len(parts) == 0 {
+		return "always"
+	}
+	return strings.Join(parts, " and ")
+}
+
+// toValues converts the requirements into a generic map so that conditions can refer to their YAML field names
+func toValues(requirements *config.RequirementsConfig) (map[string]interface{}, error) {
+	data, err := json.Marshal(requirements)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the requirements")
+	}
+	values := map[string]interface{}{}
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the requirements")
+	}
+	return values, nil
+}
+
+// fieldValue returns the string value of the dotted path or "" if it does not exist
+func fieldValue(values map[string]interface{}, path string) string {
+	var value interface{} = values
+	for _, name := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[name]
+	}
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,63 @@
+package reqhelpers_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAppsWithRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-app-rules-")
+	require.NoError(t, err, "failed to create temp dir")
+	err = util.CopyDirOverwrite(filepath.Join("test_data", "app-rules"), dir)
+	require.NoError(t, err, "failed to copy test data to %s", dir)
+
+	requirements, _, err := reqhelpers.LoadRequirements(dir, "")
+	require.NoError(t, err, "failed to load requirements in dir %s", dir)
+
+	apps, _, changes, err := reqhelpers.ValidateAppsWithRules(dir, requirements, "")
+	require.NoError(t, err, "failed to validate apps in dir %s", dir)
+
+	var explanations []string
+	for i := range changes {
+		explanations = append(explanations, changes[i].String())
+	}
+	t.Logf("explanations: %#v\n", explanations)
+
+	expectedExplanations := []string{
+		"remove stable/nginx-ingress due to rule internal-ingress: on premise clusters use the internal ingress controller",
+		"add myorg/internal-ingress due to rule internal-ingress: on premise clusters use the internal ingress controller",
+		"add myorg/monitoring due to rule monitoring: cluster.provider in [onprem] and repository not in [bucketrepo]",
+	}
+	assert.Equal(t, expectedExplanations, explanations, "explanations")
+
+	var names []string
+	for _, a := range apps.Apps {
+		names = append(names, a.Name)
+	}
+	expectedNames := []string{
+		"myorg/internal-ingress",
+		"jenkins-x/jxboot-helmfile-resources",
+		"jenkins-x/chartmuseum",
+		"jenkins-x/nexus",
+		"jenkins-x/lighthouse",
+		"myorg/monitoring",
+	}
+	assert.Equal(t, expectedNames, names, "app names")
+
+	// the modified apps should have been saved
+	saved, _, err := config.LoadAppConfig(dir)
+	require.NoError(t, err, "failed to load apps in dir %s", dir)
+	assert.Len(t, saved.Apps, len(expectedNames), "saved apps")
+
+	// validating again should not change anything
+	_, _, changes, err = reqhelpers.ValidateAppsWithRules(dir, requirements, "")
+	require.NoError(t, err, "failed to validate apps again in dir %s", dir)
+	assert.Empty(t, changes, "should not have changed the apps again")
+}
@@ -0,0 +1,188 @@
+package reqhelpers
+
+import (
+	"io/ioutil"
+
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// GetBootJobValues generates the helm values for the boot Job chart from the requirements, the git URL, the optional
+// requirements overlay and an optional job values file which can override the Job resources, image, node selectors
+// and tolerations
+func GetBootJobValues(requirements *config.RequirementsConfig, gitURL string, overlay string, jobValuesFile string) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(requirements)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal requirements to YAML")
+	}
+	reqValues := map[string]interface{}{}
+	err = yaml.Unmarshal(data, &reqValues)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal requirements YAML")
+	}
+	if gitURL != "" {
+		reqValues["bootConfigURL"] = gitURL
+	}
+
+	answer := map[string]interface{}{
+		"jxRequirements": reqValues,
+	}
+	if overlay != "" {
+		answer["requirementsOverlay"] = overlay
+	}
+	if jobValuesFile == "" {
+		return answer, nil
+	}
+
+	jobValues, err := LoadJobValues(jobValuesFile)
+	if err != nil {
+		return nil, err
+	}
+	MergeValues(answer, jobValues)
+	return answer, nil
+}
+
+// LoadJobValues loads the job values file
+func LoadJobValues(fileName string) (map[string]interface{}, error) {
+	exists, err := util.FileExists(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if file exists %s", fileName)
+	}
+	if !exists {
+		return nil, errors.Errorf("job values file %s does not exist", fileName)
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load job values file %s", fileName)
+	}
+	answer := map[string]interface{}{}
+	err = yaml.Unmarshal(data, &answer)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal job values file %s", fileName)
+	}
+	return answer, nil
+}
+
+// MergeValues recursively merges the overrides into the values
+func MergeValues(values map[string]interface{}, overrides map[string]interface{}) {
+	for k, v := range overrides {
+		overrideMap, ok := v.(map[string]interface{})
+		if ok {
+			valuesMap, ok := values[k].(map[string]interface{})
+			if ok {
+				MergeValues(valuesMap, overrideMap)
+				continue
+			}
+		}
+		values[k] = v
+	}
+}
+
+// WriteBootJobValuesFile writes the boot Job values to a temporary file returning the file name
+func WriteBootJobValuesFile(values map[string]interface{}) (string, error) {
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal boot Job values to YAML")
+	}
+	f, err := ioutil.TempFile("", "jx-boot-values-*.yaml")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create temporary values file")
+	}
+	fileName := f.Name()
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return fileName, errors.Wrapf(err, "failed to write boot Job values file %s", fileName)
+	}
+	err = f.Close()
+	if err != nil {
+		return fileName, errors.Wrapf(err, "failed to close boot Job values file %s", fileName)
+	}
+	return fileName, nil
+}
+
+// BootJobValues the boot Job values generated by GetBootJobValues which are used to create the boot Job
+type BootJobValues struct {
+	// JXRequirements the requirements values
+	JXRequirements map[string]interface{} `json:"jxRequirements,omitempty"`
+	// Image the container image of the boot Job
+	Image ImageValues `json:"image,omitempty"`
+	// Resources the resources of the boot Job container
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector the node selector of the boot Job pod
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations the tolerations of the boot Job pod
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// ClusterRole the ClusterRole bound to the boot ServiceAccount
+	ClusterRole string `json:"clusterRole,omitempty"`
+	// RequirementsOverlay the requirements overlay passed into the boot Job as $JX_REQUIREMENTS_OVERLAY
+	RequirementsOverlay string `json:"requirementsOverlay,omitempty"`
+}
+
+// ImageValues the image values
+type ImageValues struct {
+	Repository string `json:"repository,omitempty"`
+	Tag        string `json:"tag,omitempty"`
+}
+
+// ToBootJobValues converts the boot Job values into the typed values
+func ToBootJobValues(values map[string]interface{}) (*BootJobValues, error) {
+	answer := &BootJobValues{}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return answer, errors.Wrap(err, "failed to marshal boot Job values")
+	}
+	err = yaml.Unmarshal(data, answer)
+	if err != nil {
+		return answer, errors.Wrap(err, "failed to unmarshal boot Job values")
+	}
+	return answer, nil
+}
+
+// requirementsEnvVars the environment variables used to override the requirements inside the boot Job and the
+// path of the requirements value they are populated from
+var requirementsEnvVars = []struct {
+	Path string
+	Name string
+}{
+	{"cluster.provider", "JX_REQUIREMENT_PROVIDER"},
+	{"cluster.project", "JX_REQUIREMENT_PROJECT"},
+	{"cluster.clusterName", "JX_REQUIREMENT_CLUSTER_NAME"},
+	{"cluster.namespace", "JX_REQUIREMENT_NAMESPACE"},
+	{"cluster.region", "JX_REQUIREMENT_REGION"},
+	{"cluster.zone", "JX_REQUIREMENT_ZONE"},
+	{"cluster.environmentGitOwner", "JX_REQUIREMENT_ENV_GIT_OWNER"},
+	{"cluster.environmentGitPublic", "JX_REQUIREMENT_ENV_GIT_PUBLIC"},
+	{"cluster.gitPublic", "JX_REQUIREMENT_GIT_PUBLIC"},
+	{"cluster.gitServer", "JX_REQUIREMENT_GIT_SERVER"},
+	{"cluster.gitKind", "JX_REQUIREMENT_GIT_KIND"},
+	{"cluster.externalDNSSAName", "JX_REQUIREMENT_EXTERNALDNS_SA"},
+	{"cluster.kanikoSAName", "JX_REQUIREMENT_KANIKO_SA"},
+	{"velero.serviceAccount", "JX_REQUIREMENT_VELERO_SA"},
+	{"vault.serviceAccount", "JX_REQUIREMENT_VAULT_SA"},
+	{"ingress.domain", "JX_REQUIREMENT_DOMAIN"},
+	{"ingress.tls.production", "JX_REQUIREMENT_INGRESS_TLS_PRODUCTION"},
+	{"repository", "JX_REQUIREMENT_REPOSITORY"},
+	{"secretStorage", "JX_REQUIREMENT_SECRET_STORAGE_TYPE"},
+	{"webhook", "JX_REQUIREMENT_WEBHOOK"},
+	{"kaniko", "JX_REQUIREMENT_KANIKO"},
+	{"storage.logs.url", "JX_REQUIREMENT_STORAGE_LOGS_URL"},
+	{"storage.reports.url", "JX_REQUIREMENT_STORAGE_REPORTS_URL"},
+	{"storage.repository.url", "JX_REQUIREMENT_STORAGE_REPOSITORY_URL"},
+	{"storage.backup.url", "JX_REQUIREMENT_STORAGE_BACKUP_URL"},
+}
+
+// RequirementsEnvVars returns the environment variables used to pass the requirements values into the boot Job
+func RequirementsEnvVars(values map[string]interface{}) map[string]string {
+	answer := map[string]string{}
+	for _, e := range requirementsEnvVars {
+		value := fieldValue(values, e.Path)
+		if value != "" {
+			answer[e.Name] = value
+		}
+	}
+	return answer
+}
@@ -0,0 +1,92 @@
+package reqhelpers_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
+	"github.com/jenkins-x-labs/helmboot/pkg/testhelpers"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGetBootJobValues(t *testing.T) {
+	t.Parallel()
+
+	gitURL := "https://github.com/myorg/environment-mycluster-dev.git"
+	sourceDir := filepath.Join("test_data", "bootjob-values")
+
+	testDirs, err := ioutil.ReadDir(sourceDir)
+	require.NoError(t, err, "failed to read dir %s", sourceDir)
+	for _, d := range testDirs {
+		name := d.Name()
+		if !d.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		testDir := filepath.Join(sourceDir, name)
+
+		requirements, err := config.LoadRequirementsConfigFile(filepath.Join(testDir, config.RequirementsConfigFileName))
+		require.NoError(t, err, "failed to load requirements for test %s", name)
+
+		jobValuesFile := filepath.Join(testDir, "job-values.yml")
+		exists, err := util.FileExists(jobValuesFile)
+		require.NoError(t, err, "failed to check file exists %s", jobValuesFile)
+		if !exists {
+			jobValuesFile = ""
+		}
+
+		values, err := reqhelpers.GetBootJobValues(requirements, gitURL, "", jobValuesFile)
+		require.NoError(t, err, "failed to generate values for test %s", name)
+
+		data, err := yaml.Marshal(values)
+		require.NoError(t, err, "failed to marshal values for test %s", name)
+
+		expectedFile := filepath.Join(testDir, "expected-values.yml")
+		want, err := ioutil.ReadFile(expectedFile)
+		require.NoError(t, err, "failed to load %s", expectedFile)
+
+		testhelpers.AssertYamlEqual(t, string(want), string(data), "generated values for test %s", name)
+	}
+}
+
+func TestGetBootJobCommandWithValuesFile(t *testing.T) {
+	t.Parallel()
+
+	requirements := config.NewRequirementsConfig()
+	c := reqhelpers.GetBootJobCommand(requirements, "https://github.com/myorg/env.git", "jx-labs/jxl-boot", "1.2.3", "/tmp/values.yaml")
+
+	assert.Equal(t, "helm", c.Name, "command name")
+	assert.Equal(t, []string{"install", "jx-boot", "-f", "/tmp/values.yaml", "--version", "1.2.3", "jx-labs/jxl-boot"}, c.Args, "command arguments")
+}
+
+func TestToBootJobValues(t *testing.T) {
+	t.Parallel()
+
+	testDir := filepath.Join("test_data", "bootjob-values", "custom")
+	requirements, err := config.LoadRequirementsConfigFile(filepath.Join(testDir, config.RequirementsConfigFileName))
+	require.NoError(t, err, "failed to load requirements")
+
+	values, err := reqhelpers.GetBootJobValues(requirements, "", "", filepath.Join(testDir, "job-values.yml"))
+	require.NoError(t, err, "failed to generate values")
+
+	jobValues, err := reqhelpers.ToBootJobValues(values)
+	require.NoError(t, err, "failed to convert values")
+
+	assert.Equal(t, "0.0.123", jobValues.Image.Tag, "image tag")
+	assert.Equal(t, "boot", jobValues.NodeSelector["cloud.google.com/gke-nodepool"], "node selector")
+	require.Len(t, jobValues.Tolerations, 1, "tolerations")
+	assert.Equal(t, "1Gi", jobValues.Resources.Limits.Memory().String(), "memory limit")
+
+	env := reqhelpers.RequirementsEnvVars(jobValues.JXRequirements)
+	assert.Equal(t, "gke", env["JX_REQUIREMENT_PROVIDER"], "provider")
+	assert.Equal(t, "myclustername", env["JX_REQUIREMENT_CLUSTER_NAME"], "cluster name")
+	assert.Equal(t, "europe-west1", env["JX_REQUIREMENT_REGION"], "region from the job values")
+	assert.Equal(t, "myproject", env["JX_REQUIREMENT_PROJECT"], "project")
+	assert.Equal(t, "us-east1-c", env["JX_REQUIREMENT_ZONE"], "zone")
+	assert.NotContains(t, env, "JX_REQUIREMENT_EXTERNALDNS_SA", "empty values should be omitted")
+}
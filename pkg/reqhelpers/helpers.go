@@ -4,10 +4,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
 	"github.com/jenkins-x/jx/pkg/client/clientset/versioned"
-	"github.com/jenkins-x/jx/pkg/cloud"
 	"github.com/jenkins-x/jx/pkg/config"
 	"github.com/jenkins-x/jx/pkg/gits"
 	"github.com/jenkins-x/jx/pkg/jxfactory"
@@ -40,10 +40,23 @@ func GetDevEnvironmentConfig(requirements *config.RequirementsConfig) *config.En
 	return nil
 }
 
-// GetBootJobCommand returns the boot job command
-func GetBootJobCommand(requirements *config.RequirementsConfig, gitURL string, chartName string, version string) util.Command {
+// GetBootJobCommand returns the boot job command. If a values file is specified it is used to pass the requirements
+// into the chart otherwise the main cluster values are passed via --set arguments
+func GetBootJobCommand(requirements *config.RequirementsConfig, gitURL string, chartName string, version string, valuesFile string) util.Command {
 	args := []string{"install", "jx-boot"}
 
+	if valuesFile != "" {
+		args = append(args, "-f", valuesFile)
+		if version != "" {
+			args = append(args, "--version", version)
+		}
+		args = append(args, chartName)
+		return util.Command{
+			Name: "helm",
+			Args: args,
+		}
+	}
+
 	provider := requirements.Cluster.Provider
 	if provider != "" {
 		args = append(args, "--set", fmt.Sprintf("jxRequirements.cluster.provider=%s", provider))
@@ -105,8 +118,9 @@ func GetRequirementsFromEnvironment(kubeClient kubernetes.Interface, jxClient ve
 	return devEnv, requirements, nil
 }
 
-// GetRequirementsFromGit clones the given git repository to get the requirements
-func GetRequirementsFromGit(gitURL string) (*config.RequirementsConfig, error) {
+// GetRequirementsFromGit clones the given git repository to get the requirements composing them from the
+// optional requirements overlay
+func GetRequirementsFromGit(gitURL string, overlay string) (*config.RequirementsConfig, error) {
 	tempDir, err := ioutil.TempDir("", "jx-boot-")
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create temp dir")
@@ -120,16 +134,19 @@ func GetRequirementsFromGit(gitURL string) (*config.RequirementsConfig, error) {
 		return nil, errors.Wrapf(err, "failed to git clone %s to dir %s", gitURL, tempDir)
 	}
 
-	requirements, _, err := config.LoadRequirementsConfig(tempDir)
+	requirements, _, err := LoadRequirements(tempDir, overlay)
 	if err != nil {
 		return requirements, errors.Wrapf(err, "failed to requirements YAML file from %s", tempDir)
 	}
 	return requirements, nil
 }
 
-// OverrideRequirements allows CLI overrides
-func OverrideRequirements(cmd *cobra.Command, args []string, dir string, outputRequirements *config.RequirementsConfig, flags *RequirementFlags) error {
-	requirements, fileName, err := config.LoadRequirementsConfig(dir)
+// OverrideRequirements allows CLI overrides of the requirements composed from the optional requirements overlay
+func OverrideRequirements(cmd *cobra.Command, args []string, dir string, overlay string, outputRequirements *config.RequirementsConfig, flags *RequirementFlags) error {
+	if overlay == "" {
+		overlay = os.Getenv(OverlayEnvVar)
+	}
+	requirements, fileName, err := loadRequirementsToOverride(dir, overlay)
 	if err != nil {
 		return err
 	}
@@ -159,6 +176,27 @@ func OverrideRequirements(cmd *cobra.Command, args []string, dir string, outputR
 		return errors.Wrap(err, "failed to reparse arguments")
 	}
 
+	// lets compose the requirements from the overlay of the cluster name specified on the CLI
+	clusterName := outputRequirements.Cluster.ClusterName
+	if overlay == "" && clusterName != "" && HasRequirementsOverlays(dir) {
+		exists, err := util.FileExists(RequirementsOverlayFile(dir, clusterName))
+		if err != nil {
+			return errors.Wrapf(err, "failed to check if the requirements overlay exists for cluster %s", clusterName)
+		}
+		if exists {
+			requirements, err = ComposeRequirements(dir, clusterName)
+			if err != nil {
+				return errors.Wrapf(err, "failed to compose the requirements for cluster %s", clusterName)
+			}
+			*outputRequirements = *requirements
+
+			err = cmd.Flags().Parse(args)
+			if err != nil {
+				return errors.Wrap(err, "failed to reparse arguments")
+			}
+		}
+	}
+
 	err = applyDefaults(cmd, outputRequirements, flags)
 	if err != nil {
 		return err
@@ -173,63 +211,44 @@ func OverrideRequirements(cmd *cobra.Command, args []string, dir string, outputR
 	return nil
 }
 
-// ValidateApps validates the apps match the requirements
-func ValidateApps(dir string) (*config.AppConfig, string, error) {
-	requirements, _, err := config.LoadRequirementsConfig(dir)
-	if err != nil {
-		return nil, "", err
-	}
+// ValidateAppsWithRules validates the apps match the given requirements of the directory using the built in rules and
+// any rules in the version stream and development environment directories returning the changes made to the apps.
+// The requirements are passed in as they may have been composed from a requirements overlay or overridden on the CLI.
+// If no version stream directory is specified the versionStream directory inside the given directory is used
+func ValidateAppsWithRules(dir string, requirements *config.RequirementsConfig, versionStreamDir string) (*config.AppConfig, string, []AppChange, error) {
 	apps, appsFileName, err := config.LoadAppConfig(dir)
-
-	modified := false
-	if requirements.Repository != config.RepositoryTypeNexus {
-		if removeApp(apps, "jenkins-x/nexus") {
-			modified = true
-		}
-	}
-	if requirements.Repository == config.RepositoryTypeBucketRepo {
-		if removeApp(apps, "jenkins-x/chartmuseum") {
-			modified = true
-		}
-		if addApp(apps, "jenkins-x/bucketrepo", "repositories") {
-			modified = true
-		}
+	if err != nil {
+		return apps, appsFileName, nil, err
 	}
-
-	if requirements.Ingress.Kind == config.IngressTypeIstio {
-		if removeApp(apps, "stable/nginx-ingress") {
-			modified = true
-		}
-		if addApp(apps, "jx-labs/istio", "jenkins-x/jxboot-helmfile-resources") {
-			modified = true
-		}
+	if versionStreamDir == "" {
+		versionStreamDir = filepath.Join(dir, VersionStreamDirName)
 	}
-
-	if requirements.Cluster.Provider == cloud.KUBERNETES {
-		if addApp(apps, "stable/docker-registry", "jenkins-x/jxboot-helmfile-resources") {
-			modified = true
-		}
+	rules, err := LoadAppRules(versionStreamDir, dir)
+	if err != nil {
+		return apps, appsFileName, nil, errors.Wrapf(err, "failed to load the app rules")
 	}
-
-	if shouldHaveCertManager(requirements) {
-		if addApp(apps, "jetstack/cert-manager", "jenkins-x/jxboot-helmfile-resources") {
-			modified = true
-		}
-		if addApp(apps, "jenkins-x/acme", "jenkins-x/jxboot-helmfile-resources") {
-			modified = true
-		}
+	changes, err := ApplyAppRules(rules, requirements, apps)
+	if err != nil {
+		return apps, appsFileName, changes, errors.Wrapf(err, "failed to apply the app rules")
 	}
-	if modified {
+	if len(changes) > 0 {
 		err = apps.SaveConfig(appsFileName)
 		if err != nil {
-			return apps, appsFileName, errors.Wrapf(err, "failed to save modified file %s", appsFileName)
+			return apps, appsFileName, changes, errors.Wrapf(err, "failed to save modified file %s", appsFileName)
 		}
 	}
-	return apps, appsFileName, err
+	return apps, appsFileName, changes, nil
 }
 
-func shouldHaveCertManager(requirements *config.RequirementsConfig) bool {
-	return requirements.Ingress.TLS.Enabled && requirements.Ingress.TLS.SecretName == ""
+// ExplainAppChanges logs why each app was added or removed by the app rules
+func ExplainAppChanges(changes []AppChange) {
+	if len(changes) == 0 {
+		log.Logger().Infof("no apps were added or removed by the app rules")
+		return
+	}
+	for i := range changes {
+		log.Logger().Infof("%s", changes[i].String())
+	}
 }
 
 func addApp(apps *config.AppConfig, chartName string, beforeName string) bool {
@@ -356,15 +375,34 @@ func defaultStorage(storage *config.StorageEntryConfig) {
 	}
 }
 
+// loadRequirementsToOverride loads the requirements to be overridden by the CLI arguments. If there are requirements
+// overlays but no overlay is chosen yet and no jx-requirements.yml file then the base requirements are used as the
+// overlay may be chosen by the cluster name on the CLI
+func loadRequirementsToOverride(dir string, overlay string) (*config.RequirementsConfig, string, error) {
+	fileName := filepath.Join(dir, config.RequirementsConfigFileName)
+	if overlay == "" && HasRequirementsOverlays(dir) {
+		exists, err := util.FileExists(fileName)
+		if err != nil {
+			return nil, fileName, errors.Wrapf(err, "failed to check if file exists %s", fileName)
+		}
+		if !exists {
+			requirements, err := ComposeRequirements(dir, "")
+			return requirements, fileName, err
+		}
+	}
+	return LoadRequirements(dir, overlay)
+}
+
 // FindRequirementsAndGitURL tries to find the requirements and git URL via either environment or directory
-func FindRequirementsAndGitURL(jxFactory jxfactory.Factory, gitURLOption string, gitter gits.Gitter, dir string) (*config.RequirementsConfig, string, error) {
+// composing the requirements from the optional requirements overlay
+func FindRequirementsAndGitURL(jxFactory jxfactory.Factory, gitURLOption string, gitter gits.Gitter, dir string, overlay string) (*config.RequirementsConfig, string, error) {
 	var requirements *config.RequirementsConfig
 	gitURL := gitURLOption
 
 	var err error
 	if gitURLOption != "" {
 		if requirements == nil {
-			requirements, err = GetRequirementsFromGit(gitURL)
+			requirements, err = GetRequirementsFromGit(gitURL, overlay)
 			if err != nil {
 				return requirements, gitURL, errors.Wrapf(err, "failed to get requirements from git URL %s", gitURL)
 			}
@@ -390,7 +428,7 @@ func FindRequirementsAndGitURL(jxFactory jxfactory.Factory, gitURLOption string,
 		}
 	}
 	if requirements == nil {
-		requirements, _, err = config.LoadRequirementsConfig(dir)
+		requirements, _, err = LoadRequirements(dir, overlay)
 		if err != nil {
 			return requirements, gitURL, err
 		}
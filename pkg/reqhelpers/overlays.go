@@ -0,0 +1,223 @@
+package reqhelpers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// RequirementsDir the directory containing the base requirements and the cluster overlays
+	RequirementsDir = "requirements"
+
+	// RequirementsBaseFileName the base requirements file name inside the requirements directory
+	RequirementsBaseFileName = "base.yml"
+
+	// RequirementsClustersDir the directory inside the requirements directory containing the cluster overlays
+	RequirementsClustersDir = "clusters"
+
+	// OverlayEnvVar the environment variable used to specify the name of the cluster overlay
+	OverlayEnvVar = "JX_REQUIREMENTS_OVERLAY"
+
+	// environmentsKey the key of the environments which are merged by their key
+	environmentsKey = "environments"
+
+	// patchKey the strategic merge directive used to remove an environment in an overlay
+	patchKey = "$patch"
+)
+
+// HasRequirementsOverlays returns true if the directory contains the base requirements file
+func HasRequirementsOverlays(dir string) bool {
+	exists, err := util.FileExists(filepath.Join(dir, RequirementsDir, RequirementsBaseFileName))
+	return err == nil && exists
+}
+
+// RequirementsOverlayFile returns the file name of the cluster overlay
+func RequirementsOverlayFile(dir string, overlay string) string {
+	return filepath.Join(dir, RequirementsDir, RequirementsClustersDir, overlay+".yml")
+}
+
+// RequirementsOverlayNames returns the names of the cluster overlays in the given directory
+func RequirementsOverlayNames(dir string) ([]string, error) {
+	clustersDir := filepath.Join(dir, RequirementsDir, RequirementsClustersDir)
+	exists, err := util.DirExists(clustersDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if dir exists %s", clustersDir)
+	}
+	if !exists {
+		return nil, nil
+	}
+	files, err := ioutil.ReadDir(clustersDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read dir %s", clustersDir)
+	}
+	var names []string
+	for _, f := range files {
+		name := f.Name()
+		if !f.IsDir() && strings.HasSuffix(name, ".yml") {
+			names = append(names, strings.TrimSuffix(name, ".yml"))
+		}
+	}
+	return names, nil
+}
+
+// LoadRequirements loads the requirements in the given directory.
+//
+// If the directory contains requirements/base.yml and an overlay is specified (or $JX_REQUIREMENTS_OVERLAY is set)
+// then the requirements are always composed from the base file and the requirements/clusters/$overlay.yml file
+// ignoring any jx-requirements.yml file. Otherwise the jx-requirements.yml file is loaded; if it does not exist
+// an error is returned as we cannot tell which overlay to use.
+// The file name returned is the jx-requirements.yml file the requirements should be saved to
+func LoadRequirements(dir string, overlay string) (*config.RequirementsConfig, string, error) {
+	if overlay == "" {
+		overlay = os.Getenv(OverlayEnvVar)
+	}
+	fileName := filepath.Join(dir, config.RequirementsConfigFileName)
+	if !HasRequirementsOverlays(dir) {
+		return config.LoadRequirementsConfig(dir)
+	}
+	if overlay == "" {
+		exists, err := util.FileExists(fileName)
+		if err != nil {
+			return nil, fileName, errors.Wrapf(err, "failed to check if file exists %s", fileName)
+		}
+		if !exists {
+			names, err := RequirementsOverlayNames(dir)
+			if err != nil {
+				return nil, fileName, err
+			}
+			return nil, fileName, errors.Errorf("no %s file in dir %s and no requirements overlay chosen. Please specify one of the overlays %s via --overlay or $%s",
+				config.RequirementsConfigFileName, dir, strings.Join(names, ", "), OverlayEnvVar)
+		}
+		return config.LoadRequirementsConfig(dir)
+	}
+	requirements, err := ComposeRequirements(dir, overlay)
+	return requirements, fileName, err
+}
+
+// ComposeRequirements composes the requirements from the base requirements and the optional cluster overlay
+func ComposeRequirements(dir string, overlay string) (*config.RequirementsConfig, error) {
+	baseFile := filepath.Join(dir, RequirementsDir, RequirementsBaseFileName)
+	values, err := loadYAMLValues(baseFile)
+	if err != nil {
+		return nil, err
+	}
+	if overlay != "" {
+		overlayFile := RequirementsOverlayFile(dir, overlay)
+		exists, err := util.FileExists(overlayFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check if file exists %s", overlayFile)
+		}
+		if !exists {
+			return nil, errors.Errorf("the requirements overlay %s does not exist", overlayFile)
+		}
+		overlayValues, err := loadYAMLValues(overlayFile)
+		if err != nil {
+			return nil, err
+		}
+		values = MergeRequirementsValues(values, overlayValues)
+		log.Logger().Debugf("composed the requirements from %s and %s", baseFile, overlayFile)
+	}
+
+	// lets load the composed requirements from a file so that the requirements defaults are applied
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the composed requirements")
+	}
+	tmpFile, err := ioutil.TempFile("", "jx-requirements-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temporary file")
+	}
+	tmpFileName := tmpFile.Name()
+	defer os.Remove(tmpFileName)
+	_, err = tmpFile.Write(data)
+	tmpFile.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to save file %s", tmpFileName)
+	}
+	requirements, err := config.LoadRequirementsConfigFile(tmpFileName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the composed requirements")
+	}
+	return requirements, nil
+}
+
+// MergeRequirementsValues merges the overlay into the base values. Maps are merged recursively, lists are replaced
+// other than the environments which are merged by their key. An overlay environment with $patch: delete removes it
+func MergeRequirementsValues(base map[string]interface{}, overlay map[string]interface{}) map[string]interface{} {
+	answer := mergeValues(base, overlay)
+	baseEnvs, ok1 := base[environmentsKey].([]interface{})
+	overlayEnvs, ok2 := overlay[environmentsKey].([]interface{})
+	if ok1 && ok2 {
+		answer[environmentsKey] = mergeEnvironments(baseEnvs, overlayEnvs)
+	}
+	return answer
+}
+
+func mergeValues(base map[string]interface{}, overlay map[string]interface{}) map[string]interface{} {
+	answer := map[string]interface{}{}
+	for k, v := range base {
+		answer[k] = v
+	}
+	for k, v := range overlay {
+		baseMap, ok1 := answer[k].(map[string]interface{})
+		overlayMap, ok2 := v.(map[string]interface{})
+		if ok1 && ok2 {
+			answer[k] = mergeValues(baseMap, overlayMap)
+			continue
+		}
+		answer[k] = v
+	}
+	return answer
+}
+
+func mergeEnvironments(base []interface{}, overlay []interface{}) []interface{} {
+	answer := append([]interface{}{}, base...)
+	for _, o := range overlay {
+		overlayEnv, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key := overlayEnv["key"]
+		idx := -1
+		for i, b := range answer {
+			baseEnv, ok := b.(map[string]interface{})
+			if ok && key != nil && baseEnv["key"] == key {
+				idx = i
+				break
+			}
+		}
+		if overlayEnv[patchKey] == "delete" {
+			if idx >= 0 {
+				answer = append(answer[0:idx], answer[idx+1:]...)
+			}
+			continue
+		}
+		if idx >= 0 {
+			answer[idx] = mergeValues(answer[idx].(map[string]interface{}), overlayEnv)
+		} else {
+			answer = append(answer, overlayEnv)
+		}
+	}
+	return answer
+}
+
+func loadYAMLValues(fileName string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load file %s", fileName)
+	}
+	values := map[string]interface{}{}
+	err = yaml.Unmarshal(data, &values)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal YAML file %s", fileName)
+	}
+	return values, nil
+}
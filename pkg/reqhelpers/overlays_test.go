@@ -0,0 +1,76 @@
+package reqhelpers_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/reqhelpers"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposeRequirements(t *testing.T) {
+	dir := filepath.Join("test_data", "overlays")
+	require.True(t, reqhelpers.HasRequirementsOverlays(dir), "should have overlays in dir %s", dir)
+
+	requirements, err := reqhelpers.ComposeRequirements(dir, "prod-eu")
+	require.NoError(t, err, "failed to compose requirements in dir %s", dir)
+
+	// values from the base
+	assert.Equal(t, "gke", requirements.Cluster.Provider, "requirements.Cluster.Provider")
+	assert.Equal(t, "myorg", requirements.Cluster.EnvironmentGitOwner, "requirements.Cluster.EnvironmentGitOwner")
+	assert.Equal(t, true, requirements.Ingress.TLS.Enabled, "requirements.Ingress.TLS.Enabled")
+	assert.Equal(t, "me@myorg.com", requirements.Ingress.TLS.Email, "requirements.Ingress.TLS.Email")
+
+	// values from the overlay
+	assert.Equal(t, "prod-eu", requirements.Cluster.ClusterName, "requirements.Cluster.ClusterName")
+	assert.Equal(t, "myproject-eu", requirements.Cluster.ProjectID, "requirements.Cluster.ProjectID")
+	assert.Equal(t, "europe-west1-b", requirements.Cluster.Zone, "requirements.Cluster.Zone")
+	assert.Equal(t, "eu.myorg.com", requirements.Ingress.Domain, "requirements.Ingress.Domain")
+	assert.Equal(t, config.RepositoryTypeBucketRepo, requirements.Repository, "requirements.Repository")
+
+	// environments are merged by key
+	var keys []string
+	for _, e := range requirements.Environments {
+		keys = append(keys, e.Key)
+	}
+	assert.Equal(t, []string{"dev", "production", "production-eu"}, keys, "environment keys")
+
+	production := requirements.Environments[1]
+	assert.Equal(t, "Auto", string(production.PromotionStrategy), "production.PromotionStrategy")
+	assert.Equal(t, true, production.RemoteCluster, "production.RemoteCluster")
+
+	_, err = reqhelpers.ComposeRequirements(dir, "does-not-exist")
+	require.Error(t, err, "should fail for a missing overlay")
+}
+
+func TestLoadRequirementsWithOverlay(t *testing.T) {
+	os.Unsetenv(reqhelpers.OverlayEnvVar)
+	dir := filepath.Join("test_data", "overlays")
+
+	names, err := reqhelpers.RequirementsOverlayNames(dir)
+	require.NoError(t, err, "failed to find the overlays in dir %s", dir)
+	assert.Equal(t, []string{"prod-eu"}, names, "overlay names")
+
+	requirements, fileName, err := reqhelpers.LoadRequirements(dir, "prod-eu")
+	require.NoError(t, err, "failed to load requirements in dir %s", dir)
+	assert.Equal(t, filepath.Join(dir, config.RequirementsConfigFileName), fileName, "requirements file name")
+	assert.Equal(t, "eu.myorg.com", requirements.Ingress.Domain, "requirements.Ingress.Domain")
+
+	os.Setenv(reqhelpers.OverlayEnvVar, "prod-eu")
+	defer os.Unsetenv(reqhelpers.OverlayEnvVar)
+	requirements, _, err = reqhelpers.LoadRequirements(dir, "")
+	require.NoError(t, err, "failed to load requirements in dir %s with $%s", dir, reqhelpers.OverlayEnvVar)
+	assert.Equal(t, "eu.myorg.com", requirements.Ingress.Domain, "requirements.Ingress.Domain with $%s", reqhelpers.OverlayEnvVar)
+}
+
+func TestLoadRequirementsWithoutOverlayOrRequirementsFile(t *testing.T) {
+	os.Unsetenv(reqhelpers.OverlayEnvVar)
+	dir := filepath.Join("test_data", "overlays")
+
+	_, _, err := reqhelpers.LoadRequirements(dir, "")
+	require.Error(t, err, "should fail when there is no overlay chosen and no %s in dir %s", config.RequirementsConfigFileName, dir)
+	assert.Contains(t, err.Error(), "prod-eu", "error should list the overlays")
+}
@@ -24,6 +24,9 @@ type KindResolver struct {
 	Dir     string
 	GitURL  string
 
+	// Overlay the optional requirements overlay used to compose the requirements
+	Overlay string
+
 	// outputs which can be useful
 	DevEnvironment *v1.Environment
 	Requirements   *config.RequirementsConfig
@@ -158,11 +161,11 @@ func (r *KindResolver) resolveRequirements(secretsYAML string) (*config.Requirem
 				return nil, "", errors.Wrap(err, "failed to enrich git URL with user and token from the secrets YAML")
 			}
 		}
-		requirements, err := reqhelpers.GetRequirementsFromGit(r.GitURL)
+		requirements, err := reqhelpers.GetRequirementsFromGit(r.GitURL, r.Overlay)
 		return requirements, ns, err
 	}
 
-	requirements, _, err := config.LoadRequirementsConfig(r.Dir)
+	requirements, _, err := reqhelpers.LoadRequirements(r.Dir, r.Overlay)
 	if err != nil {
 		return requirements, ns, errors.Wrapf(err, "failed to requirements YAML file from %s", r.Dir)
 	}
@@ -232,7 +235,7 @@ func (r *KindResolver) LoadBootRunGitURLFromSecret() (string, error) {
 	}
 
 	var answer []byte
-	if s.Data != nil {
+	if s != nil && s.Data != nil {
 		answer = s.Data[key]
 	}
 	if len(answer) == 0 {
@@ -2,6 +2,7 @@ package secretmgr
 
 import (
 	"fmt"
+	"io/ioutil"
 	"strings"
 
 	"github.com/jenkins-x-labs/helmboot/pkg/githelpers"
@@ -130,3 +131,38 @@ func UnmarshalSecretsYAML(secretsYaml string) (map[string]interface{}, error) {
 	RemoveMapEmptyValues(existing)
 	return existing, nil
 }
+
+// WriteDummySecretsYAML writes the default secrets YAML populated with dummy values to the given file so that
+// charts can be rendered without the real secrets
+func WriteDummySecretsYAML(fileName string) error {
+	values := map[string]interface{}{}
+	err := yaml.Unmarshal([]byte(DefaultSecretsYaml), &values)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal the default secrets YAML")
+	}
+	populateDummyValues(values)
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the dummy secrets YAML")
+	}
+	err = ioutil.WriteFile(fileName, data, util.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save dummy secrets at %s", fileName)
+	}
+	return nil
+}
+
+func populateDummyValues(values map[string]interface{}) {
+	for k, v := range values {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			populateDummyValues(t)
+		case nil:
+			values[k] = "dummy"
+		case string:
+			if t == "" {
+				values[k] = "dummy"
+			}
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
+// WriteSourceRepositoriesToGitFolder writes the SourceRepository resources to the output dir returning the files written
 func WriteSourceRepositoriesToGitFolder(outDir string, srList *v1.SourceRepositoryList) ([]string, error) {
 	exists, err := util.DirExists(outDir)
 	if err != nil {
@@ -23,6 +24,7 @@ func WriteSourceRepositoriesToGitFolder(outDir string, srList *v1.SourceReposito
 		return nil, fmt.Errorf("output dir %s does not exist", outDir)
 	}
 
+	var fileNames []string
 	for _, sr := range srList.Items {
 		labels := sr.Labels
 		if labels != nil {
@@ -40,10 +42,11 @@ func WriteSourceRepositoriesToGitFolder(outDir string, srList *v1.SourceReposito
 		fileName := filepath.Join(outDir, sr.Name+".yaml")
 		err = ioutil.WriteFile(fileName, data, util.DefaultWritePermissions)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to write file %s for SourceRepository %s to YAML", fileName, sr.Name)
+			return fileNames, errors.Wrapf(err, "failed to write file %s for SourceRepository %s to YAML", fileName, sr.Name)
 		}
+		fileNames = append(fileNames, fileName)
 	}
-	return nil, nil
+	return fileNames, nil
 }
 
 // EmptyObjectMeta lets return a clean ObjectMeta without any cluster or transient specific values
@@ -0,0 +1,162 @@
+package upgrader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// UpgradeReport captures the changes the upgrader made to the development environment git repository
+// so that they can be described in the Pull Request
+type UpgradeReport struct {
+	// RequirementsChanges the changes made to the jx-requirements.yml file
+	RequirementsChanges []string
+
+	// RemovedDirs the legacy helm 2.x folders which were removed
+	RemovedDirs []string
+
+	// AddedFiles the files and folders copied from the template git repository
+	AddedFiles []string
+
+	// SourceRepositoryCount the number of SourceRepository resources exported
+	SourceRepositoryCount int
+
+	// PipelineActivityCount the number of PipelineActivity resources exported
+	PipelineActivityCount int
+
+	// ResourceCounts the number of other resources exported indexed by kind
+	ResourceCounts map[string]int
+}
+
+// PullRequestBody returns the markdown body of the upgrade Pull Request
+func (r *UpgradeReport) PullRequestBody(binaryName string) string {
+	buf := &strings.Builder{}
+	buf.WriteString("This Pull Request upgrades the development environment to use helmfile and helm 3.\n")
+
+	buf.WriteString("\n### Requirements\n\n")
+	if len(r.RequirementsChanges) == 0 {
+		buf.WriteString("No changes to `jx-requirements.yml`\n")
+	} else {
+		buf.WriteString("The following changes were made to `jx-requirements.yml`:\n\n")
+		for _, c := range r.RequirementsChanges {
+			buf.WriteString(fmt.Sprintf("* %s\n", c))
+		}
+	}
+
+	if len(r.RemovedDirs) > 0 {
+		buf.WriteString("\n### Removed folders\n\n")
+		buf.WriteString("The following helm 2.x folders are no longer required:\n\n")
+		for _, d := range r.RemovedDirs {
+			buf.WriteString(fmt.Sprintf("* `%s`\n", d))
+		}
+	}
+
+	if len(r.AddedFiles) > 0 {
+		buf.WriteString("\n### Added files\n\n")
+		buf.WriteString("The following files were copied from the helmfile template repository:\n\n")
+		for _, f := range r.AddedFiles {
+			buf.WriteString(fmt.Sprintf("* `%s`\n", f))
+		}
+	}
+
+	buf.WriteString("\n### Migrated resources\n\n")
+	buf.WriteString(fmt.Sprintf("* %d SourceRepository resources\n", r.SourceRepositoryCount))
+	buf.WriteString(fmt.Sprintf("* %d PipelineActivity resources\n", r.PipelineActivityCount))
+	var kinds []string
+	for k := range r.ResourceCounts {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	for _, k := range kinds {
+		buf.WriteString(fmt.Sprintf("* %d %s resources\n", r.ResourceCounts[k], k))
+	}
+
+	buf.WriteString("\n### Next steps\n\n")
+	buf.WriteString("1. review the changes and merge this Pull Request\n")
+	buf.WriteString(fmt.Sprintf("2. populate the secrets via `%s secrets edit`\n", binaryName))
+	buf.WriteString(fmt.Sprintf("3. boot the cluster via `%s run`\n", binaryName))
+	return buf.String()
+}
+
+// RequirementsChanges returns a sorted description of the changes between the old and new requirements
+func RequirementsChanges(oldRequirements, newRequirements *config.RequirementsConfig) ([]string, error) {
+	oldValues, err := flattenRequirements(oldRequirements)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := flattenRequirements(newRequirements)
+	if err != nil {
+		return nil, err
+	}
+
+	var answer []string
+	for k, v := range newValues {
+		old, ok := oldValues[k]
+		if !ok {
+			answer = append(answer, fmt.Sprintf("added `%s: %s`", k, v))
+		} else if old != v {
+			answer = append(answer, fmt.Sprintf("changed `%s` from `%s` to `%s`", k, old, v))
+		}
+	}
+	for k, v := range oldValues {
+		if _, ok := newValues[k]; !ok {
+			answer = append(answer, fmt.Sprintf("removed `%s: %s`", k, v))
+		}
+	}
+	sort.Strings(answer)
+	return answer, nil
+}
+
+// flattenRequirements converts the requirements into a map of dotted paths to values
+func flattenRequirements(requirements *config.RequirementsConfig) (map[string]string, error) {
+	answer := map[string]string{}
+	if requirements == nil {
+		return answer, nil
+	}
+	data, err := yaml.Marshal(requirements)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal requirements to YAML")
+	}
+	values := map[string]interface{}{}
+	err = yaml.Unmarshal(data, &values)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal requirements YAML")
+	}
+	flattenValues(answer, "", values)
+	return answer, nil
+}
+
+func flattenValues(answer map[string]string, prefix string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			flattenValues(answer, joinPath(prefix, k), child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			key := fmt.Sprintf("%d", i)
+
+			// lets use the environment key to make paths more readable
+			m, ok := child.(map[string]interface{})
+			if ok && m["key"] != nil {
+				key = fmt.Sprintf("%v", m["key"])
+			}
+			flattenValues(answer, joinPath(prefix, key), child)
+		}
+	default:
+		if value != nil && value != "" {
+			answer[prefix] = fmt.Sprintf("%v", value)
+		}
+	}
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,54 @@
+package upgrader_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/upgrader"
+	"github.com/jenkins-x/jx/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequirementsChanges(t *testing.T) {
+	t.Parallel()
+
+	oldRequirements := config.NewRequirementsConfig()
+	oldRequirements.Cluster.ClusterName = "mycluster"
+	oldRequirements.Cluster.Zone = "us-east1-c"
+
+	newRequirements := config.NewRequirementsConfig()
+	newRequirements.Cluster.ClusterName = "mycluster"
+	newRequirements.Cluster.Region = "us-east1"
+	newRequirements.Helmfile = true
+
+	changes, err := upgrader.RequirementsChanges(oldRequirements, newRequirements)
+	require.NoError(t, err, "failed to compare requirements")
+
+	assert.Contains(t, changes, "added `cluster.region: us-east1`")
+	assert.Contains(t, changes, "added `helmfile: true`")
+	assert.Contains(t, changes, "removed `cluster.zone: us-east1-c`")
+	for _, c := range changes {
+		assert.NotContains(t, c, "clusterName", "unchanged values should not be reported")
+	}
+}
+
+func TestPullRequestBody(t *testing.T) {
+	t.Parallel()
+
+	r := &upgrader.UpgradeReport{
+		RequirementsChanges:   []string{"added `helmfile: true`"},
+		RemovedDirs:           []string{"env", "systems"},
+		AddedFiles:            []string{"apps/", "jx-apps.yml"},
+		SourceRepositoryCount: 3,
+		PipelineActivityCount: 7,
+	}
+	body := r.PullRequestBody("helmboot")
+	t.Logf("generated body:\n%s\n", body)
+
+	assert.Contains(t, body, "* added `helmfile: true`")
+	assert.Contains(t, body, "* `env`")
+	assert.Contains(t, body, "* `jx-apps.yml`")
+	assert.Contains(t, body, "* 3 SourceRepository resources")
+	assert.Contains(t, body, "* 7 PipelineActivity resources")
+	assert.Contains(t, body, "`helmboot run`")
+}
@@ -0,0 +1,238 @@
+package upgrader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx/pkg/kube"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// JenkinsAPIVersion the API version of the Jenkins X custom resources
+	JenkinsAPIVersion = "jenkins.io/v1"
+
+	// KindUser the kind of the User resources
+	KindUser = "User"
+
+	// KindTeam the kind of the Team resources
+	KindTeam = "Team"
+
+	// KindScheduler the kind of the Scheduler resources
+	KindScheduler = "Scheduler"
+
+	// KindApp the kind of the App resources
+	KindApp = "App"
+
+	// KindEnvironmentRoleBinding the kind of the EnvironmentRoleBinding resources
+	KindEnvironmentRoleBinding = "EnvironmentRoleBinding"
+
+	// KindEnvironment the kind of the Environment resources
+	KindEnvironment = "Environment"
+)
+
+var (
+	// DefaultMigrateKinds the kinds of resources which are migrated to git by default
+	DefaultMigrateKinds = []string{KindUser, KindTeam, KindScheduler, KindApp, KindEnvironmentRoleBinding}
+
+	// MigrateKindValues the kinds of resources which can be migrated to git
+	MigrateKindValues = []string{KindUser, KindTeam, KindScheduler, KindApp, KindEnvironmentRoleBinding, KindEnvironment}
+)
+
+// ResourceFilter filters the kinds of resources to migrate
+type ResourceFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Matches returns true if the given kind should be migrated
+func (f *ResourceFilter) Matches(kind string) bool {
+	for _, e := range f.Exclude {
+		if strings.EqualFold(e, kind) {
+			return false
+		}
+	}
+	for _, i := range f.Include {
+		if strings.EqualFold(i, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the include and exclude kinds are valid
+func (f *ResourceFilter) Validate() error {
+	for _, k := range f.Include {
+		if !isMigrateKind(k) {
+			return util.InvalidOption("include-kind", k, MigrateKindValues)
+		}
+	}
+	for _, k := range f.Exclude {
+		if !isMigrateKind(k) {
+			return util.InvalidOption("exclude-kind", k, MigrateKindValues)
+		}
+	}
+	return nil
+}
+
+func isMigrateKind(kind string) bool {
+	for _, k := range MigrateKindValues {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourceFolderName returns the folder name used to store resources of the given kind
+func ResourceFolderName(kind string) string {
+	return strings.ToLower(kind) + "s"
+}
+
+// WriteResourcesToGitFolder writes the given resources of the given kind as helm templates into the output dir
+// returning the files written. Resources with the gitSync label set to false are ignored and any template
+// expressions in the resources are escaped
+func WriteResourcesToGitFolder(outDir string, kind string, resources []metav1.Object) ([]string, error) {
+	var fileNames []string
+	for _, r := range resources {
+		labels := r.GetLabels()
+		if labels != nil {
+			if strings.ToLower(labels[kube.LabelGitSync]) == "false" {
+				continue
+			}
+		}
+		name := r.GetName()
+		values, err := cleanResource(r, kind)
+		if err != nil {
+			return fileNames, errors.Wrapf(err, "failed to clean %s %s", kind, name)
+		}
+
+		data, err := yaml.Marshal(values)
+		if err != nil {
+			return fileNames, errors.Wrapf(err, "failed to marshal %s %s to YAML", kind, name)
+		}
+
+		if len(fileNames) == 0 {
+			err = os.MkdirAll(outDir, util.DefaultWritePermissions)
+			if err != nil {
+				return fileNames, errors.Wrapf(err, "failed to create the %s output directory: %s", kind, outDir)
+			}
+		}
+		// the resources are rendered by helm so lets escape any template expressions in their values
+		data = []byte(EscapeHelmTemplate(string(data)))
+
+		fileName := filepath.Join(outDir, name+".yaml")
+		err = ioutil.WriteFile(fileName, data, util.DefaultWritePermissions)
+		if err != nil {
+			return fileNames, errors.Wrapf(err, "failed to write file %s for %s %s to YAML", fileName, kind, name)
+		}
+		fileNames = append(fileNames, fileName)
+	}
+	return fileNames, nil
+}
+
+// serverManagedMetadata the metadata fields populated by the API server which should not be stored in git
+var serverManagedMetadata = []string{
+	"creationTimestamp",
+	"deletionGracePeriodSeconds",
+	"deletionTimestamp",
+	"generation",
+	"managedFields",
+	"ownerReferences",
+	"resourceVersion",
+	"selfLink",
+	"uid",
+}
+
+// cleanResource converts the resource to a map clearing out the server managed metadata, the namespace and the status
+// along with the empty values the typed resources marshal. The namespace is removed so that helm creates the
+// resources in the namespace of the release. The labels and annotations are kept as jx uses them, for example to
+// map git users to User resources
+func cleanResource(r metav1.Object, kind string) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal to YAML")
+	}
+	values := map[string]interface{}{}
+	err = yaml.Unmarshal(data, &values)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal YAML")
+	}
+	if values["apiVersion"] == nil || values["apiVersion"] == "" {
+		values["apiVersion"] = JenkinsAPIVersion
+	}
+	if values["kind"] == nil || values["kind"] == "" {
+		values["kind"] = kind
+	}
+	metadata, ok := values["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+	}
+	for _, f := range serverManagedMetadata {
+		delete(metadata, f)
+	}
+	delete(metadata, "namespace")
+	metadata["name"] = r.GetName()
+	values["metadata"] = metadata
+	delete(values, "status")
+	for k, v := range values {
+		if k == "metadata" {
+			continue
+		}
+		v = removeEmptyValues(v)
+		if v == nil {
+			delete(values, k)
+		} else {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+// removeEmptyValues removes the null values, empty strings and empty maps and lists. Booleans and numbers are
+// kept as a false value may differ from a missing value and the elements of lists are kept
+func removeEmptyValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		answer := map[string]interface{}{}
+		for k, e := range v {
+			e = removeEmptyValues(e)
+			if e != nil {
+				answer[k] = e
+			}
+		}
+		if len(answer) == 0 {
+			return nil
+		}
+		return answer
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		// lets keep every element so that the list does not change
+		answer := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			cleaned := removeEmptyValues(e)
+			if cleaned == nil {
+				cleaned = e
+			}
+			answer = append(answer, cleaned)
+		}
+		return answer
+	case string:
+		if v == "" {
+			return nil
+		}
+	}
+	return value
+}
+
+// EscapeHelmTemplate escapes any go template expressions so that helm renders them as literal text
+func EscapeHelmTemplate(text string) string {
+	return strings.Replace(text, "{{", `{{ "{{" }}`, -1)
+}
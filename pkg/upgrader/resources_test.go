@@ -0,0 +1,115 @@
+package upgrader_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x-labs/helmboot/pkg/upgrader"
+	v1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+func TestWriteResourcesToGitFolder(t *testing.T) {
+	t.Parallel()
+
+	sourceDir := filepath.Join("test_data", "resources")
+
+	testDirs, err := ioutil.ReadDir(sourceDir)
+	require.NoError(t, err, "failed to read dir %s", sourceDir)
+	for _, d := range testDirs {
+		kind := d.Name()
+		if !d.IsDir() || strings.HasPrefix(kind, ".") {
+			continue
+		}
+
+		testDir := filepath.Join(sourceDir, kind)
+		resourceDir := filepath.Join(testDir, "source")
+		expectedDir := filepath.Join(testDir, "expected")
+
+		files, err := ioutil.ReadDir(resourceDir)
+		require.NoError(t, err, "failed to read dir %s", resourceDir)
+
+		resources := []metav1.Object{}
+		for _, f := range files {
+			fileName := filepath.Join(resourceDir, f.Name())
+			data, err := ioutil.ReadFile(fileName)
+			require.NoError(t, err, "failed to load %s", fileName)
+
+			// lets use the typed resources like the ones returned by the list operations
+			newResource := newResources[kind]
+			require.NotNil(t, newResource, "no typed resource for kind %s", kind)
+			r := newResource()
+			err = yaml.Unmarshal(data, r)
+			require.NoError(t, err, "failed to unmarshal %s", fileName)
+			r.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+			resources = append(resources, r.(metav1.Object))
+		}
+
+		tmpDir, err := ioutil.TempDir("", "helmboot-resources-")
+		require.NoError(t, err, "failed to create temp dir")
+		defer os.RemoveAll(tmpDir)
+		outDir := filepath.Join(tmpDir, upgrader.ResourceFolderName(kind))
+
+		fileNames, err := upgrader.WriteResourcesToGitFolder(outDir, kind, resources)
+		require.NoError(t, err, "failed to write %s resources", kind)
+
+		expectedFiles, err := ioutil.ReadDir(expectedDir)
+		require.NoError(t, err, "failed to read dir %s", expectedDir)
+		assert.Len(t, fileNames, len(expectedFiles), "files generated for kind %s", kind)
+
+		for _, f := range expectedFiles {
+			expectedFile := filepath.Join(expectedDir, f.Name())
+			want, err := ioutil.ReadFile(expectedFile)
+			require.NoError(t, err, "failed to load %s", expectedFile)
+
+			actualFile := filepath.Join(outDir, f.Name())
+			require.FileExists(t, actualFile, "should have generated file for kind %s", kind)
+			got, err := ioutil.ReadFile(actualFile)
+			require.NoError(t, err, "failed to load %s", actualFile)
+
+			assert.Equal(t, string(want), string(got), "generated file %s for kind %s", f.Name(), kind)
+		}
+	}
+}
+
+// newResources creates the typed resources for each kind
+var newResources = map[string]func() runtime.Object{
+	upgrader.KindUser:                   func() runtime.Object { return &v1.User{} },
+	upgrader.KindTeam:                   func() runtime.Object { return &v1.Team{} },
+	upgrader.KindScheduler:              func() runtime.Object { return &v1.Scheduler{} },
+	upgrader.KindApp:                    func() runtime.Object { return &v1.App{} },
+	upgrader.KindEnvironmentRoleBinding: func() runtime.Object { return &v1.EnvironmentRoleBinding{} },
+	upgrader.KindEnvironment:            func() runtime.Object { return &v1.Environment{} },
+}
+
+func TestEscapeHelmTemplate(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `url: {{ "{{" }} .Values.url }}`, upgrader.EscapeHelmTemplate("url: {{ .Values.url }}"))
+	assert.Equal(t, "name: cheese", upgrader.EscapeHelmTemplate("name: cheese"))
+}
+
+func TestResourceFilter(t *testing.T) {
+	t.Parallel()
+
+	f := &upgrader.ResourceFilter{
+		Include: upgrader.DefaultMigrateKinds,
+		Exclude: []string{"app"},
+	}
+	require.NoError(t, f.Validate(), "should be a valid filter")
+
+	assert.True(t, f.Matches(upgrader.KindUser), "should match kind %s", upgrader.KindUser)
+	assert.False(t, f.Matches(upgrader.KindApp), "should not match excluded kind %s", upgrader.KindApp)
+	assert.False(t, f.Matches(upgrader.KindEnvironment), "should not match kind %s by default", upgrader.KindEnvironment)
+
+	f.Exclude = []string{"Cheese"}
+	assert.Error(t, f.Validate(), "should have failed to validate an unknown kind")
+}